@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"io"
 	"os"
 
 	"github.com/github/git-lfs/lfs"
@@ -19,19 +20,30 @@ func cleanCommand(cmd *cobra.Command, args []string) {
 	lfs.InstallHooks(false)
 
 	var fileName string
-	var cb lfs.CopyCallback
-	var file *os.File
-	var fileSize int64
 	if len(args) > 0 {
 		fileName = args[0]
+	}
+
+	if err := clean(os.Stdout, os.Stdin, fileName); err != nil {
+		logFilterError(err)
+		os.Exit(2)
+	}
+}
 
+// clean stores the content read from from in the local store, and writes its
+// pointer to to. Content that is already a pointer is written as is.
+func clean(to io.Writer, from io.Reader, fileName string) error {
+	var cb lfs.CopyCallback
+	var file *os.File
+	var fileSize int64
+	if len(fileName) > 0 {
 		stat, err := os.Stat(fileName)
 		if err == nil && stat != nil {
 			fileSize = stat.Size()
 
 			localCb, localFile, err := lfs.CopyCallbackFile("clean", fileName, 1, 1)
 			if err != nil {
-				Error(err.Error())
+				Error("%s", err)
 			} else {
 				cb = localCb
 				file = localFile
@@ -39,7 +51,7 @@ func cleanCommand(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	cleaned, err := lfs.PointerClean(os.Stdin, fileName, fileSize, cb)
+	cleaned, err := lfs.PointerClean(from, fileName, fileSize, cb)
 	if file != nil {
 		file.Close()
 	}
@@ -49,34 +61,35 @@ func cleanCommand(cmd *cobra.Command, args []string) {
 	}
 
 	if lfs.IsCleanPointerError(err) {
-		os.Stdout.Write(lfs.ErrorGetContext(err, "bytes").([]byte))
-		return
+		_, err := to.Write(lfs.ErrorGetContext(err, "bytes").([]byte))
+		return err
 	}
 
 	if err != nil {
-		Panic(err, "Error cleaning asset.")
+		return newFilterError(err, "Error cleaning asset.")
 	}
 
 	tmpfile := cleaned.Filename
 	mediafile, err := lfs.LocalMediaPath(cleaned.Oid)
 	if err != nil {
-		Panic(err, "Unable to get local media path.")
+		return newFilterError(err, "Unable to get local media path.")
 	}
 
-	if stat, _ := os.Stat(mediafile); stat != nil {
-		if stat.Size() != cleaned.Size && len(cleaned.Pointer.Extensions) == 0 {
-			Exit("Files don't match:\n%s\n%s", mediafile, tmpfile)
+	if size, err := lfs.StatLocalObject(mediafile); err == nil {
+		if size != cleaned.Size && len(cleaned.Pointer.Extensions) == 0 {
+			return newFilterError(nil, "Files don't match:\n%s\n%s", mediafile, tmpfile)
 		}
 		Debug("%s exists", mediafile)
 	} else {
-		if err := os.Rename(tmpfile, mediafile); err != nil {
-			Panic(err, "Unable to move %s to %s\n", tmpfile, mediafile)
+		if err := lfs.StoreLocalObject(tmpfile, mediafile); err != nil {
+			return newFilterError(err, "Unable to move %s to %s\n", tmpfile, mediafile)
 		}
 
 		Debug("Writing %s", mediafile)
 	}
 
-	lfs.EncodePointer(os.Stdout, cleaned.Pointer)
+	_, err = lfs.EncodePointer(to, cleaned.Pointer)
+	return err
 }
 
 func init() {
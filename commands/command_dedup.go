@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"path/filepath"
+
+	"github.com/github/git-lfs/git"
+	"github.com/github/git-lfs/lfs"
+	"github.com/github/git-lfs/vendor/_nuts/github.com/rubyist/tracerx"
+	"github.com/github/git-lfs/vendor/_nuts/github.com/spf13/cobra"
+)
+
+var (
+	dedupCmd = &cobra.Command{
+		Use: "dedup",
+		Run: dedupCommand,
+	}
+)
+
+func dedupCommand(cmd *cobra.Command, args []string) {
+	requireInRepo()
+
+	ref, err := git.CurrentRef()
+	if err != nil {
+		Panic(err, "Could not dedup")
+	}
+
+	pointers, err := lfs.ScanTree(ref.Sha)
+	if err != nil {
+		Panic(err, "Could not scan for Git LFS files")
+	}
+
+	var dedupedFiles, sharedFiles, modifiedFiles, missingFiles int
+	var reclaimed int64
+	for _, pointer := range pointers {
+		filename := filepath.Join(lfs.LocalWorkingDir, pointer.Name)
+		result, err := lfs.DedupMediaFile(filename, pointer.Pointer)
+		if err != nil {
+			Exit("Could not dedup %s: %v", pointer.Name, err)
+		}
+
+		switch result {
+		case lfs.DedupCloned:
+			tracerx.Printf("dedup: cloned %s", pointer.Name)
+			dedupedFiles++
+			reclaimed += pointer.Size
+		case lfs.DedupShared:
+			tracerx.Printf("dedup: %s already shares its storage", pointer.Name)
+			sharedFiles++
+		case lfs.DedupModified:
+			tracerx.Printf("dedup: %s has local modifications", pointer.Name)
+			modifiedFiles++
+		case lfs.DedupMissing:
+			tracerx.Printf("dedup: %s is missing", pointer.Name)
+			missingFiles++
+		case lfs.DedupSkipped:
+			tracerx.Printf("dedup: skipped %s", pointer.Name)
+		}
+	}
+
+	Print("Deduplicated %d files, reclaimed %v", dedupedFiles, humanizeBytes(reclaimed))
+	if sharedFiles > 0 {
+		Print("Skipped %d files that already share their storage", sharedFiles)
+	}
+	if modifiedFiles > 0 {
+		Print("Skipped %d files with local modifications", modifiedFiles)
+	}
+	if missingFiles > 0 {
+		Print("Skipped %d missing files", missingFiles)
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(dedupCmd)
+}
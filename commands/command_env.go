@@ -1,6 +1,8 @@
 package commands
 
 import (
+	"strings"
+
 	"github.com/github/git-lfs/git"
 	"github.com/github/git-lfs/lfs"
 	"github.com/github/git-lfs/vendor/_nuts/github.com/spf13/cobra"
@@ -32,6 +34,7 @@ func envCommand(cmd *cobra.Command, args []string) {
 		if len(endpoint.SshUserAndHost) > 0 {
 			Print("  SSH=%s:%s", endpoint.SshUserAndHost, endpoint.SshPath)
 		}
+		printCapabilities(endpoint)
 	}
 
 	for _, remote := range config.Remotes() {
@@ -40,6 +43,7 @@ func envCommand(cmd *cobra.Command, args []string) {
 		if len(remoteEndpoint.SshUserAndHost) > 0 {
 			Print("  SSH=%s:%s", remoteEndpoint.SshUserAndHost, remoteEndpoint.SshPath)
 		}
+		printCapabilities(remoteEndpoint)
 	}
 
 	for _, env := range lfs.Environ() {
@@ -52,6 +56,29 @@ func envCommand(cmd *cobra.Command, args []string) {
 	}
 }
 
+// printCapabilities prints the cached capabilities of the endpoint, if
+// lfs.probecapabilities is enabled. The server isn't probed, so they are
+// unknown until another command has probed it.
+func printCapabilities(e lfs.Endpoint) {
+	if !lfs.Config.ProbeCapabilities() {
+		return
+	}
+
+	caps := lfs.CachedCapabilities(e)
+	if caps == nil {
+		Print("  Capabilities=unknown")
+		return
+	}
+
+	Print("  Batch=%t", caps.Batch)
+	Print("  Operations=%s", strings.Join(caps.Operations, ","))
+	Print("  Transfers=%s", strings.Join(caps.Transfers, ","))
+	Print("  HashAlgorithms=%s", strings.Join(caps.HashAlgorithms, ","))
+	Print("  Locking=%t", caps.Locking)
+	Print("  MaxBatchSize=%d", caps.MaxBatchSize)
+	Print("  ContentEncodings=%s", strings.Join(caps.ContentEncodings, ","))
+}
+
 func init() {
 	RootCmd.AddCommand(envCmd)
 }
@@ -15,7 +15,7 @@ var (
 
 	extListCmd = &cobra.Command{
 		Use:   "list",
-		Short: "View details for specified extensions",
+		Short: "View details for specified extensions, or the extensions that apply to paths",
 		Run:   extListCommand,
 	}
 )
@@ -31,9 +31,31 @@ func extListCommand(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	// Arguments that aren't the names of extensions are paths.
 	config := lfs.Config
 	for _, key := range args {
-		ext := config.Extensions()[key]
+		if ext, ok := config.Extensions()[key]; ok {
+			printExt(ext)
+			continue
+		}
+
+		printPathExts(key)
+	}
+}
+
+func printPathExts(path string) {
+	extensions, err := lfs.ExtensionsForPath(path)
+	if err != nil {
+		Print("%s", err)
+		return
+	}
+
+	if len(extensions) == 0 {
+		Print("No extensions apply to %s", path)
+		return
+	}
+
+	for _, ext := range extensions {
 		printExt(ext)
 	}
 }
@@ -56,6 +78,9 @@ func printExt(ext lfs.Extension) {
 	Print("    clean = %s", ext.Clean)
 	Print("    smudge = %s", ext.Smudge)
 	Print("    priority = %d", ext.Priority)
+	if len(ext.Process) > 0 {
+		Print("    process = %s", ext.Process)
+	}
 }
 
 func init() {
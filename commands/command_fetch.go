@@ -2,6 +2,7 @@ package commands
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/github/git-lfs/git"
@@ -77,7 +78,7 @@ func fetchCommand(cmd *cobra.Command, args []string) {
 		// Fetch refs sequentially per arg order; duplicates in later refs will be ignored
 		for _, ref := range refs {
 			Print("Fetching %v", ref.Name)
-			s := fetchRef(ref.Sha, includePaths, excludePaths)
+			s := fetchRef(ref, includePaths, excludePaths)
 			success = success && s
 		}
 
@@ -93,6 +94,8 @@ func fetchCommand(cmd *cobra.Command, args []string) {
 		prune(verify, false, false)
 	}
 
+	evictToCacheMaxSize()
+
 	if !success {
 		Exit("Warning: errors occurred")
 	}
@@ -115,25 +118,25 @@ func pointersToFetchForRef(ref string) ([]*lfs.WrappedPointer, error) {
 	return lfs.ScanRefs(ref, "", opts)
 }
 
-func fetchRefToChan(ref string, include, exclude []string) chan *lfs.WrappedPointer {
+func fetchRefToChan(ref *git.Ref, include, exclude []string) chan *lfs.WrappedPointer {
 	c := make(chan *lfs.WrappedPointer)
-	pointers, err := pointersToFetchForRef(ref)
+	pointers, err := pointersToFetchForRef(ref.Sha)
 	if err != nil {
 		Panic(err, "Could not scan for Git LFS files")
 	}
 
-	go fetchAndReportToChan(pointers, include, exclude, c)
+	go fetchAndReportToChan(pointers, ref.RemoteRefspec(lfs.Config.CurrentRemote), include, exclude, c)
 
 	return c
 }
 
 // Fetch all binaries for a given ref (that we don't have already)
-func fetchRef(ref string, include, exclude []string) bool {
-	pointers, err := pointersToFetchForRef(ref)
+func fetchRef(ref *git.Ref, include, exclude []string) bool {
+	pointers, err := pointersToFetchForRef(ref.Sha)
 	if err != nil {
 		Panic(err, "Could not scan for Git LFS files")
 	}
-	return fetchPointers(pointers, include, exclude)
+	return fetchPointers(pointers, ref.RemoteRefspec(lfs.Config.CurrentRemote), include, exclude)
 }
 
 // Fetch all previous versions of objects from since to ref (not including final state at ref)
@@ -143,7 +146,7 @@ func fetchPreviousVersions(ref string, since time.Time, include, exclude []strin
 	if err != nil {
 		Panic(err, "Could not scan for Git LFS previous versions")
 	}
-	return fetchPointers(pointers, include, exclude)
+	return fetchPointers(pointers, "", include, exclude)
 }
 
 // Fetch recent objects based on config
@@ -177,7 +180,7 @@ func fetchRecent(alreadyFetchedRefs []*git.Ref, include, exclude []string) bool
 			} else {
 				uniqueRefShas[ref.Sha] = ref.Name
 				Print("Fetching %v", ref.Name)
-				k := fetchRef(ref.Sha, include, exclude)
+				k := fetchRef(ref, include, exclude)
 				ok = ok && k
 			}
 		}
@@ -204,7 +207,7 @@ func fetchRecent(alreadyFetchedRefs []*git.Ref, include, exclude []string) bool
 func fetchAll() bool {
 	pointers := scanAll()
 	Print("Fetching objects...")
-	return fetchPointers(pointers, nil, nil)
+	return fetchPointers(pointers, "", nil, nil)
 }
 
 func scanAll() []*lfs.WrappedPointer {
@@ -235,19 +238,15 @@ func scanAll() []*lfs.WrappedPointer {
 	return pointers
 }
 
-func fetchPointers(pointers []*lfs.WrappedPointer, include, exclude []string) bool {
-	return fetchAndReportToChan(pointers, include, exclude, nil)
+func fetchPointers(pointers []*lfs.WrappedPointer, ref string, include, exclude []string) bool {
+	return fetchAndReportToChan(pointers, ref, include, exclude, nil)
 }
 
 // Fetch and report completion of each OID to a channel (optional, pass nil to skip)
 // Returns true if all completed with no errors, false if errors were written to stderr/log
-func fetchAndReportToChan(pointers []*lfs.WrappedPointer, include, exclude []string, out chan<- *lfs.WrappedPointer) bool {
-
-	totalSize := int64(0)
-	for _, p := range pointers {
-		totalSize += p.Size
-	}
-	q := lfs.NewDownloadQueue(len(pointers), totalSize, false)
+// The ref being fetched is optional, and is sent with the batch API requests.
+func fetchAndReportToChan(pointers []*lfs.WrappedPointer, ref string, include, exclude []string, out chan<- *lfs.WrappedPointer) bool {
+	missing := make([]*lfs.WrappedPointer, 0, len(pointers))
 
 	for _, p := range pointers {
 		// Only add to download queue if local file is not the right size already
@@ -256,7 +255,7 @@ func fetchAndReportToChan(pointers []*lfs.WrappedPointer, include, exclude []str
 		passFilter := lfs.FilenamePassesIncludeExcludeFilter(p.Name, include, exclude)
 		if !lfs.ObjectExistsOfSize(p.Oid, p.Size) && passFilter {
 			tracerx.Printf("fetch %v [%v]", p.Name, p.Oid)
-			q.Add(lfs.NewDownloadable(p))
+			missing = append(missing, p)
 		} else {
 			if !passFilter {
 				tracerx.Printf("Skipping %v [%v], include/exclude filters applied", p.Name, p.Oid)
@@ -273,37 +272,89 @@ func fetchAndReportToChan(pointers []*lfs.WrappedPointer, include, exclude []str
 		}
 	}
 
+	// Try each remote in turn, only asking the next one for the objects that
+	// the previous remote did not have
+	ok := true
+	currentRemote := lfs.Config.CurrentRemote
+	remotes := lfs.Config.FetchRemotes()
+	for i, remote := range remotes {
+		if len(missing) == 0 {
+			break
+		}
+
+		if i > 0 {
+			tracerx.Printf("fetch: trying %d missing objects from %v", len(missing), remote)
+		}
+
+		lfs.Config.CurrentRemote = remote
+		var k bool
+		missing, k = fetchFromRemote(missing, remote, ref, i == len(remotes)-1, out)
+		ok = ok && k
+	}
+	lfs.Config.CurrentRemote = currentRemote
+
 	if out != nil {
-		dlwatch := q.Watch()
-
-		go func() {
-			// fetch only reports single OID, but OID *might* be referenced by multiple
-			// WrappedPointers if same content is at multiple paths, so map oid->slice
-			oidToPointers := make(map[string][]*lfs.WrappedPointer, len(pointers))
-			for _, pointer := range pointers {
-				plist := oidToPointers[pointer.Oid]
-				oidToPointers[pointer.Oid] = append(plist, pointer)
+		close(out)
+	}
+
+	return ok
+}
+
+// fetchFromRemote downloads the given pointers from the current remote,
+// reporting each completed pointer to out (optional, pass nil to skip). Objects
+// that the remote does not have are returned so they can be requested from the
+// next remote, unless this is the last remote in which case they are reported
+// as errors.
+func fetchFromRemote(pointers []*lfs.WrappedPointer, remote, ref string, last bool, out chan<- *lfs.WrappedPointer) ([]*lfs.WrappedPointer, bool) {
+	totalSize := int64(0)
+	// fetch only reports single OID, but OID *might* be referenced by multiple
+	// WrappedPointers if same content is at multiple paths, so map oid->slice
+	oidToPointers := make(map[string][]*lfs.WrappedPointer, len(pointers))
+	for _, p := range pointers {
+		totalSize += p.Size
+		oidToPointers[p.Oid] = append(oidToPointers[p.Oid], p)
+	}
+
+	q := lfs.NewDownloadQueue(len(pointers), totalSize, false, ref)
+	dlwatch := q.Watch()
+
+	var watchwait sync.WaitGroup
+	watchwait.Add(1)
+	go func() {
+		for oid := range dlwatch {
+			tracerx.Printf("fetch %v from %v", oid, remote)
+			if out == nil {
+				continue
 			}
 
-			for oid := range dlwatch {
-				plist, ok := oidToPointers[oid]
-				if !ok {
-					continue
-				}
-				for _, p := range plist {
-					out <- p
-				}
+			for _, p := range oidToPointers[oid] {
+				out <- p
 			}
-			close(out)
-		}()
+		}
+		watchwait.Done()
+	}()
 
+	for _, p := range pointers {
+		q.Add(lfs.NewDownloadable(p))
 	}
+
 	processQueue := time.Now()
 	q.Wait()
+	watchwait.Wait()
 	tracerx.PerformanceSince("process queue", processQueue)
 
 	ok := true
+	var notFound []*lfs.WrappedPointer
+	notFoundOids := lfs.NewStringSet()
 	for _, err := range q.Errors() {
+		if !last && lfs.IsObjectNotFoundError(err) {
+			oid, _ := lfs.ErrorGetContext(err, "OID").(string)
+			if notFoundOids.Add(oid) {
+				notFound = append(notFound, oidToPointers[oid]...)
+			}
+			continue
+		}
+
 		ok = false
 		if Debugging || lfs.IsFatalError(err) {
 			LoggedError(err, err.Error())
@@ -311,5 +362,5 @@ func fetchAndReportToChan(pointers []*lfs.WrappedPointer, include, exclude []str
 			Error(err.Error())
 		}
 	}
-	return ok
+	return notFound, ok
 }
@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/github/git-lfs/lfs"
+	"github.com/github/git-lfs/vendor/_nuts/github.com/spf13/cobra"
+)
+
+var (
+	filterProcessCmd = &cobra.Command{
+		Use: "filter-process",
+		Run: filterProcessCommand,
+	}
+)
+
+// filterProcessCommand cleans and smudges every file Git sends it over the
+// long-running filter protocol, so that Git LFS and the extension processes it
+// starts only run once per Git command.
+func filterProcessCommand(cmd *cobra.Command, args []string) {
+	requireStdin("This command should be run by the Git filter process")
+	lfs.InstallHooks(false)
+
+	s := lfs.NewFilterProcessScanner(os.Stdin, os.Stdout)
+	if err := s.Init(); err != nil {
+		Panic(err, "Error starting the filter process: %s", err)
+	}
+
+	for {
+		headers, err := s.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			Panic(err, "Error reading from Git: %s", err)
+		}
+
+		var filterErr error
+		err = s.Respond(func(w io.Writer) error {
+			filterErr = filterProcessFile(w, s.Content(), headers["command"], headers["pathname"])
+			return filterErr
+		})
+
+		if filterErr != nil {
+			// Git reports the file as failed, and sends the next one.
+			logFilterError(filterErr)
+		} else if err != nil {
+			Panic(err, "Error writing to Git: %s", err)
+		}
+	}
+}
+
+func filterProcessFile(w io.Writer, content io.Reader, command, pathname string) error {
+	switch command {
+	case "clean":
+		return clean(w, content, pathname)
+	case "smudge":
+		// Git only reads the response once it has sent the whole file,
+		// so the content must be read before anything is written.
+		data, err := ioutil.ReadAll(content)
+		if err != nil {
+			return err
+		}
+		return smudge(w, bytes.NewReader(data), pathname)
+	default:
+		return fmt.Errorf("Unknown filter command %q for %s", command, pathname)
+	}
+}
+
+func init() {
+	filterProcessCmd.Flags().BoolVarP(&smudgeSkip, "skip", "s", false, "")
+	RootCmd.AddCommand(filterProcessCmd)
+}
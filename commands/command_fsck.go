@@ -1,7 +1,6 @@
 package commands
 
 import (
-	"crypto/sha256"
 	"encoding/hex"
 	"io"
 	"os"
@@ -13,7 +12,8 @@ import (
 )
 
 var (
-	fsckDryRun bool
+	fsckDryRun   bool
+	fsckPointers bool
 
 	fsckCmd = &cobra.Command{
 		Use: "fsck",
@@ -55,50 +55,104 @@ func doFsck() (bool, error) {
 	ok := true
 
 	for oid, name := range pointerIndex {
-		path := filepath.Join(lfs.LocalMediaDir, oid[0:2], oid[2:4], oid)
+		path, err := lfs.LocalMediaPath(oid)
+		if err != nil {
+			return false, err
+		}
 
 		Debug("Examining %v (%v)", name, path)
 
-		f, err := os.Open(path)
+		f, _, err := lfs.OpenLocalObject(path)
 		if pErr, pOk := err.(*os.PathError); pOk {
 			Print("Object %s (%s) could not be checked: %s", name, oid, pErr.Err)
 			ok = false
 			continue
 		}
-		if err != nil {
-			return false, err
+
+		var recalculatedOid string
+		if err == nil {
+			alg := lfs.ObjectHashAlgorithm(oid)
+			oidHash := alg.New()
+			_, err = io.Copy(oidHash, f)
+			f.Close()
+			recalculatedOid = alg.ObjectID(hex.EncodeToString(oidHash.Sum(nil)))
 		}
 
-		oidHash := sha256.New()
-		_, err = io.Copy(oidHash, f)
-		f.Close()
 		if err != nil {
-			return false, err
+			// A compressed object that can't be decompressed, or a
+			// chunked object with missing chunks, is as corrupt as
+			// one with the wrong content.
+			if lfs.StoredObjectFile(path) == path {
+				return false, err
+			}
+			Debug("Cannot read %v: %v", name, err)
 		}
 
-		recalculatedOid := hex.EncodeToString(oidHash.Sum(nil))
-		if recalculatedOid != oid {
+		if err != nil || recalculatedOid != oid {
 			ok = false
 			Print("Object %s (%s) is corrupt", name, oid)
+			if lfs.IsHardLinkedObject(path) {
+				Print("  it is hard linked, and may have been edited in the working directory")
+			}
 			if fsckDryRun {
 				continue
 			}
 
-			badDir := filepath.Join(lfs.LocalGitStorageDir, "lfs", "bad")
+			// Alternate stores are read-only.
+			if localPath, _ := lfs.LocalStoreMediaPath(oid); path != localPath {
+				Print("  in the alternate store %s, not moved", filepath.Dir(filepath.Dir(filepath.Dir(path))))
+				continue
+			}
+
+			badDir := filepath.Join(filepath.Dir(lfs.LocalMediaDir), "bad")
 			if err := os.MkdirAll(badDir, 0755); err != nil {
 				return false, err
 			}
 
-			badFile := filepath.Join(badDir, oid)
-			if err := os.Rename(path, badFile); err != nil {
+			// Compressed and chunked objects keep their extension.
+			storedFile := lfs.StoredObjectFile(path)
+			badFile := filepath.Join(badDir, filepath.Base(storedFile))
+			if err := os.Rename(storedFile, badFile); err != nil {
 				return false, err
 			}
 			Print("  moved to %s", badFile)
 		}
 	}
+
+	if fsckPointers {
+		pointersOk, err := fsckPointerBlobs(ref.Sha)
+		if err != nil {
+			return false, err
+		}
+		ok = ok && pointersOk
+	}
+
 	return ok, nil
 }
 
+// fsckPointerBlobs reports the pointers in the history of ref that aren't
+// canonical, and returns whether there are none.
+func fsckPointerBlobs(ref string) (bool, error) {
+	pointers, err := lfs.ScanNonCanonicalPointers(ref)
+	if err != nil {
+		return false, err
+	}
+
+	for _, p := range pointers {
+		if p.Err != nil {
+			Print("Pointer %s (%s) is not a valid Git LFS pointer: %s", p.Name, p.Sha1, p.Err)
+		} else {
+			Print("Pointer %s (%s) is not canonical:", p.Name, p.Sha1)
+		}
+
+		for _, problem := range p.Problems {
+			Print("  %s", problem)
+		}
+	}
+
+	return len(pointers) == 0, nil
+}
+
 // TODO(zeroshirts): 'git fsck' reports status (percentage, current#/total) as
 // it checks... we should do the same, as we are rehashing potentially gigs and
 // gigs of content.
@@ -120,5 +174,6 @@ func fsckCommand(cmd *cobra.Command, args []string) {
 
 func init() {
 	fsckCmd.Flags().BoolVarP(&fsckDryRun, "dry-run", "d", false, "List corrupt objects without deleting them.")
+	fsckCmd.Flags().BoolVarP(&fsckPointers, "pointers", "p", false, "Also check that the pointers in the history of HEAD are canonical.")
 	RootCmd.AddCommand(fsckCmd)
 }
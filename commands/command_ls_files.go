@@ -2,6 +2,7 @@ package commands
 
 import (
 	"os"
+	"strings"
 
 	"github.com/github/git-lfs/git"
 	"github.com/github/git-lfs/lfs"
@@ -32,19 +33,24 @@ func lsFilesCommand(cmd *cobra.Command, args []string) {
 		ref = fullref.Sha
 	}
 
-	showOidLen := 10
-	if longOIDs {
-		showOidLen = 64
-	}
-
 	files, err := lfs.ScanTree(ref)
 	if err != nil {
 		Panic(err, "Could not scan for Git LFS tree: %s", err)
 	}
 
 	for _, p := range files {
-		Print("%s %s %s", p.Oid[0:showOidLen], lsFilesMarker(p), p.Name)
+		Print("%s %s %s", lsFilesOid(p.Oid), lsFilesMarker(p), p.Name)
+	}
+}
+
+// lsFilesOid returns the OID to show, shortened to 10 hex digits, after any
+// tag of its hash algorithm, unless --long is given.
+func lsFilesOid(oid string) string {
+	if longOIDs {
+		return oid
 	}
+
+	return oid[0 : strings.Index(oid, ":")+11]
 }
 
 func lsFilesMarker(p *lfs.WrappedPointer) string {
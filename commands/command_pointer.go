@@ -2,11 +2,11 @@ package commands
 
 import (
 	"bytes"
-	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
 
@@ -18,6 +18,8 @@ var (
 	pointerFile    string
 	pointerCompare string
 	pointerStdin   bool
+	pointerCheck   bool
+	pointerStrict  bool
 	pointerCmd     = &cobra.Command{
 		Use: "pointer",
 		Run: pointerCommand,
@@ -25,6 +27,11 @@ var (
 )
 
 func pointerCommand(cmd *cobra.Command, args []string) {
+	if pointerCheck {
+		pointerCheckCommand()
+		return
+	}
+
 	comparing := false
 	something := false
 	buildOid := ""
@@ -42,7 +49,8 @@ func pointerCommand(cmd *cobra.Command, args []string) {
 			os.Exit(1)
 		}
 
-		oidHash := sha256.New()
+		alg := lfs.LookupHashAlgorithm(lfs.Config.HashAlgorithm())
+		oidHash := alg.New()
 		size, err := io.Copy(oidHash, buildFile)
 		buildFile.Close()
 
@@ -51,7 +59,7 @@ func pointerCommand(cmd *cobra.Command, args []string) {
 			os.Exit(1)
 		}
 
-		ptr := lfs.NewPointer(hex.EncodeToString(oidHash.Sum(nil)), size, nil)
+		ptr := lfs.NewPointer(alg.ObjectID(hex.EncodeToString(oidHash.Sum(nil))), size, nil)
 		fmt.Printf("Git LFS pointer for %s\n\n", pointerFile)
 		buf := &bytes.Buffer{}
 		lfs.EncodePointer(io.MultiWriter(os.Stdout, buf), ptr)
@@ -106,6 +114,56 @@ func pointerCommand(cmd *cobra.Command, args []string) {
 	}
 }
 
+// pointerCheckCommand checks the pointer given with --pointer or --stdin. It
+// exits with 1 if Git LFS can't read it, or with 2 if it isn't canonical and
+// --strict is given.
+func pointerCheckCommand() {
+	if len(pointerFile) > 0 {
+		Error("--check reads the pointer from --pointer or --stdin, not --file.")
+		os.Exit(1)
+	}
+
+	compFile, err := pointerReader()
+	if err != nil {
+		Error("%s", err)
+		os.Exit(1)
+	}
+
+	data, err := ioutil.ReadAll(compFile)
+	compFile.Close()
+	if err != nil {
+		Error("%s", err)
+		os.Exit(1)
+	}
+
+	pointerName := "STDIN"
+	if !pointerStdin {
+		pointerName = pointerCompare
+	}
+
+	_, problems, err := lfs.CheckPointer(data)
+	switch {
+	case err != nil:
+		Print("Pointer from %s is not a valid Git LFS pointer: %s", pointerName, err)
+	case len(problems) > 0:
+		Print("Pointer from %s is not canonical:", pointerName)
+	default:
+		Print("Pointer from %s is canonical", pointerName)
+	}
+
+	for _, problem := range problems {
+		Print("  %s", problem)
+	}
+
+	if err != nil {
+		os.Exit(1)
+	}
+
+	if len(problems) > 0 && pointerStrict {
+		os.Exit(2)
+	}
+}
+
 func pointerReader() (io.ReadCloser, error) {
 	if len(pointerCompare) > 0 {
 		if pointerStdin {
@@ -137,5 +195,7 @@ func init() {
 	flags.StringVarP(&pointerFile, "file", "f", "", "Path to a local file to generate the pointer from.")
 	flags.StringVarP(&pointerCompare, "pointer", "p", "", "Path to a local file containing a pointer built by another Git LFS implementation.")
 	flags.BoolVarP(&pointerStdin, "stdin", "", false, "Read a pointer built by another Git LFS implementation through STDIN.")
+	flags.BoolVarP(&pointerCheck, "check", "", false, "Check that the pointer from --pointer or --stdin is valid, and report why it isn't canonical.")
+	flags.BoolVarP(&pointerStrict, "strict", "", false, "With --check, fail if the pointer isn't canonical.")
 	RootCmd.AddCommand(pointerCmd)
 }
@@ -3,7 +3,7 @@ package commands
 import (
 	"bytes"
 	"fmt"
-	"os"
+	"sort"
 	"sync"
 	"time"
 
@@ -56,6 +56,11 @@ type PruneProgress struct {
 type PruneProgressChan chan PruneProgress
 
 func prune(verifyRemote, dryRun, verbose bool) {
+	if lfs.SharedLocalStorage() {
+		Error("Not pruning %s: lfs.storage may be shared with other clones, whose refs prune can't see.", lfs.LocalMediaDir)
+		return
+	}
+
 	localObjects := make([]*lfs.Pointer, 0, 100)
 	retainedObjects := lfs.NewStringSetWithCapacity(100)
 	var reachableObjects lfs.StringSet
@@ -120,7 +125,7 @@ func prune(verifyRemote, dryRun, verbose bool) {
 	if verifyRemote {
 		lfs.Config.CurrentRemote = lfs.Config.FetchPruneConfig().PruneRemoteName
 		// build queue now, no estimates or progress output
-		verifyQueue = lfs.NewDownloadCheckQueue(0, 0, true)
+		verifyQueue = lfs.NewDownloadCheckQueue(0, 0, true, "")
 		verifiedObjects = lfs.NewStringSetWithCapacity(len(localObjects) / 2)
 	}
 	for _, pointer := range localObjects {
@@ -265,18 +270,23 @@ func pruneDeleteFiles(prunableObjects []string) {
 	var deletedFiles int
 	for i, oid := range prunableObjects {
 		spinner.Print(OutputWriter, fmt.Sprintf("Deleting object %d/%d", i, len(prunableObjects)))
-		mediaFile, err := lfs.LocalMediaPath(oid)
+		mediaFile, err := lfs.LocalStoreMediaPath(oid)
 		if err != nil {
 			problems.WriteString(fmt.Sprintf("Unable to find media path for %v: %v\n", oid, err))
 			continue
 		}
-		err = os.Remove(mediaFile)
+		err = lfs.RemoveLocalObject(mediaFile)
 		if err != nil {
 			problems.WriteString(fmt.Sprintf("Failed to remove file %v: %v\n", mediaFile, err))
 			continue
 		}
 		deletedFiles++
 	}
+	if chunks, size, err := lfs.RemoveUnreferencedChunks(); err != nil {
+		problems.WriteString(fmt.Sprintf("Failed to remove unreferenced chunks: %v\n", err))
+	} else if chunks > 0 {
+		tracerx.Printf("PRUNE: removed %d unreferenced chunks (%v)", chunks, humanizeBytes(size))
+	}
 	spinner.Finish(OutputWriter, fmt.Sprintf("Deleted %d files", deletedFiles))
 	if problems.Len() > 0 {
 		LoggedError(fmt.Errorf("Failed to delete some files"), problems.String())
@@ -455,6 +465,145 @@ func pruneTaskGetReachableObjects(outObjectSet *lfs.StringSet, errorChan chan er
 
 }
 
+// evictToCacheMaxSize deletes the least recently used objects from the local
+// store until it fits in lfs.cache.maxsize. Unlike prune, which keeps recent
+// refs and commits, it only keeps the objects needed for HEAD, the HEADs of
+// other worktrees, and unpushed commits, so that the size is a hard cap. It is
+// run after fetch and pull.
+func evictToCacheMaxSize() {
+	maxSize := lfs.Config.CacheMaxSize()
+	if maxSize <= 0 {
+		return
+	}
+	if lfs.SharedLocalStorage() {
+		Error("Not evicting objects from %s: lfs.storage may be shared with other clones, whose refs can't be seen.", lfs.LocalMediaDir)
+		return
+	}
+
+	localObjects := lfs.AllLocalObjects()
+	var totalSize int64
+	for _, pointer := range localObjects {
+		totalSize += pointer.Size
+	}
+	if totalSize <= maxSize {
+		return
+	}
+
+	tracerx.Printf("EVICT: local objects take %d bytes, over lfs.cache.maxsize of %d", totalSize, maxSize)
+
+	headref, err := git.CurrentRef()
+	if err != nil {
+		LoggedError(err, "Not evicting objects from the local cache: %v", err)
+		return
+	}
+
+	var taskwait sync.WaitGroup
+	taskwait.Add(3) // HEAD, unpushed, worktree
+
+	errorChan := make(chan error, 10)
+	var errorwait sync.WaitGroup
+	errorwait.Add(1)
+	var taskErrors []error
+	go pruneTaskCollectErrors(&taskErrors, errorChan, &errorwait)
+
+	retainChan := make(chan string, 100)
+	go pruneTaskGetRetainedAtRef(headref.Sha, retainChan, errorChan, &taskwait)
+	go pruneTaskGetRetainedUnpushed(retainChan, errorChan, &taskwait)
+	go pruneTaskGetRetainedWorktree(retainChan, errorChan, &taskwait)
+
+	retainedObjects := lfs.NewStringSetWithCapacity(100)
+	var retainwait sync.WaitGroup
+	retainwait.Add(1)
+	go func() {
+		for oid := range retainChan {
+			retainedObjects.Add(oid)
+		}
+		retainwait.Done()
+	}()
+
+	taskwait.Wait()
+	close(retainChan)
+	retainwait.Wait()
+
+	close(errorChan)
+	errorwait.Wait()
+	if len(taskErrors) > 0 {
+		// Without the full set of retained objects, evicting could
+		// delete the only copy of unpushed content.
+		for _, err := range taskErrors {
+			LoggedError(err, "Not evicting objects from the local cache: %v", err)
+		}
+		return
+	}
+
+	evictable := make([]*lfs.Pointer, 0, len(localObjects))
+	for _, pointer := range localObjects {
+		if !retainedObjects.Contains(pointer.Oid) {
+			evictable = append(evictable, pointer)
+		}
+	}
+
+	accessTimes := lfs.LocalObjectAccessTimes(evictable)
+	sort.Sort(pointersByAccessTime{evictable, accessTimes})
+
+	var evictedFiles int
+	var evictedSize int64
+	for _, pointer := range evictable {
+		if totalSize <= maxSize {
+			break
+		}
+
+		mediaFile, err := lfs.LocalStoreMediaPath(pointer.Oid)
+		if err == nil {
+			err = lfs.RemoveLocalObject(mediaFile)
+		}
+		if err != nil {
+			tracerx.Printf("EVICT: cannot remove %v: %v", pointer.Oid, err)
+			continue
+		}
+
+		tracerx.Printf("EVICT: %v last accessed %v", pointer.Oid, accessTimes[pointer.Oid])
+		evictedFiles++
+		evictedSize += pointer.Size
+		totalSize -= pointer.Size
+	}
+
+	if evictedFiles > 0 {
+		if err := lfs.CompactObjectAccessIndex(); err != nil {
+			tracerx.Printf("EVICT: cannot compact the access index: %v", err)
+		}
+		if chunks, size, err := lfs.RemoveUnreferencedChunks(); err != nil {
+			tracerx.Printf("EVICT: cannot remove unreferenced chunks: %v", err)
+		} else if chunks > 0 {
+			tracerx.Printf("EVICT: removed %d unreferenced chunks (%v)", chunks, humanizeBytes(size))
+		}
+		Print("Evicted %d files from the local cache (%v)", evictedFiles, humanizeBytes(evictedSize))
+	}
+
+	if totalSize > maxSize {
+		Print("Local cache is %v, over lfs.cache.maxsize of %v, because the remaining objects are needed for HEAD or unpushed commits",
+			humanizeBytes(totalSize), humanizeBytes(maxSize))
+	}
+}
+
+// pointersByAccessTime sorts pointers from least to most recently accessed.
+type pointersByAccessTime struct {
+	pointers    []*lfs.Pointer
+	accessTimes map[string]time.Time
+}
+
+func (p pointersByAccessTime) Len() int {
+	return len(p.pointers)
+}
+
+func (p pointersByAccessTime) Swap(i, j int) {
+	p.pointers[i], p.pointers[j] = p.pointers[j], p.pointers[i]
+}
+
+func (p pointersByAccessTime) Less(i, j int) bool {
+	return p.accessTimes[p.pointers[i].Oid].Before(p.accessTimes[p.pointers[j].Oid])
+}
+
 func init() {
 	pruneCmd.Flags().BoolVarP(&pruneDryRunArg, "dry-run", "d", false, "Don't delete anything, just report")
 	pruneCmd.Flags().BoolVarP(&pruneVerboseArg, "verbose", "v", false, "Print full details of what is/would be deleted")
@@ -42,8 +42,9 @@ func pullCommand(cmd *cobra.Command, args []string) {
 
 	includePaths, excludePaths := determineIncludeExcludePaths(pullIncludeArg, pullExcludeArg)
 
-	c := fetchRefToChan(ref.Sha, includePaths, excludePaths)
+	c := fetchRefToChan(ref, includePaths, excludePaths)
 	checkoutFromFetchChan(includePaths, excludePaths, c)
+	evictToCacheMaxSize()
 }
 
 func init() {
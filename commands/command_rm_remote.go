@@ -0,0 +1,164 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/github/git-lfs/lfs"
+	"github.com/github/git-lfs/vendor/_nuts/github.com/rubyist/tracerx"
+	"github.com/github/git-lfs/vendor/_nuts/github.com/spf13/cobra"
+)
+
+// rmRemoteConfirmThreshold is the number of objects that can be deleted without
+// asking for confirmation first.
+const rmRemoteConfirmThreshold = 10
+
+var (
+	rmRemoteCmd = &cobra.Command{
+		Use: "rm-remote",
+		Run: rmRemoteCommand,
+	}
+	rmRemoteDryRun = false
+	rmRemoteRef    = ""
+	rmRemoteYes    = false
+)
+
+// rmRemoteCommand deletes Git LFS objects from the server of the given remote.
+// It takes the remote, followed by either object IDs, or paths if --ref is
+// given:
+//
+//   `<remote> <oid>...`
+//   `--ref <ref> <remote> <path>...`
+func rmRemoteCommand(cmd *cobra.Command, args []string) {
+	requireInRepo()
+
+	if len(args) < 2 {
+		Print("Usage: git lfs rm-remote [--dry-run] <remote> <oid>...")
+		Print("       git lfs rm-remote [--dry-run] --ref=<ref> <remote> <path>...")
+		os.Exit(1)
+	}
+
+	remote := args[0]
+	lfs.Config.CurrentRemote = remote
+
+	var objects []*lfs.ObjectResource
+	if len(rmRemoteRef) > 0 {
+		objects = rmRemoteObjectsAtRef(rmRemoteRef, args[1:])
+	} else {
+		objects = rmRemoteObjectsWithOids(args[1:])
+	}
+
+	if len(objects) == 0 {
+		Print("No Git LFS objects to delete.")
+		return
+	}
+
+	if rmRemoteDryRun {
+		for _, o := range objects {
+			Print("delete %s", o.Oid)
+		}
+		return
+	}
+
+	if len(objects) > rmRemoteConfirmThreshold && !rmRemoteYes {
+		if !confirmRmRemote(remote, len(objects)) {
+			Exit("Not deleting any objects.")
+		}
+	}
+
+	results, err := lfs.DeleteObjects(objects)
+	if err != nil {
+		if lfs.IsNotImplementedError(err) {
+			Exit("The Git LFS server for %q does not support deleting objects.", remote)
+		}
+
+		if Debugging || lfs.IsFatalError(err) {
+			Panic(err, "Error deleting objects from %q", remote)
+		}
+		Exit("Error deleting objects from %q: %s", remote, err)
+	}
+
+	failed := 0
+	for _, o := range results {
+		if o.Error != nil {
+			Error("[%s] %s", o.Oid, o.Error.Message)
+			failed += 1
+			continue
+		}
+
+		Print("deleted %s", o.Oid)
+	}
+
+	if failed > 0 {
+		os.Exit(2)
+	}
+}
+
+// rmRemoteObjectsWithOids validates the given object IDs. The server doesn't
+// need the sizes of objects to delete them, so they are left blank.
+func rmRemoteObjectsWithOids(oids []string) []*lfs.ObjectResource {
+	seen := lfs.NewStringSet()
+	objects := make([]*lfs.ObjectResource, 0, len(oids))
+
+	for _, oid := range oids {
+		if !lfs.ValidObjectID(oid) {
+			Exit("Invalid Git LFS object ID: %q", oid)
+		}
+
+		if !seen.Add(oid) {
+			continue
+		}
+
+		objects = append(objects, &lfs.ObjectResource{Oid: oid})
+	}
+
+	return objects
+}
+
+// rmRemoteObjectsAtRef returns the objects of the Git LFS files in the given
+// ref that match the given paths. Paths may be directories or wildcards, like
+// the --include option of fetch.
+func rmRemoteObjectsAtRef(ref string, paths []string) []*lfs.ObjectResource {
+	pointers, err := lfs.ScanTree(ref)
+	if err != nil {
+		Panic(err, "Could not scan for Git LFS files in %q", ref)
+	}
+
+	seen := lfs.NewStringSet()
+	objects := make([]*lfs.ObjectResource, 0, len(pointers))
+
+	for _, p := range pointers {
+		if !lfs.FilenamePassesIncludeExcludeFilter(p.Name, paths, nil) {
+			continue
+		}
+
+		if !seen.Add(p.Oid) {
+			continue
+		}
+
+		tracerx.Printf("rm-remote: %s => %s", p.Name, p.Oid)
+		objects = append(objects, &lfs.ObjectResource{Oid: p.Oid, Size: p.Size})
+	}
+
+	return objects
+}
+
+func confirmRmRemote(remote string, count int) bool {
+	fmt.Fprintf(os.Stderr, "Delete %d Git LFS objects from %q? [y/N] ", count, remote)
+
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+func init() {
+	rmRemoteCmd.Flags().BoolVarP(&rmRemoteDryRun, "dry-run", "d", false, "Print the objects that would be deleted, without deleting them.")
+	rmRemoteCmd.Flags().StringVarP(&rmRemoteRef, "ref", "r", "", "Delete the objects of the given paths in this ref.")
+	rmRemoteCmd.Flags().BoolVarP(&rmRemoteYes, "yes", "y", false, "Don't ask for confirmation.")
+	RootCmd.AddCommand(rmRemoteCmd)
+}
@@ -23,39 +23,39 @@ func smudgeCommand(cmd *cobra.Command, args []string) {
 	requireStdin("This command should be run by the Git 'smudge' filter")
 	lfs.InstallHooks(false)
 
+	if err := smudge(os.Stdout, os.Stdin, smudgeFilename(args, nil)); err != nil {
+		logFilterError(err)
+		os.Exit(2)
+	}
+}
+
+// smudge writes the content of the pointer read from from to to. Data that
+// isn't a pointer is written as is. If the content can't be downloaded, the
+// pointer is written instead, which is only an error if the download wasn't
+// skipped on purpose.
+func smudge(to io.Writer, from io.Reader, filename string) error {
 	// keeps the initial buffer from lfs.DecodePointer
 	b := &bytes.Buffer{}
-	r := io.TeeReader(os.Stdin, b)
+	r := io.TeeReader(from, b)
 
 	ptr, err := lfs.DecodePointer(r)
 	if err != nil {
-		mr := io.MultiReader(b, os.Stdin)
-		_, err := io.Copy(os.Stdout, mr)
+		mr := io.MultiReader(b, from)
+		_, err := io.Copy(to, mr)
 		if err != nil {
-			Panic(err, "Error writing data to stdout:")
+			return newFilterError(err, "Error writing data to stdout:")
 		}
-		return
+		return nil
 	}
 
 	if smudgeInfo {
-		localPath, err := lfs.LocalMediaPath(ptr.Oid)
-		if err != nil {
-			Exit(err.Error())
-		}
-
-		stat, err := os.Stat(localPath)
-		if err != nil {
-			Print("%d --", ptr.Size)
-		} else {
-			Print("%d %s", stat.Size(), localPath)
-		}
-		return
+		printSmudgeInfo(ptr)
+		return nil
 	}
 
-	filename := smudgeFilename(args, err)
 	cb, file, err := lfs.CopyCallbackFile("smudge", filename, 1, 1)
 	if err != nil {
-		Error(err.Error())
+		Error("%s", err)
 	}
 
 	cfg := lfs.Config
@@ -65,19 +65,34 @@ func smudgeCommand(cmd *cobra.Command, args []string) {
 		download = false
 	}
 
-	err = ptr.Smudge(os.Stdout, filename, download, cb)
+	err = ptr.Smudge(to, filename, download, cb)
 	if file != nil {
 		file.Close()
 	}
 
 	if err != nil {
-		ptr.Encode(os.Stdout)
+		ptr.Encode(to)
 		// Download declined error is ok to skip if we weren't requesting download
 		if !(lfs.IsDownloadDeclinedError(err) && !download) {
-			LoggedError(err, "Error accessing media: %s (%s)", filename, ptr.Oid)
-			os.Exit(2)
+			return newFilterError(err, "Error accessing media: %s (%s)", filename, ptr.Oid)
 		}
 	}
+
+	return nil
+}
+
+func printSmudgeInfo(ptr *lfs.Pointer) {
+	localPath, err := lfs.LocalMediaPath(ptr.Oid)
+	if err != nil {
+		Exit("%s", err)
+	}
+
+	size, err := lfs.StatLocalObject(localPath)
+	if err != nil {
+		Print("%d --", ptr.Size)
+	} else {
+		Print("%d %s", size, localPath)
+	}
 }
 
 func smudgeFilename(args []string, err error) string {
@@ -84,6 +84,31 @@ func Panic(err error, format string, args ...interface{}) {
 	os.Exit(2)
 }
 
+// A filterError is an error from cleaning or smudging a file, with the message
+// to show for it. The error, if any, is written to the log.
+type filterError struct {
+	err     error
+	message string
+}
+
+func newFilterError(err error, format string, args ...interface{}) error {
+	return &filterError{err: err, message: fmt.Sprintf(format, args...)}
+}
+
+func (e *filterError) Error() string {
+	return e.message
+}
+
+// logFilterError prints the message of an error from cleaning or smudging a
+// file, and logs it.
+func logFilterError(err error) {
+	if fe, ok := err.(*filterError); ok {
+		LoggedError(fe.err, "%s", fe.message)
+		return
+	}
+	LoggedError(err, "%s", err)
+}
+
 func Run() {
 	RootCmd.Execute()
 }
@@ -33,5 +33,6 @@ func main() {
 
 	commands.Run()
 	lfs.LogHttpStats()
+	lfs.ReportHttpStats()
 	once.Do(lfs.ClearTempObjects)
 }
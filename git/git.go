@@ -35,6 +35,42 @@ type Ref struct {
 	Sha  string
 }
 
+// Refspec returns the fully qualified name of the ref, such as
+// "refs/heads/master". It reverses ParseRefToTypeAndName.
+func (r *Ref) Refspec() string {
+	switch r.Type {
+	case RefTypeLocalBranch:
+		return "refs/heads/" + r.Name
+	case RefTypeRemoteBranch:
+		return "refs/remotes/" + r.Name
+	case RefTypeLocalTag:
+		return "refs/tags/" + r.Name
+	case RefTypeRemoteTag:
+		return "refs/remotes/tags/" + r.Name
+	default:
+		return r.Name
+	}
+}
+
+// RemoteRefspec returns the name of the ref on the given remote, which is
+// what the remote's LFS server knows it by. A remote-tracking branch of that
+// remote, such as "origin/master", is "refs/heads/master". Refs tracking other
+// remotes return "", since their names on this remote aren't known. Other refs
+// return Refspec.
+func (r *Ref) RemoteRefspec(remote string) string {
+	switch r.Type {
+	case RefTypeRemoteBranch:
+		if strings.HasPrefix(r.Name, remote+"/") {
+			return "refs/heads/" + strings.TrimPrefix(r.Name, remote+"/")
+		}
+		return ""
+	case RefTypeRemoteTag:
+		return ""
+	default:
+		return r.Refspec()
+	}
+}
+
 // Some top level information about a commit (only first line of message)
 type CommitSummary struct {
 	Sha            string
@@ -214,6 +250,23 @@ func UpdateIndex(file string) error {
 	return err
 }
 
+// CheckAttr returns the value of a gitattributes attribute for a path, as
+// printed by git check-attr: "set", "unset", "unspecified", or the value.
+func CheckAttr(attr, path string) (string, error) {
+	output, err := simpleExec("git", "check-attr", attr, "--", path)
+	if err != nil {
+		return "", err
+	}
+
+	// Output is formatted:
+	// <path>: <attr>: <info>
+	sep := ": " + attr + ": "
+	if i := strings.LastIndex(output, sep); i >= 0 {
+		return output[i+len(sep):], nil
+	}
+	return "unspecified", nil
+}
+
 type gitConfig struct {
 }
 
@@ -272,3 +272,18 @@ func TestVersionCompare(t *testing.T) {
 	assert.Equal(t, false, IsVersionAtLeast("2.5.0", "2.5.1"))
 	assert.Equal(t, false, IsVersionAtLeast("2.5.2", "2.5.10"))
 }
+
+func TestRefspec(t *testing.T) {
+	assert.Equal(t, "refs/heads/master", (&Ref{"master", RefTypeLocalBranch, ""}).Refspec())
+	assert.Equal(t, "refs/remotes/origin/master", (&Ref{"origin/master", RefTypeRemoteBranch, ""}).Refspec())
+	assert.Equal(t, "refs/tags/v1.0", (&Ref{"v1.0", RefTypeLocalTag, ""}).Refspec())
+	assert.Equal(t, "HEAD", (&Ref{"HEAD", RefTypeHEAD, ""}).Refspec())
+}
+
+func TestRemoteRefspec(t *testing.T) {
+	assert.Equal(t, "refs/heads/master", (&Ref{"master", RefTypeLocalBranch, ""}).RemoteRefspec("origin"))
+	assert.Equal(t, "refs/heads/feature/x", (&Ref{"origin/feature/x", RefTypeRemoteBranch, ""}).RemoteRefspec("origin"))
+	assert.Equal(t, "", (&Ref{"upstream/master", RefTypeRemoteBranch, ""}).RemoteRefspec("origin"))
+	assert.Equal(t, "", (&Ref{"origin", RefTypeRemoteBranch, ""}).RemoteRefspec("origin"))
+	assert.Equal(t, "refs/tags/v1.0", (&Ref{"v1.0", RefTypeLocalTag, ""}).RemoteRefspec("origin"))
+}
@@ -0,0 +1,135 @@
+package lfs
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/rubyist/tracerx"
+)
+
+// accessIndexPath returns the path of the access index, which records when
+// objects in the local store were last read. Each line holds an OID and a Unix
+// time, and later lines win, so that recording an access is a single append
+// that is safe with concurrent smudges.
+func accessIndexPath() string {
+	return filepath.Join(filepath.Dir(LocalMediaDir), "access")
+}
+
+// accessIndexCompactSize is the size over which the access index is compacted
+// when an access is recorded. Compacted, it takes about 80 bytes per object, so
+// this is far more than a compacted index of any but the largest stores.
+var accessIndexCompactSize int64 = 16 * 1024 * 1024
+
+// recordObjectAccess appends the current time for the object to the access
+// index, if lfs.cache.maxsize is set, since eviction is the only reader. Shared
+// stores are never evicted, so their accesses aren't recorded either. Failures are only traced, since the index is just a hint for eviction.
+func recordObjectAccess(oid string) {
+	if len(LocalMediaDir) == 0 || Config.CacheMaxSize() <= 0 || SharedLocalStorage() {
+		return
+	}
+
+	file, err := os.OpenFile(accessIndexPath(), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		tracerx.Printf("access index: %s", err)
+		return
+	}
+	defer file.Close()
+
+	if _, err := fmt.Fprintf(file, "%s %d\n", oid, time.Now().Unix()); err != nil {
+		tracerx.Printf("access index: %s", err)
+		return
+	}
+
+	if stat, err := file.Stat(); err == nil && stat.Size() > accessIndexCompactSize {
+		if err := CompactObjectAccessIndex(); err != nil {
+			tracerx.Printf("access index: %s", err)
+		}
+	}
+}
+
+func readAccessIndex() map[string]time.Time {
+	times := make(map[string]time.Time)
+
+	file, err := os.Open(accessIndexPath())
+	if err != nil {
+		return times
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || !ValidObjectID(fields[0]) {
+			continue
+		}
+
+		secs, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		t := time.Unix(secs, 0)
+		if t.After(times[fields[0]]) {
+			times[fields[0]] = t
+		}
+	}
+
+	return times
+}
+
+// LocalObjectAccessTimes returns when each of the objects in the local store
+// was last read, according to the access index. Objects that haven't been read
+// since they were stored use the time they were stored instead.
+func LocalObjectAccessTimes(pointers []*Pointer) map[string]time.Time {
+	index := readAccessIndex()
+	times := make(map[string]time.Time, len(pointers))
+
+	for _, p := range pointers {
+		if t, ok := index[p.Oid]; ok {
+			times[p.Oid] = t
+		} else if stat, err := os.Stat(StoredObjectFile(localMediaPathNoCreate(p.Oid))); err == nil {
+			times[p.Oid] = stat.ModTime()
+		}
+	}
+
+	return times
+}
+
+// CompactObjectAccessIndex rewrites the access index with a single line for
+// each object that is still in the local store. Accesses recorded while it is
+// rewritten may be lost, which only makes those objects look older.
+func CompactObjectAccessIndex() error {
+	index := readAccessIndex()
+
+	tmp, err := ioutil.TempFile(filepath.Dir(accessIndexPath()), "access")
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(tmp)
+	for oid, t := range index {
+		if localObjectExists(localMediaPathNoCreate(oid)) {
+			fmt.Fprintf(w, "%s %d\n", oid, t.Unix())
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	tmp.Close()
+
+	if err := os.Rename(tmp.Name(), accessIndexPath()); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package lfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/technoweenie/assert"
+)
+
+func TestLocalObjectAccessTimes(t *testing.T) {
+	tmp := tempdir(t)
+	defer os.RemoveAll(tmp)
+
+	oldMediaDir := LocalMediaDir
+	LocalMediaDir = filepath.Join(tmp, "lfs", "objects")
+	defer func() {
+		LocalMediaDir = oldMediaDir
+	}()
+
+	defer Config.ResetConfig()
+	Config.SetConfig("lfs.cache.maxsize", "1g")
+
+	read := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	unread := "60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752"
+	deleted := "fcde2b2edba56bf408601fb721fe9b5c338d10ee429ea04fae5511b68fbf8fb9"
+
+	stored := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	for _, oid := range []string{read, unread} {
+		path, err := LocalStoreMediaPath(oid)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, nil, ioutil.WriteFile(path, []byte("test"), 0644))
+		assert.Equal(t, nil, os.Chtimes(path, stored, stored))
+	}
+
+	assert.Equal(t, nil, ioutil.WriteFile(accessIndexPath(),
+		[]byte(read+" 1000\n"+read+" 3000\n"+read+" 2000\nnot an oid 4000\n"+deleted+" 5000\n"), 0644))
+
+	pointers := []*Pointer{NewPointer(read, 4, nil), NewPointer(unread, 4, nil)}
+	times := LocalObjectAccessTimes(pointers)
+	assert.Equal(t, 2, len(times))
+	assert.Equal(t, int64(3000), times[read].Unix())
+	assert.Equal(t, stored.Unix(), times[unread].Unix())
+
+	before := time.Now().Add(-time.Second)
+	recordObjectAccess(unread)
+	times = LocalObjectAccessTimes(pointers)
+	assert.Equal(t, true, times[unread].After(before))
+
+	// only the latest access of objects still in the store are kept
+	assert.Equal(t, nil, CompactObjectAccessIndex())
+	index := readAccessIndex()
+	assert.Equal(t, 2, len(index))
+	assert.Equal(t, int64(3000), index[read].Unix())
+	_, ok := index[deleted]
+	assert.Equal(t, false, ok)
+}
+
+func TestRecordObjectAccess(t *testing.T) {
+	tmp := tempdir(t)
+	defer os.RemoveAll(tmp)
+
+	oldMediaDir := LocalMediaDir
+	LocalMediaDir = filepath.Join(tmp, "lfs", "objects")
+	oldCompactSize := accessIndexCompactSize
+	accessIndexCompactSize = 200
+	defer func() {
+		LocalMediaDir = oldMediaDir
+		accessIndexCompactSize = oldCompactSize
+	}()
+
+	oid := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	path, err := LocalStoreMediaPath(oid)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, ioutil.WriteFile(path, []byte("test"), 0644))
+
+	// nothing is recorded without lfs.cache.maxsize
+	recordObjectAccess(oid)
+	_, err = os.Stat(accessIndexPath())
+	assert.Equal(t, true, os.IsNotExist(err))
+
+	defer Config.ResetConfig()
+	Config.SetConfig("lfs.cache.maxsize", "1g")
+
+	// the index is compacted once it is over accessIndexCompactSize
+	recordObjectAccess(oid)
+	recordObjectAccess(oid)
+	data, err := ioutil.ReadFile(accessIndexPath())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 2, strings.Count(string(data), "\n"))
+
+	recordObjectAccess(oid)
+	data, err = ioutil.ReadFile(accessIndexPath())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, strings.Count(string(data), "\n"))
+}
@@ -0,0 +1,81 @@
+package lfs
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/rubyist/tracerx"
+)
+
+var (
+	alternatesMu  sync.Mutex
+	alternatesDir string // LocalMediaDir that the alternates were read for
+	alternates    []string
+)
+
+// LocalMediaAlternates returns the read-only object stores that are consulted
+// for objects missing from the local store. Like Git's objects/info/alternates,
+// they are listed one per line in .git/lfs/objects/info/alternates, as absolute
+// paths or paths relative to .git/lfs/objects. Lines starting with "#" are
+// ignored. Alternates of alternates are not followed.
+func LocalMediaAlternates() []string {
+	alternatesMu.Lock()
+	defer alternatesMu.Unlock()
+
+	if alternatesDir != LocalMediaDir {
+		alternatesDir = LocalMediaDir
+		alternates = readAlternates(LocalMediaDir)
+	}
+
+	return alternates
+}
+
+func readAlternates(mediaDir string) []string {
+	if len(mediaDir) == 0 {
+		return nil
+	}
+
+	file, err := os.Open(filepath.Join(mediaDir, "info", "alternates"))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var dirs []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if !filepath.IsAbs(line) {
+			line = filepath.Join(mediaDir, line)
+		}
+
+		if !DirExists(line) {
+			tracerx.Printf("alternates: ignoring missing object store %s", line)
+			continue
+		}
+
+		dirs = append(dirs, filepath.Clean(line))
+	}
+
+	return dirs
+}
+
+// alternateMediaPath returns the path of the object in the first alternate
+// store that has it, or "" if none do.
+func alternateMediaPath(sha string) string {
+	for _, dir := range LocalMediaAlternates() {
+		path := filepath.Join(dir, objectRelPath(sha))
+		if localObjectExists(path) {
+			return path
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,51 @@
+package lfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/technoweenie/assert"
+)
+
+func TestLocalMediaAlternates(t *testing.T) {
+	tmp := tempdir(t)
+	defer os.RemoveAll(tmp)
+
+	local := filepath.Join(tmp, "local", "objects")
+	shared := filepath.Join(tmp, "shared", "objects")
+	oid := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	sharedPath := filepath.Join(shared, oid[0:2], oid[2:4], oid)
+
+	assert.Equal(t, nil, os.MkdirAll(filepath.Join(local, "info"), 0755))
+	assert.Equal(t, nil, os.MkdirAll(filepath.Dir(sharedPath), 0755))
+	assert.Equal(t, nil, ioutil.WriteFile(sharedPath, []byte("test"), 0644))
+	assert.Equal(t, nil, ioutil.WriteFile(filepath.Join(local, "info", "alternates"),
+		[]byte("# shared store\n../../shared/objects\n/does/not/exist\n"), 0644))
+
+	oldMediaDir := LocalMediaDir
+	LocalMediaDir = local
+	defer func() {
+		LocalMediaDir = oldMediaDir
+	}()
+
+	assert.Equal(t, []string{shared}, LocalMediaAlternates())
+	assert.Equal(t, true, ObjectExistsOfSize(oid, 4))
+	assert.Equal(t, false, ObjectExistsOfSize(oid, 5))
+
+	path, err := LocalMediaPath(oid)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, sharedPath, path)
+
+	// new objects are written to the local store
+	localPath, err := LocalStoreMediaPath(oid)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, filepath.Join(local, oid[0:2], oid[2:4], oid), localPath)
+
+	// the local store wins once it has the object
+	assert.Equal(t, nil, ioutil.WriteFile(localPath, []byte("test"), 0644))
+	path, err = LocalMediaPath(oid)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, localPath, path)
+}
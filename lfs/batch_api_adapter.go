@@ -0,0 +1,221 @@
+package lfs
+
+import (
+	"sync"
+
+	"github.com/github/git-lfs/git"
+	"github.com/github/git-lfs/vendor/_nuts/github.com/rubyist/tracerx"
+)
+
+// batchApiAdapter asks the batch API how to transfer the objects of a
+// TransferQueue, making one POST call per batch of objects. Batches are bounded
+// by lfs.batchmaxobjects and lfs.batchmaxbytes.
+type batchApiAdapter struct {
+	q        *TransferQueue
+	batcher  *Batcher
+	batchc   chan []*ObjectResource // Channel for processing resolved batches
+	fallback *legacyApiAdapter      // Set if the server has no batch API
+	routines sync.WaitGroup         // Routines taking batches from the batcher
+
+	startProgress sync.Once
+
+	mu          sync.Mutex // Guards fallback and unsupported
+	unsupported bool       // Set if the server has no batch API, without fallback
+}
+
+func newBatchApiAdapter(q *TransferQueue, caps *Capabilities) *batchApiAdapter {
+	maxObjects, maxBytes := Config.BatchMaxObjects(), Config.BatchMaxBytes()
+	if caps != nil && caps.MaxBatchSize > 0 && caps.MaxBatchSize < maxObjects {
+		maxObjects = caps.MaxBatchSize
+	}
+	tracerx.Printf("tq: running as batched queue, batch size of %d, max bytes of %d", maxObjects, maxBytes)
+
+	a := &batchApiAdapter{
+		q:       q,
+		batcher: NewSizedBatcher(maxObjects, maxBytes),
+		batchc:  make(chan []*ObjectResource, batchLookahead),
+	}
+
+	a.routines.Add(1)
+	go a.batchApiRoutine()
+	go a.batchTransferRoutine()
+
+	return a
+}
+
+func (a *batchApiAdapter) Add(t Transferable) {
+	a.batcher.Add(t)
+}
+
+func (a *batchApiAdapter) Flush() {
+	a.batcher.Exit()
+}
+
+// Close waits for the batches being resolved, since they still send to batchc,
+// and then stops the adapter.
+func (a *batchApiAdapter) Close() {
+	a.batcher.Close()
+	a.routines.Wait()
+	close(a.batchc)
+	if a.fallback != nil {
+		a.fallback.Close()
+	}
+}
+
+// batchApiRoutine sends each batch from the batcher to the batch API, and
+// hands the resolved objects off to batchTransferRoutine. The first request is
+// made on its own, so that credentials are only asked for once. Once a request
+// succeeds, up to lfs.concurrenttransfers requests are made at once, so that
+// pushes of many small objects don't wait on one round trip at a time.
+func (a *batchApiAdapter) batchApiRoutine() {
+	defer a.routines.Done()
+
+	var requests sync.WaitGroup
+	defer requests.Wait()
+
+	inflight := make(chan struct{}, a.q.workers)
+	concurrent := false
+
+	for {
+		batch := a.batcher.Next()
+		if batch == nil {
+			break
+		}
+
+		if a.batchUnsupported() {
+			a.notImplemented(batch)
+			continue
+		}
+
+		if concurrent {
+			inflight <- struct{}{}
+			requests.Add(1)
+			go func(batch []Transferable) {
+				// Other errors are reported by resolveBatch.
+				if err := a.resolveBatch(batch); IsNotImplementedError(err) {
+					a.notImplemented(batch)
+				}
+				<-inflight
+				requests.Done()
+			}(batch)
+			continue
+		}
+
+		err := a.resolveBatch(batch)
+		if err == nil {
+			concurrent = true
+		} else if IsNotImplementedError(err) {
+			a.notImplemented(batch)
+		}
+	}
+}
+
+// batchUnsupported returns whether the server was found to have no batch API.
+func (a *batchApiAdapter) batchUnsupported() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.unsupported || a.fallback != nil
+}
+
+// notImplemented handles a batch sent to a server without the batch API. With
+// lfs.legacyapi, the batch is handed to the legacy API adapter to be processed
+// individually. Otherwise the error is reported once, and the batch is dropped.
+func (a *batchApiAdapter) notImplemented(batch []Transferable) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if Config.LegacyApi() {
+		if a.fallback == nil {
+			tracerx.Printf("tq: batch api not implemented, falling back to individual")
+			git.Config.SetLocal("", "lfs.batch", "false")
+			a.fallback = newLegacyApiAdapter(a.q)
+		}
+
+		for _, t := range batch {
+			a.fallback.Add(t)
+		}
+		return
+	}
+
+	if !a.unsupported {
+		a.unsupported = true
+		a.q.errorc <- legacyApiDisabledError(Config.Endpoint())
+	}
+	a.q.wait.Add(-len(batch))
+}
+
+// resolveBatch asks the batch API how to transfer the batch, and sends the
+// result to batchTransferRoutine. Errors are retried or reported, except for
+// a server without the batch API, which is returned without either.
+func (a *batchApiAdapter) resolveBatch(batch []Transferable) error {
+	tracerx.Printf("tq: sending batch of size %d", len(batch))
+
+	transfers := make([]*ObjectResource, 0, len(batch))
+	for _, t := range batch {
+		o := &ObjectResource{Oid: t.Oid(), Size: t.Size()}
+		if c, ok := t.(chunkedTransferable); ok {
+			o.Chunks = c.Chunks()
+		}
+		transfers = append(transfers, o)
+	}
+
+	objects, err := Batch(transfers, a.q.transferKind, a.q.ref)
+	if err != nil {
+		if IsNotImplementedError(err) {
+			return err
+		}
+
+		if a.q.canRetry(err) {
+			for _, t := range batch {
+				a.q.retry(t)
+			}
+		} else {
+			a.q.errorc <- err
+		}
+
+		a.q.wait.Add(-len(transfers))
+		return err
+	}
+
+	a.startProgress.Do(a.q.meter.Start)
+	a.batchc <- objects
+	return nil
+}
+
+// batchTransferRoutine hands off the objects of each resolved batch to the
+// transfer workers. It runs separately from batchApiRoutine, so that later
+// batches are resolved while the transfers of earlier batches are queued.
+func (a *batchApiAdapter) batchTransferRoutine() {
+	q := a.q
+
+	for objects := range a.batchc {
+		for _, o := range objects {
+			if o.Error != nil {
+				err := Errorf(o.Error, "[%v] %v", o.Oid, o.Error.Message)
+				if o.Error.Code == 404 {
+					err = newObjectNotFoundError(err, o.Oid)
+				}
+				q.errorc <- err
+				q.meter.Skip(o.Size)
+				q.wait.Done()
+				continue
+			}
+
+			if _, ok := o.Rel(q.transferKind); ok || len(o.Chunks) > 0 {
+				// This object, or some of its chunks, need to be
+				// transferred
+				if transfer, ok := q.transferables[o.Oid]; ok {
+					transfer.SetObject(o)
+					q.meter.Add(transfer.Name())
+					q.transferc <- transfer
+				} else {
+					q.meter.Skip(o.Size)
+					q.wait.Done()
+				}
+			} else {
+				q.meter.Skip(o.Size)
+				q.wait.Done()
+			}
+		}
+	}
+}
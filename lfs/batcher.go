@@ -6,19 +6,31 @@ import "sync/atomic"
 // be added to the batcher from multiple goroutines and pulled off in groups
 // when one of the following conditions occurs:
 //   * The batch size is reached
+//   * The batch byte size would be exceeded by the next item
 //   * Exit() is called
-// When an Exit() occurs, the group may be smaller than the batch size.
+// When an Exit() occurs, the group may be smaller than the batch size, and an
+// empty group is dropped.
 type Batcher struct {
 	exited     uint32
 	batchSize  int
+	maxBytes   int64
 	input      chan Transferable
 	batchReady chan []Transferable
 }
 
 // NewBatcher creates a Batcher with the batchSize.
 func NewBatcher(batchSize int) *Batcher {
+	return NewSizedBatcher(batchSize, 0)
+}
+
+// NewSizedBatcher creates a Batcher with the batchSize, that also limits the
+// total size of the items in each batch to maxBytes. An item larger than
+// maxBytes is sent in a batch on its own. A maxBytes of 0 means batches are
+// only limited by the batchSize.
+func NewSizedBatcher(batchSize int, maxBytes int64) *Batcher {
 	b := &Batcher{
 		batchSize:  batchSize,
+		maxBytes:   maxBytes,
 		input:      make(chan Transferable, batchSize),
 		batchReady: make(chan []Transferable),
 	}
@@ -51,14 +63,31 @@ func (b *Batcher) Exit() {
 	close(b.input)
 }
 
+// Close makes Next() return nil for good. It must only be called after Exit(),
+// once every batch has been taken with Next().
+func (b *Batcher) Close() {
+	close(b.batchReady)
+}
+
 // acceptInput runs in its own goroutine and accepts input from external
 // clients. It fills and dispenses batches in a sequential order: for a batch
-// size N, N items will be processed before a new batch is ready.
+// size N, N items will be processed before a new batch is ready. If an item
+// would push the batch over maxBytes, the batch is dispensed and the item
+// starts the next one.
 func (b *Batcher) acceptInput() {
 	exit := false
+	var next Transferable
 
 	for {
 		batch := make([]Transferable, 0, b.batchSize)
+		var size int64
+
+		if next != nil {
+			batch = append(batch, next)
+			size = next.Size()
+			next = nil
+		}
+
 	Loop:
 		for len(batch) < b.batchSize {
 			t, ok := <-b.input
@@ -66,9 +95,22 @@ func (b *Batcher) acceptInput() {
 				exit = true // input channel was closed by Exit()
 				break Loop
 			}
+
+			if b.maxBytes > 0 {
+				if len(batch) > 0 && size+t.Size() > b.maxBytes {
+					next = t
+					break Loop
+				}
+				size += t.Size()
+			}
+
 			batch = append(batch, t)
 		}
 
+		if exit && len(batch) == 0 {
+			return
+		}
+
 		b.batchReady <- batch
 
 		if exit {
@@ -70,3 +70,37 @@ func runBatcherTests(cases []batcherTestCase, t *testing.T) {
 		}
 	}
 }
+
+func TestSizedBatcherMaxBytes(t *testing.T) {
+	b := NewSizedBatcher(10, 100)
+	for _, size := range []int64{40, 40, 40, 150, 10, 10} {
+		b.Add(&Uploadable{size: size})
+	}
+	b.Exit()
+
+	assertBatchSizes(t, b.Next(), 40, 40)
+	assertBatchSizes(t, b.Next(), 40)
+	assertBatchSizes(t, b.Next(), 150)
+	assertBatchSizes(t, b.Next(), 10, 10)
+}
+
+func TestSizedBatcherMaxObjects(t *testing.T) {
+	b := NewSizedBatcher(2, 100)
+	for _, size := range []int64{10, 10, 10} {
+		b.Add(&Uploadable{size: size})
+	}
+	b.Exit()
+
+	assertBatchSizes(t, b.Next(), 10, 10)
+	assertBatchSizes(t, b.Next(), 10)
+}
+
+func assertBatchSizes(t *testing.T, batch []Transferable, sizes ...int64) {
+	if len(batch) != len(sizes) {
+		t.Fatalf("expected batch of %d, got %d", len(sizes), len(batch))
+	}
+
+	for i, size := range sizes {
+		assert.Equal(t, size, batch[i].Size())
+	}
+}
@@ -0,0 +1,261 @@
+package lfs
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/rubyist/tracerx"
+)
+
+// capabilitiesCacheTTL is how long probed capabilities are trusted before the
+// endpoint is probed again.
+const capabilitiesCacheTTL = 24 * time.Hour
+
+// Capabilities describes what a Git LFS server supports, as reported by
+// "GET <lfs url>/capabilities". See docs/api/http-v1-capabilities.md.
+type Capabilities struct {
+	Batch            bool     `json:"batch"`
+	Operations       []string `json:"operations,omitempty"`
+	Transfers        []string `json:"transfers,omitempty"`
+	HashAlgorithms   []string `json:"hash_algorithms,omitempty"`
+	Locking          bool     `json:"locking"`
+	MaxBatchSize     int      `json:"max_batch_size,omitempty"`
+	ContentEncodings []string `json:"content_encodings,omitempty"`
+}
+
+// SupportsOperation returns whether the server supports the given batch API
+// operation, such as "upload", "download", or "delete". Servers that don't list
+// their operations are assumed to support all of them.
+func (c *Capabilities) SupportsOperation(operation string) bool {
+	if len(c.Operations) == 0 {
+		return true
+	}
+
+	for _, op := range c.Operations {
+		if op == operation {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsHashAlgorithm returns whether the server supports objects with OIDs
+// of the given hash algorithm, such as "sha512". Servers that don't list their
+// hash algorithms are assumed to only support "sha256".
+func (c *Capabilities) SupportsHashAlgorithm(name string) bool {
+	if len(c.HashAlgorithms) == 0 {
+		return name == defaultHashAlgorithm.Name
+	}
+
+	for _, a := range c.HashAlgorithms {
+		if a == name {
+			return true
+		}
+	}
+	return false
+}
+
+// AcceptsContentEncoding returns whether the server accepts batch request
+// bodies with the given Content-Encoding, such as "gzip".
+func (c *Capabilities) AcceptsContentEncoding(encoding string) bool {
+	for _, e := range c.ContentEncodings {
+		if strings.EqualFold(e, encoding) {
+			return true
+		}
+	}
+	return false
+}
+
+// capabilitiesCacheEntry is a cached probe of one endpoint. Capabilities is nil
+// if the server does not support the probe.
+type capabilitiesCacheEntry struct {
+	Capabilities *Capabilities `json:"capabilities"`
+	ProbedAt     time.Time     `json:"probed_at"`
+}
+
+var (
+	capabilitiesMu      sync.Mutex
+	capabilitiesEntries map[string]*capabilitiesCacheEntry
+)
+
+// EndpointCapabilities returns the capabilities of the given endpoint, if
+// lfs.probecapabilities is enabled. Results are cached per endpoint in
+// .git/lfs/capabilities.json. It returns nil if probing is disabled, or the
+// server does not support it, in which case the client discovers what the
+// server supports by making requests.
+func EndpointCapabilities(e Endpoint) *Capabilities {
+	if !Config.ProbeCapabilities() || len(e.Url) == 0 {
+		return nil
+	}
+
+	capabilitiesMu.Lock()
+	defer capabilitiesMu.Unlock()
+
+	loadCapabilitiesCache()
+	if entry, ok := capabilitiesEntries[e.Url]; ok && time.Since(entry.ProbedAt) < capabilitiesCacheTTL {
+		return entry.Capabilities
+	}
+
+	caps, err := probeCapabilities(e)
+	if err != nil {
+		tracerx.Printf("capabilities: error probing %s: %s", e.Url, err)
+		return nil
+	}
+
+	capabilitiesEntries[e.Url] = &capabilitiesCacheEntry{Capabilities: caps, ProbedAt: time.Now()}
+	if err := saveCapabilitiesCache(); err != nil {
+		tracerx.Printf("capabilities: error saving cache: %s", err)
+	}
+
+	return caps
+}
+
+// CachedCapabilities returns the cached capabilities of the given endpoint,
+// without probing the server. It returns nil if none are cached, or the server
+// does not support the probe.
+func CachedCapabilities(e Endpoint) *Capabilities {
+	capabilitiesMu.Lock()
+	defer capabilitiesMu.Unlock()
+
+	loadCapabilitiesCache()
+	if entry, ok := capabilitiesEntries[e.Url]; ok {
+		return entry.Capabilities
+	}
+	return nil
+}
+
+// probeCapabilities requests the capabilities of the endpoint. A server that
+// does not support the probe returns nil capabilities and no error, so that the
+// result is cached.
+func probeCapabilities(e Endpoint) (*Capabilities, error) {
+	req, err := newCapabilitiesApiRequest(e)
+	if err != nil {
+		return nil, err
+	}
+
+	tracerx.Printf("capabilities: probing %s", e.Url)
+	useCreds := Config.EndpointAccess(e) != "none"
+	res, err := doAPIRequest(req, useCreds)
+	if err != nil {
+		if res != nil {
+			if IsAuthError(err) && !useCreds {
+				// Like a batch request, remember that the endpoint needs
+				// authentication, and try again with credentials.
+				Config.SetEndpointAccess(e, getAuthType(res))
+				return probeCapabilities(e)
+			}
+
+			switch res.StatusCode {
+			case 404, 405, 410, 501:
+				tracerx.Printf("capabilities: not supported: %d", res.StatusCode)
+				return nil, nil
+			}
+		}
+		return nil, err
+	}
+
+	ctype := res.Header.Get("Content-Type")
+	if !(lfsMediaTypeRE.MatchString(ctype) || jsonMediaTypeRE.MatchString(ctype)) {
+		res.Body.Close()
+		tracerx.Printf("capabilities: not supported: Content-Type %q", ctype)
+		return nil, nil
+	}
+
+	caps := &Capabilities{}
+	err = decodeApiResponse(res, caps)
+	if err != nil {
+		return nil, err
+	}
+
+	return caps, nil
+}
+
+// newCapabilitiesApiRequest builds the GET request for
+// "<lfs url>/capabilities".
+func newCapabilitiesApiRequest(e Endpoint) (*http.Request, error) {
+	res, err := sshAuthenticate(e, "download", "")
+	if err != nil {
+		tracerx.Printf("ssh: capabilities attempted with %s.  Error: %s",
+			e.SshUserAndHost, err.Error(),
+		)
+	}
+
+	if len(res.Href) > 0 {
+		e.Url = res.Href
+	}
+
+	u, err := url.Parse(e.Url)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = path.Join(u.Path, "capabilities")
+
+	req, err := newClientRequest("GET", u.String(), res.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", mediaType)
+	return req, nil
+}
+
+func capabilitiesCachePath() string {
+	if len(LocalGitStorageDir) == 0 {
+		return ""
+	}
+	return filepath.Join(LocalGitStorageDir, "lfs", "capabilities.json")
+}
+
+// loadCapabilitiesCache reads the cache file once per process. It must be
+// called with capabilitiesMu held.
+func loadCapabilitiesCache() {
+	if capabilitiesEntries != nil {
+		return
+	}
+
+	capabilitiesEntries = make(map[string]*capabilitiesCacheEntry)
+
+	cachePath := capabilitiesCachePath()
+	if len(cachePath) == 0 {
+		return
+	}
+
+	file, err := os.Open(cachePath)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	if err := json.NewDecoder(file).Decode(&capabilitiesEntries); err != nil {
+		tracerx.Printf("capabilities: ignoring invalid cache %s: %s", cachePath, err)
+		capabilitiesEntries = make(map[string]*capabilitiesCacheEntry)
+	}
+}
+
+// saveCapabilitiesCache writes the cache file. It must be called with
+// capabilitiesMu held.
+func saveCapabilitiesCache() error {
+	cachePath := capabilitiesCachePath()
+	if len(cachePath) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cachePath), localMediaDirPerms); err != nil {
+		return err
+	}
+
+	by, err := json.MarshalIndent(capabilitiesEntries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(cachePath, by, 0644)
+}
@@ -0,0 +1,98 @@
+package lfs
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/technoweenie/assert"
+)
+
+func TestEndpointCapabilities(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	probes := 0
+	mux.HandleFunc("/capabilities", func(w http.ResponseWriter, r *http.Request) {
+		probes += 1
+		w.Header().Set("Content-Type", mediaType)
+		w.Write([]byte(`{"batch":false,"operations":["download"],"hash_algorithms":["sha256"],"locking":true,"max_batch_size":25}`))
+	})
+
+	dir, err := ioutil.TempDir("", "capabilities")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	oldStorageDir := LocalGitStorageDir
+	LocalGitStorageDir = dir
+	capabilitiesEntries = nil
+	defer func() {
+		LocalGitStorageDir = oldStorageDir
+		capabilitiesEntries = nil
+	}()
+
+	Config.SetConfig("lfs.url", server.URL)
+	defer Config.ResetConfig()
+
+	endpoint := Config.Endpoint()
+	assert.Equal(t, (*Capabilities)(nil), EndpointCapabilities(endpoint))
+	assert.Equal(t, 0, probes)
+
+	Config.SetConfig("lfs.probecapabilities", "true")
+	caps := EndpointCapabilities(endpoint)
+	if caps == nil {
+		t.Fatal("no capabilities")
+	}
+
+	assert.Equal(t, 1, probes)
+	assert.Equal(t, false, caps.Batch)
+	assert.Equal(t, true, caps.Locking)
+	assert.Equal(t, 25, caps.MaxBatchSize)
+	assert.Equal(t, []string{"sha256"}, caps.HashAlgorithms)
+	assert.Equal(t, true, caps.SupportsOperation("download"))
+	assert.Equal(t, false, caps.SupportsOperation("upload"))
+
+	// cached on disk for the next process
+	capabilitiesEntries = nil
+	_, err = os.Stat(filepath.Join(dir, "lfs", "capabilities.json"))
+	assert.Equal(t, nil, err)
+
+	caps = EndpointCapabilities(endpoint)
+	assert.Equal(t, 1, probes)
+	assert.Equal(t, 25, caps.MaxBatchSize)
+}
+
+func TestEndpointCapabilitiesNotSupported(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	probes := 0
+	mux.HandleFunc("/capabilities", func(w http.ResponseWriter, r *http.Request) {
+		probes += 1
+		w.WriteHeader(404)
+	})
+
+	oldStorageDir := LocalGitStorageDir
+	LocalGitStorageDir = ""
+	capabilitiesEntries = nil
+	defer func() {
+		LocalGitStorageDir = oldStorageDir
+		capabilitiesEntries = nil
+	}()
+
+	Config.SetConfig("lfs.url", server.URL)
+	Config.SetConfig("lfs.probecapabilities", "true")
+	defer Config.ResetConfig()
+
+	endpoint := Config.Endpoint()
+	assert.Equal(t, (*Capabilities)(nil), EndpointCapabilities(endpoint))
+	assert.Equal(t, (*Capabilities)(nil), EndpointCapabilities(endpoint))
+	assert.Equal(t, 1, probes)
+}
@@ -0,0 +1,153 @@
+package lfs
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/rubyist/tracerx"
+)
+
+// Values of lfs.checkout.linkmode.
+const (
+	linkModeCopy     = "copy"
+	linkModeHardlink = "hardlink"
+	linkModeReflink  = "reflink"
+)
+
+// readOnlyMediaPerms is the mode of stored objects that are hard linked into
+// the working directory, so that the shared content isn't edited in place.
+const readOnlyMediaPerms = 0444
+
+// linkableMediaFile returns the path of the stored object for the pointer, if
+// it can be linked into the working directory instead of copied. Only objects
+// in the local store with the expected size can be linked, since alternate
+// stores are read-only and the content of pointers with extensions differs
+// from the stored object.
+func linkableMediaFile(ptr *Pointer) (string, bool) {
+	if len(ptr.Extensions) > 0 || ptr.Size <= 0 {
+		return "", false
+	}
+
+	path := localMediaPathNoCreate(ptr.Oid)
+	if !FileExistsOfSize(path, ptr.Size) {
+		return "", false
+	}
+	return path, true
+}
+
+// unlinkWorkingFile removes filename if it is a hard link to the pointer's
+// stored object, so that writing the working file never writes through to the
+// stored object.
+func unlinkWorkingFile(filename string, ptr *Pointer) error {
+	stat, err := os.Stat(filename)
+	if err != nil {
+		return nil
+	}
+
+	mediaStat, err := os.Stat(localMediaPathNoCreate(ptr.Oid))
+	if err != nil || !os.SameFile(stat, mediaStat) {
+		return nil
+	}
+
+	tracerx.Printf("checkout: unlinking %s from the stored object", filename)
+	return os.Remove(filename)
+}
+
+// IsHardLinkedObject returns whether the stored object at path has other hard
+// links, such as working files checked out with lfs.checkout.linkmode=hardlink.
+func IsHardLinkedObject(path string) bool {
+	n, err := linkCount(path)
+	return err == nil && n > 1
+}
+
+// VerifyLinkedObject returns an error if the stored object at path is hard
+// linked and its content no longer matches its OID, because a linked working
+// file was edited in place. Objects without other links aren't read, since Git
+// LFS never writes to stored objects.
+func VerifyLinkedObject(oid, path string) error {
+	if !IsHardLinkedObject(path) {
+		return nil
+	}
+
+	alg := ObjectHashAlgorithm(oid)
+	if alg == nil {
+		return fmt.Errorf("Unknown hash algorithm of object %s", oid)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	hash := alg.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return err
+	}
+
+	if alg.ObjectID(hex.EncodeToString(hash.Sum(nil))) != oid {
+		return errors.New("the object was changed through a hard link to it in the working directory, run git lfs fsck")
+	}
+	return nil
+}
+
+// hardlinkMediaFile replaces filename with a hard link to the pointer's stored
+// object, which is made read-only first. It returns false if the file should be
+// copied instead, such as for executable files, whose mode can't be shared
+// with the stored object, or if the store is on another filesystem. Making the
+// object read-only doesn't stop edits through the link, which VerifyLinkedObject
+// can only detect afterwards.
+func hardlinkMediaFile(filename string, ptr *Pointer) bool {
+	mediafile, ok := linkableMediaFile(ptr)
+	if !ok {
+		return false
+	}
+
+	if stat, err := os.Stat(filename); err == nil && stat.Mode().Perm()&0111 != 0 {
+		return false
+	}
+
+	if err := os.Chmod(mediafile, readOnlyMediaPerms); err != nil {
+		tracerx.Printf("checkout: cannot make %s read-only: %s", mediafile, err)
+		return false
+	}
+
+	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		tracerx.Printf("checkout: cannot remove %s: %s", filename, err)
+		return false
+	}
+
+	if err := os.Link(mediafile, filename); err != nil {
+		tracerx.Printf("checkout: cannot hard link %s: %s", filename, err)
+		return false
+	}
+
+	return true
+}
+
+// reflinkMediaFile makes file a copy-on-write clone of the pointer's stored
+// object, so that they share content until either is changed. It returns false
+// if the content should be copied instead, such as when the filesystem doesn't
+// support reflinks.
+func reflinkMediaFile(file *os.File, ptr *Pointer) bool {
+	mediafile, ok := linkableMediaFile(ptr)
+	if !ok {
+		return false
+	}
+
+	src, err := os.Open(mediafile)
+	if err != nil {
+		return false
+	}
+	defer src.Close()
+
+	if err := reflinkFile(file, src); err != nil {
+		tracerx.Printf("checkout: cannot reflink %s: %s", file.Name(), err)
+		return false
+	}
+
+	return true
+}
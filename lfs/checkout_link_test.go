@@ -0,0 +1,116 @@
+package lfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/technoweenie/assert"
+)
+
+func TestPointerSmudgeToFileLinkModes(t *testing.T) {
+	tmp := tempdir(t)
+	defer os.RemoveAll(tmp)
+
+	oldMediaDir := LocalMediaDir
+	LocalMediaDir = filepath.Join(tmp, "objects")
+	defer func() {
+		LocalMediaDir = oldMediaDir
+	}()
+	defer Config.ResetConfig()
+
+	oid := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	mediafile, err := LocalStoreMediaPath(oid)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, ioutil.WriteFile(mediafile, []byte("test"), 0644))
+
+	ptr := NewPointer(oid, 4, nil)
+	filename := filepath.Join(tmp, "work", "a.dat")
+
+	Config.SetConfig("lfs.checkout.linkmode", "hardlink")
+	assert.Equal(t, nil, PointerSmudgeToFile(filename, ptr, false, nil))
+	assertSameFile(t, true, filename, mediafile)
+
+	stat, err := os.Stat(mediafile)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, os.FileMode(readOnlyMediaPerms), stat.Mode().Perm())
+
+	// Copying over a hard link replaces the link, instead of writing
+	// through it to the stored object.
+	Config.SetConfig("lfs.checkout.linkmode", "copy")
+	assert.Equal(t, nil, PointerSmudgeToFile(filename, ptr, false, nil))
+	assertSameFile(t, false, filename, mediafile)
+
+	by, err := ioutil.ReadFile(filename)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "test", string(by))
+
+	by, err = ioutil.ReadFile(mediafile)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "test", string(by))
+
+	// Reflinks fall back to copies where they aren't supported.
+	Config.SetConfig("lfs.checkout.linkmode", "reflink")
+	assert.Equal(t, nil, PointerSmudgeToFile(filename, ptr, false, nil))
+	assertSameFile(t, false, filename, mediafile)
+
+	by, err = ioutil.ReadFile(filename)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "test", string(by))
+}
+
+func TestVerifyLinkedObject(t *testing.T) {
+	tmp := tempdir(t)
+	defer os.RemoveAll(tmp)
+
+	oid := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	mediafile := filepath.Join(tmp, oid)
+	assert.Equal(t, nil, ioutil.WriteFile(mediafile, []byte("test"), 0644))
+	assert.Equal(t, false, IsHardLinkedObject(mediafile))
+
+	// objects without links aren't checked
+	assert.Equal(t, nil, ioutil.WriteFile(mediafile, []byte("edited"), 0644))
+	assert.Equal(t, nil, VerifyLinkedObject(oid, mediafile))
+
+	assert.Equal(t, nil, ioutil.WriteFile(mediafile, []byte("test"), 0644))
+	linked := filepath.Join(tmp, "a.dat")
+	assert.Equal(t, nil, os.Link(mediafile, linked))
+	assert.Equal(t, true, IsHardLinkedObject(mediafile))
+	assert.Equal(t, nil, VerifyLinkedObject(oid, mediafile))
+
+	// editing the working file in place changes the object too
+	assert.Equal(t, nil, ioutil.WriteFile(linked, []byte("edited"), 0644))
+	assert.NotEqual(t, nil, VerifyLinkedObject(oid, mediafile))
+}
+
+func TestCheckoutLinkMode(t *testing.T) {
+	tests := map[string]string{
+		"":         "copy",
+		"copy":     "copy",
+		"Hardlink": "hardlink",
+		"reflink":  "reflink",
+		"symlink":  "copy",
+	}
+
+	for value, expected := range tests {
+		config := &Configuration{
+			gitConfig: map[string]string{"lfs.checkout.linkmode": value},
+		}
+
+		if actual := config.CheckoutLinkMode(); actual != expected {
+			t.Errorf("lfs.checkout.linkmode %q == %q, not %q", value, actual, expected)
+		}
+	}
+}
+
+func assertSameFile(t *testing.T, expected bool, a, b string) {
+	statA, err := os.Stat(a)
+	assert.Equal(t, nil, err)
+	statB, err := os.Stat(b)
+	assert.Equal(t, nil, err)
+
+	if os.SameFile(statA, statB) != expected {
+		t.Errorf("expected os.SameFile(%q, %q) to be %v", a, b, expected)
+	}
+}
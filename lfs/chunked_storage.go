@@ -0,0 +1,341 @@
+package lfs
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/rubyist/tracerx"
+)
+
+// Large objects can be stored in chunks, as set by lfs.chunking, so that
+// versions of a file that only differ in a few places share most of their
+// storage. A chunked object is a manifest next to where the raw object would
+// be, with a ".chunks" extension, listing the OID and size of each chunk in
+// order. The chunks are stored once each, by their own OID, in a "chunks"
+// directory next to the "objects" directory of the store.
+const chunkManifestExt = ".chunks"
+
+// objectChunk is one chunk of a chunked object.
+type objectChunk struct {
+	Oid  string
+	Size int64
+}
+
+func chunkManifestPath(path string) string {
+	return path + chunkManifestExt
+}
+
+// chunkStoreDir returns the directory with the chunks of the objects in
+// objectsDir.
+func chunkStoreDir(objectsDir string) string {
+	return filepath.Join(filepath.Dir(objectsDir), "chunks")
+}
+
+// chunkPath returns the path of a chunk of the object at path, which is in the
+// chunk store of the object's store.
+func chunkPath(path, oid string) string {
+	return filepath.Join(chunkStoreDir(objectStoreDir(path)), oid[0:2], oid[2:4], oid)
+}
+
+// readChunkManifest reads the chunks of the object at path.
+func readChunkManifest(path string) ([]*objectChunk, error) {
+	manifest := chunkManifestPath(path)
+	file, err := os.Open(manifest)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var chunks []*objectChunk
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || len(fields[0]) != 64 || !oidRE.MatchString(fields[0]) {
+			return nil, fmt.Errorf("invalid chunk manifest %q", manifest)
+		}
+
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("invalid chunk size in manifest %q", manifest)
+		}
+
+		chunks = append(chunks, &objectChunk{Oid: fields[0], Size: size})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("empty chunk manifest %q", manifest)
+	}
+
+	return chunks, nil
+}
+
+// writeChunkManifest atomically writes the manifest of the object at path,
+// using a temp file in tempDir.
+func writeChunkManifest(path, tempDir string, chunks []*objectChunk) error {
+	tmp, err := ioutil.TempFile(tempDir, filepath.Base(path)+"-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	w := bufio.NewWriter(tmp)
+	for _, c := range chunks {
+		fmt.Fprintf(w, "%s %d\n", c.Oid, c.Size)
+	}
+
+	err = w.Flush()
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), chunkManifestPath(path))
+}
+
+// storedChunks returns the paths of the chunks of the object at path, and the
+// object's size, after checking that all of its chunks are stored.
+func storedChunks(path string) ([]string, int64, error) {
+	chunks, err := readChunkManifest(path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	paths := make([]string, 0, len(chunks))
+	var size int64
+	for _, c := range chunks {
+		chunkfile := chunkPath(path, c.Oid)
+		if !FileExistsOfSize(chunkfile, c.Size) {
+			return nil, 0, fmt.Errorf("chunk %s of object %s is missing", c.Oid, filepath.Base(path))
+		}
+		paths = append(paths, chunkfile)
+		size += c.Size
+	}
+
+	return paths, size, nil
+}
+
+func statChunkedObject(path string) (int64, error) {
+	_, size, err := storedChunks(path)
+	return size, err
+}
+
+// openChunkedObject opens the content of the chunked object at path, reading
+// its chunks in order.
+func openChunkedObject(path string) (io.ReadCloser, int64, error) {
+	paths, size, err := storedChunks(path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return &chunkedObjectReader{paths: paths}, size, nil
+}
+
+type chunkedObjectReader struct {
+	paths []string
+	file  *os.File
+}
+
+func (r *chunkedObjectReader) Read(p []byte) (int, error) {
+	for {
+		if r.file == nil {
+			if len(r.paths) == 0 {
+				return 0, io.EOF
+			}
+
+			file, err := os.Open(r.paths[0])
+			if err != nil {
+				return 0, err
+			}
+			r.file = file
+			r.paths = r.paths[1:]
+		}
+
+		n, err := r.file.Read(p)
+		if err == io.EOF {
+			r.file.Close()
+			r.file = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (r *chunkedObjectReader) Close() error {
+	if r.file == nil {
+		return nil
+	}
+	return r.file.Close()
+}
+
+// storedChunkMatches returns whether the chunk stored at path has the size and
+// OID of c.
+func storedChunkMatches(path string, c *objectChunk) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	verifier := newVerifyingWriter(ioutil.Discard, c.Oid, c.Size)
+	_, err = io.Copy(verifier, file)
+	return err == nil && verifier.Verify() == nil
+}
+
+// storeChunkedObject splits tmpfile into chunks, stores the chunks that aren't
+// stored yet, and writes the manifest of the object at path. It returns false
+// if the object is too small to be split.
+func storeChunkedObject(tmpfile, path string) (bool, error) {
+	src, err := os.Open(tmpfile)
+	if err != nil {
+		return false, err
+	}
+	defer src.Close()
+
+	stat, err := src.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	if stat.Size() <= chunkMaxSize {
+		return false, nil
+	}
+
+	tempDir := filepath.Dir(tmpfile)
+	var chunks []*objectChunk
+	var stored int
+	err = splitChunks(src, func(data []byte) error {
+		hash := sha256.Sum256(data)
+		c := &objectChunk{Oid: hex.EncodeToString(hash[:]), Size: int64(len(data))}
+		chunks = append(chunks, c)
+
+		dest := chunkPath(path, c.Oid)
+		if FileExistsOfSize(dest, c.Size) {
+			return nil
+		}
+
+		stored++
+		return storeChunk(dest, tempDir, data)
+	})
+	if err != nil {
+		return false, fmt.Errorf("cannot chunk %q: %v", tmpfile, err)
+	}
+
+	if err := writeChunkManifest(path, tempDir, chunks); err != nil {
+		return false, err
+	}
+
+	tracerx.Printf("chunking: stored %s in %d chunks, %d of them new", filepath.Base(path), len(chunks), stored)
+
+	src.Close()
+	os.Remove(tmpfile)
+	os.Remove(path)
+	os.Remove(compressedObjectPath(path))
+	return true, nil
+}
+
+// storeChunk atomically writes the content of a chunk to dest, using a temp
+// file in tempDir.
+func storeChunk(dest, tempDir string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(dest), localMediaDirPerms); err != nil {
+		return err
+	}
+
+	// Prefix the temp file with the chunk's OID, like other temp objects,
+	// so that ClearTempObjects doesn't treat it as invalid.
+	tmp, err := ioutil.TempFile(tempDir, filepath.Base(dest)+"-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), dest)
+}
+
+// localObjectChunks returns the chunks of the object at path as resources for
+// the batch API, or nil if the object isn't stored in chunks.
+func localObjectChunks(path string) []*ObjectResource {
+	if StoredObjectFile(path) != chunkManifestPath(path) {
+		return nil
+	}
+
+	chunks, err := readChunkManifest(path)
+	if err != nil {
+		tracerx.Printf("chunking: %v", err)
+		return nil
+	}
+
+	resources := make([]*ObjectResource, 0, len(chunks))
+	for _, c := range chunks {
+		resources = append(resources, &ObjectResource{Oid: c.Oid, Size: c.Size})
+	}
+	return resources
+}
+
+// RemoveUnreferencedChunks removes the chunks in the local store that no
+// stored object refers to anymore, such as after objects are pruned. It
+// returns the number of chunks removed, and their total size.
+func RemoveUnreferencedChunks() (int, int64, error) {
+	referenced := NewStringSet()
+	err := filepath.Walk(LocalMediaDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(path) != chunkManifestExt {
+			return err
+		}
+
+		chunks, err := readChunkManifest(strings.TrimSuffix(path, chunkManifestExt))
+		if err != nil {
+			return err
+		}
+		for _, c := range chunks {
+			referenced.Add(c.Oid)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var removed int
+	var removedSize int64
+	err = filepath.Walk(chunkStoreDir(LocalMediaDir), func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil || info.IsDir() || referenced.Contains(info.Name()) {
+			return err
+		}
+
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed++
+		removedSize += info.Size()
+		return nil
+	})
+
+	return removed, removedSize, err
+}
@@ -0,0 +1,98 @@
+package lfs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/technoweenie/assert"
+)
+
+func TestChunkedLocalObjects(t *testing.T) {
+	tmp := tempdir(t)
+	defer os.RemoveAll(tmp)
+
+	oldMediaDir := LocalMediaDir
+	LocalMediaDir = filepath.Join(tmp, "objects")
+	defer func() {
+		LocalMediaDir = oldMediaDir
+	}()
+
+	Config.SetConfig("lfs.chunking", "true")
+	defer Config.ResetConfig()
+
+	small := []byte("too small to chunk")
+	large := make([]byte, 12*1024*1024)
+	rand.New(rand.NewSource(1)).Read(large)
+	edited := append([]byte{}, large...)
+	copy(edited[6000000:], "edited")
+
+	smallPath := storeTestObject(t, tmp, small)
+	largePath := storeTestObject(t, tmp, large)
+
+	// only objects larger than a chunk are chunked
+	assert.Equal(t, smallPath, StoredObjectFile(smallPath))
+	assert.Equal(t, largePath+".chunks", StoredObjectFile(largePath))
+	assert.Equal(t, false, FileExists(largePath))
+
+	size, err := StatLocalObject(largePath)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(len(large)), size)
+
+	reader, size, err := OpenLocalObject(largePath)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(len(large)), size)
+	content, err := ioutil.ReadAll(reader)
+	reader.Close()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, bytes.Equal(large, content))
+
+	chunks := localObjectChunks(largePath)
+	assert.Equal(t, true, len(chunks) > 2)
+	assert.Equal(t, 0, len(localObjectChunks(smallPath)))
+
+	// a similar version shares most chunks
+	editedPath := storeTestObject(t, tmp, edited)
+	editedChunks := localObjectChunks(editedPath)
+	shared := NewStringSet()
+	for _, c := range chunks {
+		shared.Add(c.Oid)
+	}
+	var changed int
+	for _, c := range editedChunks {
+		if !shared.Contains(c.Oid) {
+			changed++
+		}
+	}
+	assert.Equal(t, 1, changed)
+
+	sizes := make(map[string]int64)
+	for _, p := range AllLocalObjects() {
+		sizes[p.Oid] = p.Size
+	}
+	assert.Equal(t, 3, len(sizes))
+	assert.Equal(t, int64(len(large)), sizes[filepath.Base(largePath)])
+
+	// only chunks that no object refers to are removed
+	assert.Equal(t, nil, RemoveLocalObject(largePath))
+	assert.Equal(t, "", StoredObjectFile(largePath))
+	removed, _, err := RemoveUnreferencedChunks()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, removed)
+
+	reader, _, err = OpenLocalObject(editedPath)
+	assert.Equal(t, nil, err)
+	content, err = ioutil.ReadAll(reader)
+	reader.Close()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, bytes.Equal(edited, content))
+
+	// objects with missing chunks aren't stored
+	assert.Equal(t, nil, os.Remove(chunkPath(editedPath, editedChunks[0].Oid)))
+	_, err = StatLocalObject(editedPath)
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, false, ObjectExistsOfSize(filepath.Base(editedPath), int64(len(edited))))
+}
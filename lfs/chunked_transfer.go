@@ -0,0 +1,208 @@
+package lfs
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/rubyist/tracerx"
+)
+
+// A server that supports chunks gets the chunks of each object stored in
+// chunks with batch API upload requests. If it doesn't have the object, it
+// responds with the chunks instead of an upload action for the object, with
+// upload actions for the chunks that it doesn't have, and a verify action to
+// put the object together. Clients ask for the chunks of the objects they
+// download with a "chunking" property in the request, and skip the chunks they
+// already have. Servers that don't support chunks ignore both, and objects are
+// transferred whole.
+
+// chunkCallback reports the progress of a chunk that starts at offset as the
+// progress of the whole object.
+func chunkCallback(cb CopyCallback, total, offset int64) CopyCallback {
+	if cb == nil {
+		return nil
+	}
+
+	return func(_, read int64, current int) error {
+		return cb(total, offset+read, current)
+	}
+}
+
+// validChunks checks the chunks in a batch API response for o.
+func validChunks(o *ObjectResource) ([]*objectChunk, error) {
+	chunks := make([]*objectChunk, 0, len(o.Chunks))
+	var size int64
+	for _, c := range o.Chunks {
+		if len(c.Oid) != 64 || !oidRE.MatchString(c.Oid) || c.Size <= 0 {
+			return nil, Errorf(nil, "[%s] Invalid chunk %q", o.Oid, c.Oid)
+		}
+		chunks = append(chunks, &objectChunk{Oid: c.Oid, Size: c.Size})
+		size += c.Size
+	}
+
+	if size != o.Size {
+		return nil, Errorf(nil, "[%s] Chunks add up to %d bytes, expected %d", o.Oid, size, o.Size)
+	}
+
+	return chunks, nil
+}
+
+// uploadChunkedObject uploads the chunks of o that have an upload action, and
+// asks the server to verify the object once they are all uploaded.
+func uploadChunkedObject(o *ObjectResource, cb CopyCallback) error {
+	path, err := LocalMediaPath(o.Oid)
+	if err != nil {
+		return Error(err)
+	}
+
+	// Only the chunks in the local manifest are sent, and the server is
+	// told to put the object together from them.
+	local := localObjectChunks(path)
+	if len(local) == 0 {
+		return Errorf(nil, "[%s] The server asked for chunks, but the object isn't stored in chunks", o.Oid)
+	}
+	stored := NewStringSet()
+	for _, c := range local {
+		stored.Add(c.Oid)
+	}
+
+	var offset int64
+	var uploaded int
+	for _, c := range o.Chunks {
+		if _, ok := c.Rel("upload"); !ok {
+			if cb != nil {
+				cb(o.Size, offset+c.Size, int(c.Size))
+			}
+			offset += c.Size
+			continue
+		}
+
+		if !stored.Contains(c.Oid) {
+			return Errorf(nil, "[%s] The server asked for unknown chunk %q", o.Oid, c.Oid)
+		}
+
+		file, err := os.Open(chunkPath(path, c.Oid))
+		if err != nil {
+			return Error(err)
+		}
+
+		reader := &CallbackReader{
+			C:         chunkCallback(cb, o.Size, offset),
+			TotalSize: c.Size,
+			Reader:    file,
+		}
+
+		err = putObject(c, reader)
+		file.Close()
+		if err != nil {
+			return err
+		}
+
+		offset += c.Size
+		uploaded++
+	}
+
+	tracerx.Printf("chunking: uploaded %d of %d chunks of %s", uploaded, len(o.Chunks), o.Oid)
+
+	return verifyUpload(o, &ObjectResource{Oid: objectIDHex(o.Oid), Size: o.Size, Chunks: local})
+}
+
+// downloadChunkedObject downloads the chunks of obj that aren't in the local
+// store, and stores obj in chunks at mediafile once the content of all of
+// them is verified against the object's OID.
+func downloadChunkedObject(obj *ObjectResource, mediafile string, cb CopyCallback) error {
+	chunks, err := validChunks(obj)
+	if err != nil {
+		return err
+	}
+
+	var offset int64
+	var downloaded int
+	for i, c := range chunks {
+		// Stored chunks are checked before they're used, so that a
+		// corrupt chunk is downloaded again instead of corrupting
+		// every object that shares it.
+		dest := chunkPath(mediafile, c.Oid)
+		if storedChunkMatches(dest, c) {
+			if cb != nil {
+				cb(obj.Size, offset+c.Size, int(c.Size))
+			}
+			offset += c.Size
+			continue
+		}
+
+		if err := downloadChunk(obj.Chunks[i], dest, chunkCallback(cb, obj.Size, offset)); err != nil {
+			return err
+		}
+
+		offset += c.Size
+		downloaded++
+	}
+
+	tracerx.Printf("chunking: downloaded %d of %d chunks of %s", downloaded, len(chunks), obj.Oid)
+
+	paths := make([]string, 0, len(chunks))
+	for _, c := range chunks {
+		paths = append(paths, chunkPath(mediafile, c.Oid))
+	}
+
+	reader := &chunkedObjectReader{paths: paths}
+	defer reader.Close()
+
+	verifier := newVerifyingWriter(ioutil.Discard, obj.Oid, obj.Size)
+	if _, err := io.Copy(verifier, reader); err != nil {
+		return err
+	}
+	if err := verifier.Verify(); err != nil {
+		return err
+	}
+
+	if err := writeChunkManifest(mediafile, LocalObjectTempDir, chunks); err != nil {
+		return Errorf(err, "Error storing chunks of %s", obj.Oid)
+	}
+
+	os.Remove(mediafile)
+	os.Remove(compressedObjectPath(mediafile))
+	return nil
+}
+
+// downloadChunk downloads the chunk c to dest, verifying its content against
+// its OID.
+func downloadChunk(c *ObjectResource, dest string, cb CopyCallback) error {
+	reader, _, err := DownloadObject(c)
+	if reader != nil {
+		defer reader.Close()
+	}
+	if err != nil {
+		return Errorf(err, "Error downloading chunk %s", c.Oid)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), localMediaDirPerms); err != nil {
+		return Error(err)
+	}
+
+	tmp, err := ioutil.TempFile(LocalObjectTempDir, c.Oid+"-")
+	if err != nil {
+		return Error(err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	verifier := newVerifyingWriter(tmp, c.Oid, c.Size)
+	_, err = CopyWithCallback(verifier, reader, c.Size, cb)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = verifier.Verify()
+	}
+	if err != nil {
+		if IsIntegrityError(err) {
+			return err
+		}
+		return Errorf(err, "Error writing chunk %s", c.Oid)
+	}
+
+	return os.Rename(tmp.Name(), dest)
+}
@@ -0,0 +1,92 @@
+package lfs
+
+import (
+	"io"
+)
+
+// Objects are split into chunks by content-defined chunking, so that an edit
+// to one part of a large file only changes the chunks around it. A chunk ends
+// where a rolling "gear" hash of the last 64 bytes has its top chunkAvgBits
+// bits unset, which happens once every 1MiB on average, and chunks are kept
+// between chunkMinSize and chunkMaxSize. Since boundaries only depend on the
+// content around them, inserting or removing bytes shifts the boundaries
+// along with the content instead of changing every chunk after the edit.
+const (
+	chunkMinSize = 256 * 1024
+	chunkAvgBits = 20
+	chunkMaxSize = 4 * 1024 * 1024
+)
+
+// gearTable maps each byte to a random value for the gear hash. It is
+// generated from a fixed seed, so that every client finds the same chunk
+// boundaries for the same content.
+var gearTable = newGearTable(0x6769742d6c6673)
+
+func newGearTable(seed uint64) [256]uint64 {
+	var table [256]uint64
+	for i := range table {
+		// splitmix64
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+	return table
+}
+
+// chunkBoundary returns the length of the first chunk of data, which is all of
+// data if it is no longer than chunkMinSize.
+func chunkBoundary(data []byte) int {
+	if len(data) <= chunkMinSize {
+		return len(data)
+	}
+
+	end := len(data)
+	if end > chunkMaxSize {
+		end = chunkMaxSize
+	}
+
+	// The hash only depends on the last 64 bytes, so it is started just
+	// before the first possible boundary.
+	var hash uint64
+	for i := chunkMinSize - 64; i < end; i++ {
+		hash = (hash << 1) + gearTable[data[i]]
+		if i >= chunkMinSize && hash>>(64-chunkAvgBits) == 0 {
+			return i + 1
+		}
+	}
+
+	return end
+}
+
+// splitChunks reads r to the end and calls fn with each content-defined chunk
+// of it, in order. The chunk is only valid until fn returns.
+func splitChunks(r io.Reader, fn func(chunk []byte) error) error {
+	buf := make([]byte, chunkMaxSize)
+	buffered := 0
+	eof := false
+
+	for {
+		if !eof && buffered < len(buf) {
+			n, err := io.ReadFull(r, buf[buffered:])
+			buffered += n
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				eof = true
+			} else if err != nil {
+				return err
+			}
+		}
+
+		if buffered == 0 {
+			return nil
+		}
+
+		n := chunkBoundary(buf[:buffered])
+		if err := fn(buf[:n]); err != nil {
+			return err
+		}
+
+		buffered = copy(buf, buf[n:buffered])
+	}
+}
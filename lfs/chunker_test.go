@@ -0,0 +1,69 @@
+package lfs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/rand"
+	"testing"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/technoweenie/assert"
+)
+
+func TestSplitChunks(t *testing.T) {
+	content := make([]byte, 12*1024*1024)
+	rand.New(rand.NewSource(1)).Read(content)
+
+	chunks := testChunks(t, content)
+	assert.Equal(t, true, len(chunks) > 2)
+
+	var joined []byte
+	for i, chunk := range chunks {
+		if i < len(chunks)-1 {
+			assert.Equalf(t, true, len(chunk) >= chunkMinSize, "chunk %d is %d bytes", i, len(chunk))
+		}
+		assert.Equalf(t, true, len(chunk) <= chunkMaxSize, "chunk %d is %d bytes", i, len(chunk))
+		joined = append(joined, chunk...)
+	}
+	assert.Equal(t, true, bytes.Equal(content, joined))
+
+	// chunks after an insertion are the same as before it
+	edited := append(append(append([]byte{}, content[:5000000]...), []byte("inserted")...), content[5000000:]...)
+	editedChunks := testChunks(t, edited)
+
+	before := NewStringSet()
+	for _, chunk := range chunks {
+		before.Add(testChunkOid(chunk))
+	}
+
+	var changed int
+	for _, chunk := range editedChunks {
+		if !before.Contains(testChunkOid(chunk)) {
+			changed++
+		}
+	}
+	assert.Equalf(t, true, changed > 0 && changed <= 2, "%d of %d chunks changed", changed, len(editedChunks))
+}
+
+func TestSplitChunksSmallContent(t *testing.T) {
+	chunks := testChunks(t, []byte("small"))
+	assert.Equal(t, 1, len(chunks))
+	assert.Equal(t, "small", string(chunks[0]))
+
+	assert.Equal(t, 0, len(testChunks(t, nil)))
+}
+
+func testChunks(t *testing.T, content []byte) [][]byte {
+	var chunks [][]byte
+	err := splitChunks(bytes.NewReader(content), func(chunk []byte) error {
+		chunks = append(chunks, append([]byte{}, chunk...))
+		return nil
+	})
+	assert.Equal(t, nil, err)
+	return chunks
+}
+
+func testChunkOid(chunk []byte) string {
+	hash := sha256.Sum256(chunk)
+	return hex.EncodeToString(hash[:])
+}
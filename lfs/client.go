@@ -2,6 +2,7 @@ package lfs
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -11,7 +12,6 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -54,6 +54,7 @@ type ObjectResource struct {
 	Size    int64                    `json:"size"`
 	Actions map[string]*linkRelation `json:"actions,omitempty"`
 	Links   map[string]*linkRelation `json:"_links,omitempty"`
+	Chunks  []*ObjectResource        `json:"chunks,omitempty"`
 	Error   *objectError             `json:"error,omitempty"`
 }
 
@@ -114,16 +115,23 @@ func Download(oid string, size int64) (io.ReadCloser, int64, error) {
 		return DownloadLegacy(oid)
 	}
 
+	if caps := EndpointCapabilities(Config.Endpoint()); caps != nil && !caps.Batch {
+		return DownloadLegacy(oid)
+	}
+
 	objects := []*ObjectResource{
 		&ObjectResource{Oid: oid, Size: size},
 	}
 
-	objs, err := Batch(objects, "download")
+	objs, err := Batch(objects, "download", "")
 	if err != nil {
-		if IsNotImplementedError(err) {
+		if IsNotImplementedError(err) && Config.LegacyApi() {
 			git.Config.SetLocal("", "lfs.batch", "false")
 			return DownloadLegacy(oid)
 		}
+		if IsNotImplementedError(err) {
+			return nil, 0, legacyApiDisabledError(Config.Endpoint())
+		}
 		return nil, 0, err
 	}
 
@@ -131,12 +139,52 @@ func Download(oid string, size int64) (io.ReadCloser, int64, error) {
 		return nil, 0, Error(fmt.Errorf("Object not found: %s", oid))
 	}
 
+	if o := objs[0]; o.Error != nil {
+		err := Errorf(o.Error, "[%v] %v", o.Oid, o.Error.Message)
+		if o.Error.Code == 404 {
+			return nil, 0, newObjectNotFoundError(err, oid)
+		}
+		return nil, 0, err
+	}
+
 	return DownloadObject(objs[0])
 }
 
+// downloadFromRemotes works like Download, but tries each of the remotes in
+// Config.FetchRemotes() in turn, moving on to the next one whenever the
+// object is not found on the current remote.
+func downloadFromRemotes(oid string, size int64) (io.ReadCloser, int64, error) {
+	remotes := Config.FetchRemotes()
+	currentRemote := Config.CurrentRemote
+	defer func() {
+		Config.CurrentRemote = currentRemote
+	}()
+
+	for i, remote := range remotes {
+		Config.CurrentRemote = remote
+
+		reader, size, err := Download(oid, size)
+		if err != nil && IsObjectNotFoundError(err) && i < len(remotes)-1 {
+			tracerx.Printf("download: %s not found on %s, trying %s", oid, remote, remotes[i+1])
+			continue
+		}
+
+		if err == nil {
+			tracerx.Printf("download: %s from %s", oid, remote)
+		}
+		return reader, size, err
+	}
+
+	return nil, 0, newObjectNotFoundError(nil, oid)
+}
+
 // DownloadLegacy attempts to download the object for the given oid using the
 // legacy API.
 func DownloadLegacy(oid string) (io.ReadCloser, int64, error) {
+	if !Config.LegacyApi() {
+		return nil, 0, legacyApiDisabledError(Config.Endpoint())
+	}
+
 	req, err := newApiRequest("GET", oid)
 	if err != nil {
 		return nil, 0, Error(err)
@@ -166,6 +214,10 @@ type byteCloser struct {
 }
 
 func DownloadCheck(oid string) (*ObjectResource, error) {
+	if !Config.LegacyApi() {
+		return nil, legacyApiDisabledError(Config.Endpoint())
+	}
+
 	req, err := newApiRequest("GET", oid)
 	if err != nil {
 		return nil, Error(err)
@@ -204,12 +256,106 @@ func (b *byteCloser) Close() error {
 	return nil
 }
 
-func Batch(objects []*ObjectResource, operation string) ([]*ObjectResource, error) {
+// setBatchRequestBody sets the JSON body of a batch request. The body is
+// gzipped if the server accepts gzipped request bodies, as reported by its
+// capabilities, and gzipping makes it smaller.
+func setBatchRequestBody(req *http.Request, by []byte) {
+	if caps := EndpointCapabilities(Config.Endpoint()); caps != nil && caps.AcceptsContentEncoding("gzip") {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		if _, err := gz.Write(by); err == nil && gz.Close() == nil && buf.Len() < len(by) {
+			tracerx.Printf("api: gzipped batch request from %d to %d bytes", len(by), buf.Len())
+			req.Header.Set("Content-Encoding", "gzip")
+			by = buf.Bytes()
+		}
+	}
+
+	req.Header.Set("Content-Length", strconv.Itoa(len(by)))
+	req.ContentLength = int64(len(by))
+	req.Body = &byteCloser{bytes.NewReader(by)}
+}
+
+// Batch requests the given objects from the batch API for the given operation,
+// "upload" or "download". The ref is optional, and is sent so the server can
+// authorize the request by branch. Objects of different hash algorithms are
+// requested separately.
+func Batch(objects []*ObjectResource, operation, ref string) ([]*ObjectResource, error) {
+	return batchByHashAlgorithm(objects, func(alg *HashAlgorithm, objects []*ObjectResource) ([]*ObjectResource, error) {
+		return batch(objects, operation, ref, alg)
+	})
+}
+
+// batchByHashAlgorithm calls fn with the objects of each hash algorithm in
+// turn, with the bare hex OIDs that the API uses, and returns the objects fn
+// returns with their OIDs tagged again.
+func batchByHashAlgorithm(objects []*ObjectResource, fn func(*HashAlgorithm, []*ObjectResource) ([]*ObjectResource, error)) ([]*ObjectResource, error) {
 	if len(objects) == 0 {
 		return nil, nil
 	}
 
+	var algs []*HashAlgorithm
+	byAlg := make(map[*HashAlgorithm][]*ObjectResource)
+	for _, o := range objects {
+		alg := ObjectHashAlgorithm(o.Oid)
+		if alg == nil {
+			return nil, Error(fmt.Errorf("Unknown hash algorithm of object %s", o.Oid))
+		}
+		if _, ok := byAlg[alg]; !ok {
+			algs = append(algs, alg)
+		}
+		byAlg[alg] = append(byAlg[alg], apiObject(o))
+	}
+
+	var results []*ObjectResource
+	for _, alg := range algs {
+		objs, err := fn(alg, byAlg[alg])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, obj := range objs {
+			if alg.validHex(obj.Oid) {
+				obj.Oid = alg.ObjectID(obj.Oid)
+			}
+		}
+		results = append(results, objs...)
+	}
+
+	return results, nil
+}
+
+// apiObject returns o as it is sent to the API, with the bare hex OID. The hash
+// algorithm of the OID is sent with the request.
+func apiObject(o *ObjectResource) *ObjectResource {
+	if ObjectHashAlgorithm(o.Oid) == defaultHashAlgorithm {
+		return o
+	}
+
+	api := *o
+	api.Oid = objectIDHex(o.Oid)
+	return &api
+}
+
+// batch requests objects whose OIDs are computed with alg from the batch API.
+func batch(objects []*ObjectResource, operation, ref string, alg *HashAlgorithm) ([]*ObjectResource, error) {
 	o := map[string]interface{}{"objects": objects, "operation": operation}
+	if len(ref) > 0 {
+		// Lets the server authorize the request by the ref being pushed or
+		// fetched, such as "refs/heads/master".
+		o["ref"] = map[string]string{"name": ref}
+	}
+	if operation == "download" && Config.Chunking() {
+		// Asks the server to list the chunks of objects it stores in
+		// chunks, so that chunks stored locally aren't downloaded again.
+		o["chunking"] = true
+	}
+	if alg != defaultHashAlgorithm {
+		// Left out for SHA-256, which all servers support.
+		if caps := EndpointCapabilities(Config.Endpoint()); caps != nil && !caps.SupportsHashAlgorithm(alg.Name) {
+			return nil, Error(fmt.Errorf("The Git LFS server does not support %s objects", alg.Name))
+		}
+		o["hash_algo"] = alg.Name
+	}
 
 	by, err := json.Marshal(o)
 	if err != nil {
@@ -222,17 +368,23 @@ func Batch(objects []*ObjectResource, operation string) ([]*ObjectResource, erro
 	}
 
 	req.Header.Set("Content-Type", mediaType)
-	req.Header.Set("Content-Length", strconv.Itoa(len(by)))
-	req.ContentLength = int64(len(by))
-	req.Body = &byteCloser{bytes.NewReader(by)}
+	setBatchRequestBody(req, by)
 
-	tracerx.Printf("api: batch %d files", len(objects))
+	if len(ref) > 0 {
+		tracerx.Printf("api: batch %d files for %s", len(objects), ref)
+	} else {
+		tracerx.Printf("api: batch %d files", len(objects))
+	}
 
-	res, objs, err := doApiBatchRequest(req)
+	res, objs, err := doApiBatchRequest(req, false)
 
 	if err != nil {
 
 		if res == nil {
+			// the credentials could not be filled, so retrying won't help
+			if IsAuthError(err) {
+				return nil, err
+			}
 			return nil, newRetriableError(err)
 		}
 
@@ -242,7 +394,7 @@ func Batch(objects []*ObjectResource, operation string) ([]*ObjectResource, erro
 
 		if IsAuthError(err) {
 			setAuthType(res)
-			return Batch(objects, operation)
+			return batch(objects, operation, ref, alg)
 		}
 
 		switch res.StatusCode {
@@ -263,17 +415,90 @@ func Batch(objects []*ObjectResource, operation string) ([]*ObjectResource, erro
 	return objs, nil
 }
 
+// DeleteObjects asks the LFS API to remove the given objects from the server.
+// The request and response use the same format as the batch API, with a
+// "delete" operation. Objects that could not be deleted are returned with an
+// error.
+func DeleteObjects(objects []*ObjectResource) ([]*ObjectResource, error) {
+	if len(objects) == 0 {
+		return nil, nil
+	}
+
+	if caps := EndpointCapabilities(Config.Endpoint()); caps != nil && !caps.SupportsOperation("delete") {
+		return nil, newNotImplementedError(nil)
+	}
+
+	return batchByHashAlgorithm(objects, deleteObjects)
+}
+
+func deleteObjects(alg *HashAlgorithm, objects []*ObjectResource) ([]*ObjectResource, error) {
+	o := map[string]interface{}{"objects": objects, "operation": "delete"}
+	if alg != defaultHashAlgorithm {
+		o["hash_algo"] = alg.Name
+	}
+
+	by, err := json.Marshal(o)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	req, err := newDeleteApiRequest()
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	req.Header.Set("Content-Type", mediaType)
+	req.Header.Set("Content-Length", strconv.Itoa(len(by)))
+	req.ContentLength = int64(len(by))
+	req.Body = &byteCloser{bytes.NewReader(by)}
+
+	tracerx.Printf("api: delete %d files", len(objects))
+
+	// Deleting always needs write access, so credentials are always sent.
+	res, objs, err := doApiBatchRequest(req, true)
+	if err != nil {
+		if res == nil || res.StatusCode == 0 {
+			return nil, err
+		}
+
+		if IsAuthError(err) {
+			setAuthType(res)
+			return deleteObjects(alg, objects)
+		}
+
+		switch res.StatusCode {
+		case 404, 405, 410, 501:
+			tracerx.Printf("api: delete not implemented: %d", res.StatusCode)
+			return nil, newNotImplementedError(nil)
+		}
+
+		tracerx.Printf("api error: %s", err)
+		return nil, Error(err)
+	}
+	LogTransfer("lfs.api.delete", res)
+
+	if res.StatusCode != 200 {
+		return nil, Error(fmt.Errorf("Invalid status for %s %s: %d", req.Method, req.URL, res.StatusCode))
+	}
+
+	return objs, nil
+}
+
 func UploadCheck(oidPath string) (*ObjectResource, error) {
-	oid := filepath.Base(oidPath)
+	if !Config.LegacyApi() {
+		return nil, legacyApiDisabledError(Config.Endpoint())
+	}
 
-	stat, err := os.Stat(oidPath)
+	oid := objectIDFromPath(oidPath)
+
+	size, err := StatLocalObject(oidPath)
 	if err != nil {
 		return nil, Error(err)
 	}
 
 	reqObj := &ObjectResource{
 		Oid:  oid,
-		Size: stat.Size(),
+		Size: size,
 	}
 
 	by, err := json.Marshal(reqObj)
@@ -296,6 +521,11 @@ func UploadCheck(oidPath string) (*ObjectResource, error) {
 
 	if err != nil {
 		if IsAuthError(err) {
+			// the credentials could not be filled, so retrying won't help
+			if res == nil {
+				return nil, err
+			}
+
 			setAuthType(res)
 			return UploadCheck(oidPath)
 		}
@@ -318,13 +548,20 @@ func UploadCheck(oidPath string) (*ObjectResource, error) {
 	return obj, nil
 }
 
+// UploadObject uploads the content of the object to the upload action of o.
+// If the server asked for the object's chunks instead, only the chunks it
+// doesn't have are uploaded.
 func UploadObject(o *ObjectResource, cb CopyCallback) error {
+	if _, ok := o.Rel("upload"); !ok && len(o.Chunks) > 0 {
+		return uploadChunkedObject(o, cb)
+	}
+
 	path, err := LocalMediaPath(o.Oid)
 	if err != nil {
 		return Error(err)
 	}
 
-	file, err := os.Open(path)
+	file, _, err := OpenLocalObject(path)
 	if err != nil {
 		return Error(err)
 	}
@@ -336,6 +573,15 @@ func UploadObject(o *ObjectResource, cb CopyCallback) error {
 		Reader:    file,
 	}
 
+	if err := putObject(o, reader); err != nil {
+		return err
+	}
+
+	return verifyUpload(o, apiObject(o))
+}
+
+// putObject sends the content in reader to the upload action of o.
+func putObject(o *ObjectResource, reader io.Reader) error {
 	req, err := o.NewRequest("upload", "PUT")
 	if err != nil {
 		return Error(err)
@@ -351,11 +597,28 @@ func UploadObject(o *ObjectResource, cb CopyCallback) error {
 		req.Header.Set("Content-Length", strconv.FormatInt(o.Size, 10))
 	}
 
+	// Let the storage server verify the content as it is written. The OID
+	// of a SHA-256 object is the hex SHA-256 of the content.
+	checksumHeader := Config.ChecksumHeader()
+	if ObjectHashAlgorithm(o.Oid) != defaultHashAlgorithm {
+		checksumHeader = ""
+	}
+	if len(checksumHeader) > 0 && len(req.Header.Get(checksumHeader)) == 0 {
+		req.Header.Set(checksumHeader, o.Oid)
+	}
+
 	req.ContentLength = o.Size
 	req.Body = ioutil.NopCloser(reader)
 
 	res, err := doStorageRequest(req)
 	if err != nil {
+		// A 422 in response to a checksum means the server received
+		// different content than was sent. Sending it again won't help.
+		if len(checksumHeader) > 0 && res != nil && res.StatusCode == 422 {
+			err = newIntegrityError(Errorf(nil, "[%s] The server rejected the uploaded content as corrupt: %s", o.Oid, err), o.Oid)
+			setErrorResponseContext(err, res)
+			return err
+		}
 		return newRetriableError(err)
 	}
 	LogTransfer("lfs.data.upload", res)
@@ -373,16 +636,22 @@ func UploadObject(o *ObjectResource, cb CopyCallback) error {
 	io.Copy(ioutil.Discard, res.Body)
 	res.Body.Close()
 
+	return nil
+}
+
+// verifyUpload posts body to the verify action of o, if the server asked for
+// one.
+func verifyUpload(o *ObjectResource, body interface{}) error {
 	if _, ok := o.Rel("verify"); !ok {
 		return nil
 	}
 
-	req, err = o.NewRequest("verify", "POST")
+	req, err := o.NewRequest("verify", "POST")
 	if err != nil {
 		return Error(err)
 	}
 
-	by, err := json.Marshal(o)
+	by, err := json.Marshal(body)
 	if err != nil {
 		return Error(err)
 	}
@@ -391,7 +660,7 @@ func UploadObject(o *ObjectResource, cb CopyCallback) error {
 	req.Header.Set("Content-Length", strconv.Itoa(len(by)))
 	req.ContentLength = int64(len(by))
 	req.Body = ioutil.NopCloser(bytes.NewReader(by))
-	res, err = doAPIRequest(req, true)
+	res, err := doAPIRequest(req, true)
 	if err != nil {
 		return err
 	}
@@ -424,10 +693,10 @@ func doLegacyApiRequest(req *http.Request) (*http.Response, *ObjectResource, err
 
 // doApiBatchRequest runs the request to the LFS batch API. If the API returns a
 // 401, the repo will be marked as having private access and the request will be
-// re-run. When the repo is marked as having private access, credentials will
-// be retrieved.
-func doApiBatchRequest(req *http.Request) (*http.Response, []*ObjectResource, error) {
-	res, err := doAPIRequest(req, Config.PrivateAccess())
+// re-run. When the repo is marked as having private access, or useCreds is
+// true, credentials will be retrieved.
+func doApiBatchRequest(req *http.Request, useCreds bool) (*http.Response, []*ObjectResource, error) {
+	res, err := doAPIRequest(req, useCreds || Config.PrivateAccess())
 
 	if err != nil {
 		if res != nil && res.StatusCode == 401 {
@@ -607,9 +876,15 @@ func decodeApiResponse(res *http.Response, obj interface{}) error {
 		return nil
 	}
 
-	err := json.NewDecoder(res.Body).Decode(obj)
-	io.Copy(ioutil.Discard, res.Body)
-	res.Body.Close()
+	body, err := decodedResponseBody(res)
+	if err != nil {
+		res.Body.Close()
+		return Errorf(err, "Unable to decompress HTTP response for %s %s", res.Request.Method, res.Request.URL)
+	}
+
+	err = json.NewDecoder(body).Decode(obj)
+	io.Copy(ioutil.Discard, body)
+	body.Close()
 
 	if err != nil {
 		return Errorf(err, "Unable to parse HTTP response for %s %s", res.Request.Method, res.Request.URL)
@@ -618,6 +893,40 @@ func decodeApiResponse(res *http.Response, obj interface{}) error {
 	return nil
 }
 
+// decodedResponseBody returns the body of the response, decompressing it if
+// the server gzipped it. HTTP responses are only compressed if the request
+// asked for it with an explicit Accept-Encoding header, like batch requests.
+// The decompressed body is traced, since compressed bodies are not.
+func decodedResponseBody(res *http.Response) (io.ReadCloser, error) {
+	if !strings.EqualFold(res.Header.Get("Content-Encoding"), "gzip") {
+		return res.Body, nil
+	}
+
+	gz, err := gzip.NewReader(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &countingReadCloser{
+		ReadCloser:      &gzipReadCloser{gz, res.Body},
+		isTraceableType: isTraceableContentType(res.Header),
+		useGitTrace:     true,
+	}, nil
+}
+
+// gzipReadCloser reads a gzipped body, closing both the gzip reader and the
+// underlying body.
+type gzipReadCloser struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (r *gzipReadCloser) Close() error {
+	r.Reader.Close()
+	io.Copy(ioutil.Discard, r.body)
+	return r.body.Close()
+}
+
 func defaultError(res *http.Response) error {
 	var msgFmt string
 
@@ -633,6 +942,10 @@ func defaultError(res *http.Response) error {
 }
 
 func newApiRequest(method, oid string) (*http.Request, error) {
+	if ObjectHashAlgorithm(oid) != defaultHashAlgorithm {
+		return nil, fmt.Errorf("The legacy API only supports sha256 objects, not %s", oid)
+	}
+
 	endpoint := Config.Endpoint()
 	objectOid := oid
 	operation := "download"
@@ -683,7 +996,29 @@ func newClientRequest(method, rawurl string, header map[string]string) (*http.Re
 	return req, nil
 }
 
+// newBatchApiRequest builds a request for the batch endpoint. Batch responses
+// for many objects are large, so the request asks for a gzipped response, which
+// decodeApiResponse decompresses.
 func newBatchApiRequest(operation string) (*http.Request, error) {
+	req, err := newObjectsApiRequest(operation, "batch")
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept-Encoding", "gzip")
+	return req, nil
+}
+
+// newDeleteApiRequest builds a request for the delete endpoint. Deleting
+// requires the same access as uploading, so SSH authentication asks for the
+// "upload" operation.
+func newDeleteApiRequest() (*http.Request, error) {
+	return newObjectsApiRequest("upload", "delete")
+}
+
+// newObjectsApiRequest builds a POST request for a batch-style endpoint at
+// "<lfs url>/objects/<path>", authenticating with SSH for the given operation.
+func newObjectsApiRequest(operation, path string) (*http.Request, error) {
 	endpoint := Config.Endpoint()
 
 	res, err := sshAuthenticate(endpoint, operation, "")
@@ -697,7 +1032,7 @@ func newBatchApiRequest(operation string) (*http.Request, error) {
 		endpoint.Url = res.Href
 	}
 
-	u, err := ObjectUrl(endpoint, "batch")
+	u, err := ObjectUrl(endpoint, path)
 	if err != nil {
 		return nil, err
 	}
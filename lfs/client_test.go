@@ -1,12 +1,17 @@
 package lfs
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/base64"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"testing"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/technoweenie/assert"
 )
 
 func tempdir(t *testing.T) string {
@@ -26,3 +31,75 @@ func expectedAuth(t *testing.T, server *httptest.Server) string {
 	token := fmt.Sprintf("%s:%s", u.Host, "monkey")
 	return "Basic " + base64.URLEncoding.EncodeToString([]byte(token))
 }
+
+func TestDecodeGzippedApiResponse(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(`{"objects":[{"oid":"abc","size":3}]}`))
+	gz.Close()
+
+	req, _ := http.NewRequest("POST", "https://example.com/objects/batch", nil)
+	res := &http.Response{
+		StatusCode: 200,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(&buf),
+		Request:    req,
+	}
+	res.Header.Set("Content-Type", mediaType)
+	res.Header.Set("Content-Encoding", "gzip")
+
+	var objs map[string][]*ObjectResource
+	err := decodeApiResponse(res, &objs)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, len(objs["objects"]))
+	assert.Equal(t, "abc", objs["objects"][0].Oid)
+	assert.Equal(t, int64(3), objs["objects"][0].Size)
+}
+
+func TestBatchByHashAlgorithm(t *testing.T) {
+	sha256Oid := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	objects := []*ObjectResource{
+		&ObjectResource{Oid: "sha512:" + sha512Hex, Size: 4},
+		&ObjectResource{Oid: sha256Oid, Size: 4},
+	}
+
+	var algs []string
+	objs, err := batchByHashAlgorithm(objects, func(alg *HashAlgorithm, objects []*ObjectResource) ([]*ObjectResource, error) {
+		algs = append(algs, alg.Name)
+		assert.Equal(t, 1, len(objects))
+
+		// the server responds with bare hex OIDs
+		return []*ObjectResource{&ObjectResource{Oid: objects[0].Oid, Size: objects[0].Size}}, nil
+	})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"sha512", "sha256"}, algs)
+	assert.Equal(t, 2, len(objs))
+	assert.Equal(t, "sha512:"+sha512Hex, objs[0].Oid)
+	assert.Equal(t, sha256Oid, objs[1].Oid)
+
+	// the objects themselves keep their tagged OIDs
+	assert.Equal(t, "sha512:"+sha512Hex, objects[0].Oid)
+}
+
+func TestDeleteObjectsSendsCredentials(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	requests := 0
+	mux.HandleFunc("/media/objects/delete", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		// the endpoint isn't marked private, but the first request has credentials
+		assert.Equal(t, expectedAuth(t, server), r.Header.Get("Authorization"))
+
+		w.Header().Set("Content-Type", mediaType)
+		fmt.Fprint(w, `{"objects":[]}`)
+	})
+
+	defer Config.ResetConfig()
+	Config.SetConfig("lfs.url", server.URL+"/media")
+
+	_, err := DeleteObjects([]*ObjectResource{&ObjectResource{Oid: fmt.Sprintf("%064x", 1), Size: 1}})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, requests)
+}
@@ -0,0 +1,253 @@
+package lfs
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/rubyist/tracerx"
+)
+
+// Objects can be stored compressed, as set by lfs.compression. A compressed
+// object is a gzip file next to where the raw object would be, with a ".gz"
+// extension. Its OID is still the SHA-256 of the uncompressed content, and the
+// uncompressed size is kept in the gzip header's comment, so that sizes can be
+// checked without decompressing. Objects can also be stored in chunks, see
+// chunked_storage.go. Paths to objects always name the raw object, and the
+// functions here find whichever form is stored.
+const (
+	compressedObjectExt = ".gz"
+	compressionGzip     = "gzip"
+	compressionNone     = "none"
+)
+
+func compressedObjectPath(path string) string {
+	return path + compressedObjectExt
+}
+
+// StoredObjectFile returns the file that the object at path is stored in: path
+// itself, its compressed form, or its chunk manifest. It returns "" if the
+// object isn't stored.
+func StoredObjectFile(path string) string {
+	if FileExists(path) {
+		return path
+	}
+
+	if compressed := compressedObjectPath(path); FileExists(compressed) {
+		return compressed
+	}
+
+	if manifest := chunkManifestPath(path); FileExists(manifest) {
+		return manifest
+	}
+
+	return ""
+}
+
+func localObjectExists(path string) bool {
+	return len(StoredObjectFile(path)) > 0
+}
+
+func localObjectExistsOfSize(path string, size int64) bool {
+	n, err := StatLocalObject(path)
+	return err == nil && n == size
+}
+
+// StatLocalObject returns the size of the uncompressed content of the object
+// at path.
+func StatLocalObject(path string) (int64, error) {
+	stat, err := os.Stat(path)
+	if err == nil {
+		return stat.Size(), nil
+	}
+
+	compressed := compressedObjectPath(path)
+	if !FileExists(compressed) {
+		if FileExists(chunkManifestPath(path)) {
+			return statChunkedObject(path)
+		}
+		return 0, err
+	}
+
+	reader, size, err := openCompressedObject(compressed)
+	if err != nil {
+		return 0, err
+	}
+	reader.Close()
+
+	return size, nil
+}
+
+// OpenLocalObject opens the uncompressed content of the object at path, and
+// returns its size.
+func OpenLocalObject(path string) (io.ReadCloser, int64, error) {
+	file, err := os.Open(path)
+	if err == nil {
+		stat, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, 0, err
+		}
+		return file, stat.Size(), nil
+	}
+
+	compressed := compressedObjectPath(path)
+	if !FileExists(compressed) {
+		if FileExists(chunkManifestPath(path)) {
+			return openChunkedObject(path)
+		}
+		return nil, 0, err
+	}
+
+	return openCompressedObject(compressed)
+}
+
+// RemoveLocalObject removes the object at path, in any form. The chunks of a
+// chunked object are left for RemoveUnreferencedChunks, since other objects
+// may share them.
+func RemoveLocalObject(path string) error {
+	err := os.Remove(path)
+	cerr := os.Remove(compressedObjectPath(path))
+	merr := os.Remove(chunkManifestPath(path))
+
+	for _, e := range []error{err, cerr, merr} {
+		if e != nil && !os.IsNotExist(e) {
+			return e
+		}
+	}
+
+	if err != nil && cerr != nil && merr != nil {
+		return err // no form is stored
+	}
+	return nil
+}
+
+// StoreLocalObject moves tmpfile, with the verified content of an object, to
+// path. If lfs.chunking is set, objects larger than the largest chunk are
+// stored in chunks instead. Otherwise, if lfs.compression is set, the object is
+// stored compressed, unless that doesn't make it smaller.
+func StoreLocalObject(tmpfile, path string) error {
+	if Config.Chunking() {
+		stored, err := storeChunkedObject(tmpfile, path)
+		if err != nil {
+			return err
+		}
+		if stored {
+			return nil
+		}
+	}
+
+	if Config.Compression() == compressionGzip {
+		stored, err := storeCompressedObject(tmpfile, path)
+		if err != nil {
+			return err
+		}
+		if stored {
+			return nil
+		}
+	}
+
+	if err := os.Rename(tmpfile, path); err != nil {
+		return err
+	}
+
+	os.Remove(compressedObjectPath(path))
+	os.Remove(chunkManifestPath(path))
+	return nil
+}
+
+// storeCompressedObject compresses tmpfile to the compressed form of path. It
+// returns false if the compressed form would be no smaller.
+func storeCompressedObject(tmpfile, path string) (bool, error) {
+	src, err := os.Open(tmpfile)
+	if err != nil {
+		return false, err
+	}
+	defer src.Close()
+
+	stat, err := src.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	// Prefix the temp file with the OID, so that ClearTempObjects removes
+	// it if it is left behind.
+	dest, err := ioutil.TempFile(filepath.Dir(tmpfile), filepath.Base(path)+"-")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(dest.Name()) // no-op once renamed
+
+	gz := gzip.NewWriter(dest)
+	gz.Name = filepath.Base(path)
+	gz.Comment = strconv.FormatInt(stat.Size(), 10)
+
+	_, err = io.Copy(gz, src)
+	if err == nil {
+		err = gz.Close()
+	}
+	if cerr := dest.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return false, fmt.Errorf("cannot compress %q: %v", tmpfile, err)
+	}
+
+	compressedStat, err := os.Stat(dest.Name())
+	if err != nil {
+		return false, err
+	}
+
+	if compressedStat.Size() >= stat.Size() {
+		tracerx.Printf("compression: storing %s raw, it doesn't compress", filepath.Base(path))
+		return false, nil
+	}
+
+	if err := os.Rename(dest.Name(), compressedObjectPath(path)); err != nil {
+		return false, err
+	}
+
+	src.Close()
+	os.Remove(tmpfile)
+	os.Remove(path)
+	os.Remove(chunkManifestPath(path))
+	return true, nil
+}
+
+type compressedObjectReader struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (r *compressedObjectReader) Close() error {
+	err := r.Reader.Close()
+	if ferr := r.file.Close(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+func openCompressedObject(compressed string) (io.ReadCloser, int64, error) {
+	file, err := os.Open(compressed)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, 0, fmt.Errorf("cannot read compressed object %q: %v", compressed, err)
+	}
+
+	size, err := strconv.ParseInt(gz.Comment, 10, 64)
+	if err != nil || size < 0 {
+		file.Close()
+		return nil, 0, fmt.Errorf("compressed object %q has no size", compressed)
+	}
+
+	return &compressedObjectReader{Reader: gz, file: file}, size, nil
+}
@@ -0,0 +1,83 @@
+package lfs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/technoweenie/assert"
+)
+
+func TestCompressedLocalObjects(t *testing.T) {
+	tmp := tempdir(t)
+	defer os.RemoveAll(tmp)
+
+	oldMediaDir := LocalMediaDir
+	LocalMediaDir = filepath.Join(tmp, "objects")
+	defer func() {
+		LocalMediaDir = oldMediaDir
+	}()
+
+	Config.SetConfig("lfs.compression", "gzip")
+	defer Config.ResetConfig()
+
+	compressible := bytes.Repeat([]byte("compress me "), 1000)
+	incompressible := make([]byte, 1000)
+	rand.New(rand.NewSource(1)).Read(incompressible)
+
+	compressedPath := storeTestObject(t, tmp, compressible)
+	rawPath := storeTestObject(t, tmp, incompressible)
+
+	// only content that gets smaller is compressed
+	assert.Equal(t, compressedPath+".gz", StoredObjectFile(compressedPath))
+	assert.Equal(t, rawPath, StoredObjectFile(rawPath))
+	assert.Equal(t, false, FileExists(compressedPath))
+
+	size, err := StatLocalObject(compressedPath)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(len(compressible)), size)
+	assert.Equal(t, true, ObjectExistsOfSize(filepath.Base(compressedPath), int64(len(compressible))))
+
+	reader, size, err := OpenLocalObject(compressedPath)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(len(compressible)), size)
+	content, err := ioutil.ReadAll(reader)
+	reader.Close()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, bytes.Equal(compressible, content))
+
+	// smudging decompresses
+	var buf bytes.Buffer
+	ptr := NewPointer(filepath.Base(compressedPath), int64(len(compressible)), nil)
+	assert.Equal(t, nil, PointerSmudge(&buf, ptr, "compressible.dat", false, nil))
+	assert.Equal(t, true, bytes.Equal(compressible, buf.Bytes()))
+
+	sizes := make(map[string]int64)
+	for _, p := range AllLocalObjects() {
+		sizes[p.Oid] = p.Size
+	}
+	assert.Equal(t, 2, len(sizes))
+	assert.Equal(t, int64(len(compressible)), sizes[filepath.Base(compressedPath)])
+	assert.Equal(t, int64(len(incompressible)), sizes[filepath.Base(rawPath)])
+
+	assert.Equal(t, nil, RemoveLocalObject(compressedPath))
+	assert.Equal(t, "", StoredObjectFile(compressedPath))
+	assert.Equal(t, true, os.IsNotExist(RemoveLocalObject(compressedPath)))
+}
+
+func storeTestObject(t *testing.T, dir string, content []byte) string {
+	hash := sha256.Sum256(content)
+	path, err := LocalStoreMediaPath(hex.EncodeToString(hash[:]))
+	assert.Equal(t, nil, err)
+
+	tmpfile := filepath.Join(dir, "tmp-object")
+	assert.Equal(t, nil, ioutil.WriteFile(tmpfile, content, 0644))
+	assert.Equal(t, nil, StoreLocalObject(tmpfile, path))
+	assert.Equal(t, false, FileExists(tmpfile))
+	return path
+}
@@ -51,6 +51,8 @@ type Configuration struct {
 	isTracingHttp         bool
 	isDebuggingHttp       bool
 	isLoggingStats        bool
+	statsOnce             sync.Once
+	collectStats          bool
 
 	loading           sync.Mutex // guards initialization of gitConfig and remotes
 	gitConfig         map[string]string
@@ -140,7 +142,40 @@ func (c *Configuration) ConcurrentTransfers() int {
 	return uploads
 }
 
+// BatchMaxObjects returns the most objects that are sent in a single batch
+// API request. Default 100.
+func (c *Configuration) BatchMaxObjects() int {
+	size := batchSize
+
+	if v, ok := c.GitConfig("lfs.batchmaxobjects"); ok {
+		n, err := strconv.Atoi(v)
+		if err == nil && n > 0 {
+			size = n
+		}
+	}
+
+	return size
+}
+
+// BatchMaxBytes returns the most bytes of object data that are requested in a
+// single batch API request. A single object larger than this is still sent in
+// a batch on its own. Default 0, meaning batches are not limited by size.
+func (c *Configuration) BatchMaxBytes() int64 {
+	if v, ok := c.GitConfig("lfs.batchmaxbytes"); ok {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err == nil && n > 0 {
+			return n
+		}
+	}
+
+	return 0
+}
+
 func (c *Configuration) BatchTransfer() bool {
+	if !c.LegacyApi() {
+		return true
+	}
+
 	value, ok := c.GitConfig("lfs.batch")
 	if !ok || len(value) == 0 {
 		return true
@@ -154,6 +189,30 @@ func (c *Configuration) BatchTransfer() bool {
 	return useBatch
 }
 
+// LegacyApi returns whether the client may use the legacy, per-object API, as
+// set by lfs.legacyapi. Default false. Without it, lfs.batch is ignored, and
+// servers without the batch API are reported as errors.
+func (c *Configuration) LegacyApi() bool {
+	if v, ok := c.GitConfig("lfs.legacyapi"); ok {
+		legacy, err := parseConfigBool(v)
+		return err == nil && legacy
+	}
+
+	return false
+}
+
+// ProbeCapabilities returns whether the client asks the LFS server what it
+// supports before making requests, as set by lfs.probecapabilities. Default
+// false.
+func (c *Configuration) ProbeCapabilities() bool {
+	if v, ok := c.GitConfig("lfs.probecapabilities"); ok {
+		probe, err := parseConfigBool(v)
+		return err == nil && probe
+	}
+
+	return false
+}
+
 func (c *Configuration) NtlmAccess() bool {
 	return c.Access() == "ntlm"
 }
@@ -171,6 +230,127 @@ func (c *Configuration) Access() string {
 	return c.EndpointAccess(c.Endpoint())
 }
 
+// MetricsEnabled returns whether transfer metrics are sent to the current LFS
+// endpoint, as set by lfs.<url>.metrics. Setting lfs.metrics to false turns
+// metrics off for every endpoint.
+func (c *Configuration) MetricsEnabled() bool {
+	if v, ok := c.GitConfig("lfs.metrics"); ok {
+		if enabled, err := parseConfigBool(v); err == nil && !enabled {
+			return false
+		}
+	}
+
+	return c.EndpointMetrics(c.Endpoint())
+}
+
+func (c *Configuration) EndpointMetrics(e Endpoint) bool {
+	key := fmt.Sprintf("lfs.%s.metrics", e.Url)
+	if v, ok := c.GitConfig(key); ok {
+		enabled, err := parseConfigBool(v)
+		return err == nil && enabled
+	}
+	return false
+}
+
+// CheckoutLinkMode returns how checked out files get the content of stored
+// objects, as set by lfs.checkout.linkmode: "copy" (the default), "hardlink",
+// or "reflink". Unknown values are treated as "copy".
+func (c *Configuration) CheckoutLinkMode() string {
+	v, _ := c.GitConfig("lfs.checkout.linkmode")
+	switch mode := strings.ToLower(strings.TrimSpace(v)); mode {
+	case linkModeHardlink, linkModeReflink:
+		return mode
+	case "", linkModeCopy:
+	default:
+		tracerx.Printf("Unknown lfs.checkout.linkmode %q, copying", v)
+	}
+	return linkModeCopy
+}
+
+// CacheMaxSize returns the most bytes that objects in the local store should
+// take up, as set by lfs.cache.maxsize. Like Git's size settings, the value
+// can end in "k", "m", or "g". Default 0, meaning the store is not limited.
+func (c *Configuration) CacheMaxSize() int64 {
+	if v, ok := c.GitConfig("lfs.cache.maxsize"); ok {
+		n, err := parseConfigSize(v)
+		if err == nil && n > 0 {
+			return n
+		}
+		if err != nil {
+			tracerx.Printf("Invalid lfs.cache.maxsize %q: %s", v, err)
+		}
+	}
+
+	return 0
+}
+
+// Compression returns how new objects are compressed in the local store, as
+// set by lfs.compression: "none" (the default) or "gzip". Unknown values are
+// treated as "none". Objects already stored are read in either form.
+func (c *Configuration) Compression() string {
+	v, _ := c.GitConfig("lfs.compression")
+	switch mode := strings.ToLower(strings.TrimSpace(v)); mode {
+	case compressionGzip:
+		return mode
+	case "", compressionNone:
+	default:
+		tracerx.Printf("Unknown lfs.compression %q, not compressing", v)
+	}
+	return compressionNone
+}
+
+// Chunking returns whether large objects are stored and transferred in
+// content-defined chunks, as set by lfs.chunking. It is off by default.
+func (c *Configuration) Chunking() bool {
+	if v, ok := c.GitConfig("lfs.chunking"); ok {
+		chunking, err := parseConfigBool(v)
+		return err == nil && chunking
+	}
+
+	return false
+}
+
+// HashAlgorithm returns the name of the hash algorithm that the OIDs of new
+// objects are computed with, as set by lfs.hashalgorithm. It is "sha256" by
+// default, and for algorithms that aren't registered.
+func (c *Configuration) HashAlgorithm() string {
+	v, _ := c.GitConfig("lfs.hashalgorithm")
+	name := strings.ToLower(strings.TrimSpace(v))
+	if len(name) == 0 {
+		return defaultHashAlgorithm.Name
+	}
+
+	if LookupHashAlgorithm(name) == nil {
+		tracerx.Printf("Unknown lfs.hashalgorithm %q, using %s", v, defaultHashAlgorithm.Name)
+		return defaultHashAlgorithm.Name
+	}
+	return name
+}
+
+// ChecksumHeader returns the name of the header that carries the SHA-256 of
+// each object uploaded for the current LFS endpoint, as set by
+// lfs.<url>.checksumheader, such as "x-amz-content-sha256". It is empty if no
+// checksum is sent.
+func (c *Configuration) ChecksumHeader() string {
+	return c.EndpointChecksumHeader(c.Endpoint())
+}
+
+func (c *Configuration) EndpointChecksumHeader(e Endpoint) string {
+	key := fmt.Sprintf("lfs.%s.checksumheader", e.Url)
+	v, _ := c.GitConfig(key)
+	return strings.TrimSpace(v)
+}
+
+// collectingStats returns whether HTTP transfer stats should be collected,
+// either for GIT_LOG_STATS or for reporting metrics. It is checked once per
+// process.
+func (c *Configuration) collectingStats() bool {
+	c.statsOnce.Do(func() {
+		c.collectStats = c.isLoggingStats || c.MetricsEnabled()
+	})
+	return c.collectStats
+}
+
 // SetAccess will set the private access flag in .git/config.
 func (c *Configuration) SetAccess(authType string) {
 	c.SetEndpointAccess(c.Endpoint(), authType)
@@ -219,6 +399,28 @@ func (c *Configuration) FetchExcludePaths() []string {
 	return c.fetchExcludePaths
 }
 
+// FetchRemotes returns the ordered list of remotes that objects are downloaded
+// from. The current remote is always tried first, followed by any remotes
+// listed in the comma separated lfs.fetchremotes setting. Later remotes are
+// only consulted for objects that the previous ones do not have.
+func (c *Configuration) FetchRemotes() []string {
+	remotes := []string{c.CurrentRemote}
+	seen := NewStringSet()
+	seen.Add(c.CurrentRemote)
+
+	if v, ok := c.GitConfig("lfs.fetchremotes"); ok {
+		for _, remote := range strings.Split(v, ",") {
+			remote = strings.TrimSpace(remote)
+			if len(remote) == 0 || !seen.Add(remote) {
+				continue
+			}
+			remotes = append(remotes, remote)
+		}
+	}
+
+	return remotes
+}
+
 func (c *Configuration) RemoteEndpoint(remote string) Endpoint {
 	if len(remote) == 0 {
 		remote = defaultRemote
@@ -320,6 +522,33 @@ func parseConfigBool(str string) (bool, error) {
 	return false, fmt.Errorf("Unable to parse %q as a boolean", str)
 }
 
+// parseConfigSize parses a size in bytes with an optional "k", "m", or "g"
+// suffix, the way Git parses sizes like core.bigFileThreshold.
+func parseConfigSize(value string) (int64, error) {
+	str := strings.ToLower(strings.TrimSpace(value))
+
+	var unit int64 = 1
+	if len(str) > 0 {
+		switch str[len(str)-1] {
+		case 'k':
+			unit = 1024
+		case 'm':
+			unit = 1024 * 1024
+		case 'g':
+			unit = 1024 * 1024 * 1024
+		}
+		if unit > 1 {
+			str = str[:len(str)-1]
+		}
+	}
+
+	n, err := strconv.ParseInt(str, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("Unable to parse %q as a size", value)
+	}
+	return n * unit, nil
+}
+
 func (c *Configuration) loadGitConfig() bool {
 	c.loading.Lock()
 	defer c.loading.Unlock()
@@ -424,6 +653,11 @@ func (c *Configuration) readGitConfig(output string, uniqRemotes map[string]bool
 					continue
 				}
 				ext.Smudge = value
+			case "process":
+				if onlySafe {
+					continue
+				}
+				ext.Process = value
 			case "priority":
 				allowed = true
 				p, err := strconv.Atoi(value)
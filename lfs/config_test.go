@@ -293,7 +293,7 @@ func TestBatch(t *testing.T) {
 
 	for value, expected := range tests {
 		config := &Configuration{
-			gitConfig: map[string]string{"lfs.batch": value},
+			gitConfig: map[string]string{"lfs.batch": value, "lfs.legacyapi": "true"},
 		}
 
 		if actual := config.BatchTransfer(); actual != expected {
@@ -302,6 +302,15 @@ func TestBatch(t *testing.T) {
 	}
 }
 
+func TestBatchRequiresLegacyApi(t *testing.T) {
+	config := &Configuration{
+		gitConfig: map[string]string{"lfs.batch": "false"},
+	}
+
+	assert.Equal(t, false, config.LegacyApi())
+	assert.Equal(t, true, config.BatchTransfer())
+}
+
 func TestBatchAbsentIsTrue(t *testing.T) {
 	config := &Configuration{}
 
@@ -358,6 +367,7 @@ func TestLoadValidExtension(t *testing.T) {
 				"foo-clean %f",
 				"foo-smudge %f",
 				2,
+				"",
 			},
 		},
 	}
@@ -415,6 +425,26 @@ func TestFetchPruneConfigCustom(t *testing.T) {
 	assert.Equal(t, true, fp.PruneVerifyRemoteAlways)
 }
 
+func TestFetchRemotesDefault(t *testing.T) {
+	config := &Configuration{
+		CurrentRemote: "origin",
+		gitConfig:     map[string]string{},
+	}
+
+	assert.Equal(t, []string{"origin"}, config.FetchRemotes())
+}
+
+func TestFetchRemotesCustom(t *testing.T) {
+	config := &Configuration{
+		CurrentRemote: "mirror",
+		gitConfig: map[string]string{
+			"lfs.fetchremotes": "mirror, origin,,upstream,origin",
+		},
+	}
+
+	assert.Equal(t, []string{"mirror", "origin", "upstream"}, config.FetchRemotes())
+}
+
 // only used for tests
 func (c *Configuration) SetConfig(key, value string) {
 	if c.loadGitConfig() {
@@ -437,3 +467,91 @@ func (c *Configuration) ResetConfig() {
 	}
 	c.loading.Unlock()
 }
+
+func TestBatchMaxObjectsAndBytes(t *testing.T) {
+	config := &Configuration{gitConfig: map[string]string{}}
+	assert.Equal(t, 100, config.BatchMaxObjects())
+	assert.Equal(t, int64(0), config.BatchMaxBytes())
+
+	config.gitConfig["lfs.batchmaxobjects"] = "500"
+	config.gitConfig["lfs.batchmaxbytes"] = "1048576"
+	assert.Equal(t, 500, config.BatchMaxObjects())
+	assert.Equal(t, int64(1048576), config.BatchMaxBytes())
+
+	config.gitConfig["lfs.batchmaxobjects"] = "-1"
+	config.gitConfig["lfs.batchmaxbytes"] = "lots"
+	assert.Equal(t, 100, config.BatchMaxObjects())
+	assert.Equal(t, int64(0), config.BatchMaxBytes())
+}
+
+func TestCacheMaxSize(t *testing.T) {
+	config := &Configuration{gitConfig: map[string]string{}}
+	assert.Equal(t, int64(0), config.CacheMaxSize())
+
+	tests := map[string]int64{
+		"1048576": 1048576,
+		"512k":    512 * 1024,
+		"20M":     20 * 1024 * 1024,
+		" 2g ":    2 * 1024 * 1024 * 1024,
+		"0":       0,
+		"-1":      0,
+		"lots":    0,
+		"10t":     0,
+	}
+
+	for value, expected := range tests {
+		config.gitConfig["lfs.cache.maxsize"] = value
+		assert.Equalf(t, expected, config.CacheMaxSize(), "lfs.cache.maxsize %q", value)
+	}
+}
+
+func TestCompression(t *testing.T) {
+	tests := map[string]string{
+		"":      "none",
+		"none":  "none",
+		"gzip":  "gzip",
+		" GZIP": "gzip",
+		"zstd":  "none",
+	}
+
+	for value, expected := range tests {
+		config := &Configuration{gitConfig: map[string]string{"lfs.compression": value}}
+		assert.Equalf(t, expected, config.Compression(), "lfs.compression %q", value)
+	}
+}
+
+func TestChunking(t *testing.T) {
+	tests := map[string]bool{
+		"":      false,
+		"true":  true,
+		"1":     true,
+		"false": false,
+		"maybe": false,
+	}
+
+	for value, expected := range tests {
+		config := &Configuration{gitConfig: map[string]string{"lfs.chunking": value}}
+		assert.Equalf(t, expected, config.Chunking(), "lfs.chunking %q", value)
+	}
+
+	config := &Configuration{}
+	assert.Equal(t, false, config.Chunking())
+}
+
+func TestHashAlgorithm(t *testing.T) {
+	tests := map[string]string{
+		"":        "sha256",
+		"sha256":  "sha256",
+		"sha512":  "sha512",
+		" SHA512": "sha512",
+		"md5":     "sha256",
+	}
+
+	for value, expected := range tests {
+		config := &Configuration{gitConfig: map[string]string{"lfs.hashalgorithm": value}}
+		assert.Equalf(t, expected, config.HashAlgorithm(), "lfs.hashalgorithm %q", value)
+	}
+
+	config := &Configuration{}
+	assert.Equal(t, "sha256", config.HashAlgorithm())
+}
@@ -0,0 +1,129 @@
+package lfs
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/rubyist/tracerx"
+)
+
+const httpOnlyCookiePrefix = "#HttpOnly_"
+
+// cookieJar returns a cookie jar with the cookies from the file in the
+// http.cookieFile Git config, if it is set. Like Git, the file is only read,
+// so cookies set by the server are kept in memory for the current process.
+func (c *Configuration) cookieJar() http.CookieJar {
+	filename, ok := c.GitConfig("http.cookiefile")
+	if !ok || len(filename) == 0 {
+		return nil
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil
+	}
+
+	filename = expandHomePath(filename)
+	file, err := os.Open(filename)
+	if err != nil {
+		tracerx.Printf("http: unable to read cookie file %q: %s", filename, err)
+		return jar
+	}
+	defer file.Close()
+
+	cookies := parseCookieFile(file, time.Now())
+	for _, fc := range cookies {
+		scheme := "http"
+		if fc.Cookie.Secure {
+			scheme = "https"
+		}
+
+		u := &url.URL{Scheme: scheme, Host: fc.Host, Path: fc.Cookie.Path}
+		jar.SetCookies(u, []*http.Cookie{fc.Cookie})
+	}
+
+	tracerx.Printf("http: loaded %d cookie(s) from %q", len(cookies), filename)
+	return jar
+}
+
+// fileCookie is a cookie read from a cookie file, along with the host it was
+// set for.
+type fileCookie struct {
+	Host   string
+	Cookie *http.Cookie
+}
+
+// parseCookieFile reads the cookies from a Netscape format cookie file, as
+// written by curl and browsers. Each line has 7 tab separated fields: domain,
+// include subdomains, path, secure, expiration, name, and value. Expired
+// cookies are skipped.
+func parseCookieFile(r io.Reader, now time.Time) []*fileCookie {
+	cookies := make([]*fileCookie, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		httpOnly := false
+		if strings.HasPrefix(line, httpOnlyCookiePrefix) {
+			httpOnly = true
+			line = line[len(httpOnlyCookiePrefix):]
+		}
+
+		if len(strings.TrimSpace(line)) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			continue
+		}
+
+		cookie := &http.Cookie{
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			Name:     fields[5],
+			Value:    fields[6],
+			HttpOnly: httpOnly,
+		}
+
+		if expires, err := strconv.ParseInt(fields[4], 10, 64); err == nil && expires > 0 {
+			cookie.Expires = time.Unix(expires, 0)
+			if cookie.Expires.Before(now) {
+				continue
+			}
+		}
+
+		// Cookies without a Domain are only sent to the exact host.
+		host := strings.TrimPrefix(fields[0], ".")
+		if strings.EqualFold(fields[1], "TRUE") {
+			cookie.Domain = host
+		}
+
+		cookies = append(cookies, &fileCookie{Host: host, Cookie: cookie})
+	}
+
+	return cookies
+}
+
+// expandHomePath expands a leading "~/" to the user's home directory, like Git
+// does for pathname config values.
+func expandHomePath(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+
+	home := Config.Getenv("HOME")
+	if len(home) == 0 {
+		return path
+	}
+
+	return filepath.Join(home, path[2:])
+}
@@ -0,0 +1,109 @@
+package lfs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/technoweenie/assert"
+)
+
+func TestParseCookieFile(t *testing.T) {
+	now := time.Unix(1000, 0)
+	file := strings.Join([]string{
+		"# Netscape HTTP Cookie File",
+		"",
+		".example.com\tTRUE\t/\tFALSE\t0\tsession\tabc",
+		"git.example.com\tFALSE\t/repo\tTRUE\t2000\tsecure\tdef",
+		"#HttpOnly_git.example.com\tFALSE\t/\tFALSE\t0\thttponly\tghi",
+		"git.example.com\tFALSE\t/\tFALSE\t500\texpired\tjkl",
+		"malformed line",
+	}, "\n")
+
+	cookies := parseCookieFile(strings.NewReader(file), now)
+	assert.Equal(t, 3, len(cookies))
+
+	assert.Equal(t, "example.com", cookies[0].Host)
+	assert.Equal(t, "example.com", cookies[0].Cookie.Domain)
+	assert.Equal(t, "session", cookies[0].Cookie.Name)
+	assert.Equal(t, "abc", cookies[0].Cookie.Value)
+	assert.Equal(t, false, cookies[0].Cookie.Secure)
+
+	assert.Equal(t, "git.example.com", cookies[1].Host)
+	assert.Equal(t, "", cookies[1].Cookie.Domain)
+	assert.Equal(t, "/repo", cookies[1].Cookie.Path)
+	assert.Equal(t, true, cookies[1].Cookie.Secure)
+	assert.Equal(t, time.Unix(2000, 0), cookies[1].Cookie.Expires)
+
+	assert.Equal(t, "httponly", cookies[2].Cookie.Name)
+	assert.Equal(t, true, cookies[2].Cookie.HttpOnly)
+}
+
+func TestCookieFileSentAcrossRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tmp := tempdir(t)
+	defer os.RemoveAll(tmp)
+
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cookieFile := filepath.Join(tmp, "cookies")
+	line := serverURL.Host[:strings.Index(serverURL.Host, ":")] + "\tFALSE\t/\tFALSE\t0\tsession\tsso\n"
+	if err := ioutil.WriteFile(cookieFile, []byte(line), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mux.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("session"); err != nil || c.Value != "sso" {
+			t.Errorf("Expected session cookie on /api, got %v", r.Header.Get("Cookie"))
+		}
+
+		http.SetCookie(w, &http.Cookie{Name: "redirected", Value: "1", Path: "/"})
+		w.Header().Set("Location", server.URL+"/redirected")
+		w.WriteHeader(307)
+	})
+
+	mux.HandleFunc("/redirected", func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("session"); err != nil || c.Value != "sso" {
+			t.Errorf("Expected session cookie on /redirected, got %v", r.Header.Get("Cookie"))
+		}
+
+		if c, err := r.Cookie("redirected"); err != nil || c.Value != "1" {
+			t.Errorf("Expected redirect cookie on /redirected, got %v", r.Header.Get("Cookie"))
+		}
+
+		w.WriteHeader(200)
+	})
+
+	Config.SetConfig("http.cookiefile", cookieFile)
+	Config.httpClient = nil
+	defer func() {
+		Config.ResetConfig()
+		Config.httpClient = nil
+	}()
+
+	req, err := newClientRequest("POST", server.URL+"/api", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Body = &byteCloser{bytes.NewReader([]byte("{}"))}
+
+	res, err := doAPIRequest(req, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 200, res.StatusCode)
+}
@@ -0,0 +1,207 @@
+package lfs
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/rubyist/tracerx"
+)
+
+// credentialCache remembers the credentials filled by 'git credential' for the
+// life of the process, so that repeated API and storage requests to the same
+// host don't spawn (and possibly prompt through) 'git credential fill' again.
+// Credentials are grouped by protocol, host, and path, mirroring how Git's
+// own credential helpers scope them.
+type credentialCache struct {
+	mu       sync.Mutex
+	entries  map[string][]*credentialCacheEntry
+	rejected map[string]bool
+}
+
+type credentialCacheEntry struct {
+	creds    Creds
+	approved bool
+}
+
+var credCache = newCredentialCache()
+
+func newCredentialCache() *credentialCache {
+	return &credentialCache{
+		entries:  make(map[string][]*credentialCacheEntry),
+		rejected: make(map[string]bool),
+	}
+}
+
+// Fill returns cached credentials matching the input, or runs
+// 'git credential fill' and caches the result. Credentials that were
+// previously rejected by the server are never returned.
+func (c *credentialCache) Fill(input Creds) (Creds, error) {
+	// Hold the lock while running 'git credential fill', so that concurrent
+	// requests to the same host wait for a single prompt.
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := credentialCacheKey(input)
+	if entry := c.find(key, input["username"], ""); entry != nil {
+		tracerx.Printf("creds: using cached credentials for %s", key)
+		return entry.creds, nil
+	}
+
+	creds, err := execCreds(input, "fill")
+	if err != nil || len(creds) < 1 {
+		return creds, err
+	}
+
+	if c.rejected[credentialRejectKey(key, creds)] {
+		return nil, fmt.Errorf("Git credentials for %s were rejected by the server.", key)
+	}
+
+	c.entries[key] = append(c.entries[key], &credentialCacheEntry{creds: creds})
+	return creds, nil
+}
+
+// Approve tells 'git credential' to store the credentials, the first time
+// they are accepted by the server.
+func (c *credentialCache) Approve(creds Creds) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := credentialCacheKey(creds)
+	entry := c.find(key, creds["username"], creds["password"])
+	if entry != nil && entry.approved {
+		return
+	}
+
+	if entry != nil {
+		entry.approved = true
+	}
+
+	execCreds(creds, "approve")
+}
+
+// Reject tells 'git credential' to erase the credentials, and removes them from
+// the cache so they aren't sent again.
+func (c *credentialCache) Reject(creds Creds) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := credentialCacheKey(creds)
+	c.rejected[credentialRejectKey(key, creds)] = true
+
+	entries := c.entries[key][:0]
+	for _, entry := range c.entries[key] {
+		if entry.creds["username"] != creds["username"] ||
+			entry.creds["password"] != creds["password"] {
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) == 0 {
+		delete(c.entries, key)
+	} else {
+		c.entries[key] = entries
+	}
+
+	execCreds(creds, "reject")
+}
+
+// find returns the cached entry for the given key and username. An empty
+// username matches any entry, and an empty password is not compared.
+func (c *credentialCache) find(key, username, password string) *credentialCacheEntry {
+	for _, entry := range c.entries[key] {
+		if len(username) > 0 && entry.creds["username"] != username {
+			continue
+		}
+
+		if len(password) > 0 && entry.creds["password"] != password {
+			continue
+		}
+
+		return entry
+	}
+
+	return nil
+}
+
+// credentialCacheKey returns the URL that the given credentials are scoped to.
+// The path is only included if 'credential.useHttpPath' is enabled for the
+// URL, just like Git.
+func credentialCacheKey(creds Creds) string {
+	u := &url.URL{
+		Scheme: creds["protocol"],
+		Host:   creds["host"],
+		Path:   "/" + strings.TrimPrefix(creds["path"], "/"),
+	}
+
+	if v, ok := credentialConfig(u, "usehttppath"); ok {
+		if useHttpPath, err := parseConfigBool(v); err == nil && useHttpPath {
+			return u.String()
+		}
+	}
+
+	u.Path = ""
+	return u.String()
+}
+
+func credentialRejectKey(key string, creds Creds) string {
+	return fmt.Sprintf("%s\n%s\n%s", key, creds["username"], creds["password"])
+}
+
+// credentialConfig looks up a "credential.*" Git config value for the given
+// URL. Like Git, a "credential.<url>.<name>" entry matching the URL's scheme,
+// host, and path prefix takes precedence over "credential.<name>". If several
+// URL entries match, the most specific one wins.
+func credentialConfig(u *url.URL, name string) (string, bool) {
+	suffix := "." + strings.ToLower(name)
+	value, found := "", false
+	matchLen := -1
+
+	for key, v := range Config.AllGitConfig() {
+		if key == "credential"+suffix {
+			if matchLen < 0 {
+				value, found = v, true
+			}
+			continue
+		}
+
+		if !strings.HasPrefix(key, "credential.") || !strings.HasSuffix(key, suffix) {
+			continue
+		}
+
+		pattern := key[len("credential.") : len(key)-len(suffix)]
+		if credentialURLMatches(pattern, u) && len(pattern) > matchLen {
+			value, found = v, true
+			matchLen = len(pattern)
+		}
+	}
+
+	return value, found
+}
+
+func credentialURLMatches(pattern string, u *url.URL) bool {
+	p, err := url.Parse(pattern)
+	if err != nil || len(p.Host) == 0 {
+		return false
+	}
+
+	if len(p.Scheme) > 0 && !strings.EqualFold(p.Scheme, u.Scheme) {
+		return false
+	}
+
+	if !strings.EqualFold(p.Host, u.Host) {
+		return false
+	}
+
+	if p.User != nil && u.User != nil && p.User.Username() != u.User.Username() {
+		return false
+	}
+
+	patternPath := strings.Trim(p.Path, "/")
+	if len(patternPath) == 0 {
+		return true
+	}
+
+	path := strings.Trim(u.Path, "/")
+	return path == patternPath || strings.HasPrefix(path, patternPath+"/")
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"os"
 	"os/exec"
 	"strings"
 
@@ -103,13 +104,19 @@ func skipCredsCheck(req *http.Request) bool {
 }
 
 func fillCredentials(req *http.Request, u *url.URL) (Creds, error) {
+	if setRequestAuthFromNetrc(req, u) {
+		return nil, nil
+	}
+
 	path := strings.TrimPrefix(u.Path, "/")
 	input := Creds{"protocol": u.Scheme, "host": u.Host, "path": path}
 	if u.User != nil && u.User.Username() != "" {
 		input["username"] = u.User.Username()
+	} else if username, ok := credentialConfig(u, "username"); ok && len(username) > 0 {
+		input["username"] = username
 	}
 
-	creds, err := execCreds(input, "fill")
+	creds, err := credCache.Fill(input)
 	if creds == nil || len(creds) < 1 {
 		errmsg := fmt.Sprintf("Git credentials for %s not found", u)
 		if err != nil {
@@ -117,7 +124,12 @@ func fillCredentials(req *http.Request, u *url.URL) (Creds, error) {
 		} else {
 			errmsg = errmsg + "."
 		}
-		err = errors.New(errmsg)
+
+		if IsAuthError(err) {
+			err = newAuthError(Error(errors.New(errmsg)))
+		} else {
+			err = errors.New(errmsg)
+		}
 	}
 
 	if err != nil {
@@ -137,10 +149,10 @@ func saveCredentials(creds Creds, res *http.Response) {
 
 	switch res.StatusCode {
 	case 401, 403:
-		execCreds(creds, "reject")
+		credCache.Reject(creds)
 	default:
 		if res.StatusCode < 300 {
-			execCreds(creds, "approve")
+			credCache.Approve(creds)
 		}
 	}
 }
@@ -163,8 +175,19 @@ func (c Creds) Buffer() *bytes.Buffer {
 type credentialFunc func(Creds, string) (Creds, error)
 
 func execCredsCommand(input Creds, subCommand string) (Creds, error) {
+	askpass := ""
+	if subCommand == "fill" {
+		askpass = credentialAskpass()
+	}
+
 	output := new(bytes.Buffer)
 	cmd := exec.Command("git", "credential", subCommand)
+	if len(askpass) > 0 {
+		// Only ask the credential helpers, so that 'git credential' never blocks
+		// on a terminal. Git LFS prompts with the askpass program below if the
+		// helpers don't answer.
+		cmd.Env = append(os.Environ(), "GIT_ASKPASS=", "GIT_TERMINAL_PROMPT=0")
+	}
 	cmd.Stdin = input.Buffer()
 	cmd.Stdout = output
 	/*
@@ -182,9 +205,13 @@ func execCredsCommand(input Creds, subCommand string) (Creds, error) {
 	}
 
 	if _, ok := err.(*exec.ExitError); ok {
+		if subCommand == "fill" && len(askpass) > 0 {
+			return askpassCredentials(askpass, input)
+		}
+
 		if !Config.GetenvBool("GIT_TERMINAL_PROMPT", true) {
-			return nil, fmt.Errorf("Change the GIT_TERMINAL_PROMPT env var to be prompted to enter your credentials for %s://%s.",
-				input["protocol"], input["host"])
+			return nil, newAuthError(fmt.Errorf("Change the GIT_TERMINAL_PROMPT env var to be prompted to enter your credentials for %s://%s.",
+				input["protocol"], input["host"]))
 		}
 
 		// 'git credential' exits with 128 if the helper doesn't fill the username
@@ -211,3 +238,54 @@ func execCredsCommand(input Creds, subCommand string) (Creds, error) {
 }
 
 var execCreds credentialFunc = execCredsCommand
+
+// credentialAskpass returns the program used to prompt for credentials, using
+// the same precedence as Git: GIT_ASKPASS, core.askpass, then SSH_ASKPASS.
+func credentialAskpass() string {
+	if askpass := Config.Getenv("GIT_ASKPASS"); len(askpass) > 0 {
+		return askpass
+	}
+
+	if askpass, ok := Config.GitConfig("core.askpass"); ok && len(askpass) > 0 {
+		return askpass
+	}
+
+	return Config.Getenv("SSH_ASKPASS")
+}
+
+// askpassCredentials prompts for the username (unless given) and password with
+// the askpass program, like Git does when no credential helper answers.
+func askpassCredentials(askpass string, input Creds) (Creds, error) {
+	creds := make(Creds)
+	for key, value := range input {
+		creds[key] = value
+	}
+
+	u := &url.URL{Scheme: input["protocol"], Host: input["host"]}
+	if len(creds["username"]) == 0 {
+		username, err := askpassPrompt(askpass, fmt.Sprintf("Username for '%s': ", u))
+		if err != nil {
+			return nil, err
+		}
+		creds["username"] = username
+	}
+
+	u.User = url.User(creds["username"])
+	password, err := askpassPrompt(askpass, fmt.Sprintf("Password for '%s': ", u))
+	if err != nil {
+		return nil, err
+	}
+	creds["password"] = password
+
+	return creds, nil
+}
+
+func askpassPrompt(askpass, prompt string) (string, error) {
+	tracerx.Printf("creds: prompting with %s", askpass)
+	out, err := exec.Command(askpass, prompt).Output()
+	if err != nil {
+		return "", newAuthError(fmt.Errorf("Error running %s: %s", askpass, err))
+	}
+
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
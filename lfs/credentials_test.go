@@ -3,7 +3,10 @@ package lfs
 import (
 	"encoding/base64"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -153,6 +156,7 @@ func checkGetCredentials(t *testing.T, getCredsFunc func(*http.Request) (Creds,
 	existingRemote := Config.CurrentRemote
 	for _, check := range checks {
 		t.Logf("Checking %q", check.Desc)
+		credCache = newCredentialCache()
 		Config.CurrentRemote = check.CurrentRemote
 
 		for key, value := range check.Config {
@@ -226,6 +230,103 @@ func checkGetCredentials(t *testing.T, getCredsFunc func(*http.Request) (Creds,
 	}
 }
 
+func TestCredentialCacheFillsOnce(t *testing.T) {
+	calls := stubCredentialCalls()
+	defer restoreCredentialCalls()
+
+	input := Creds{"protocol": "https", "host": "git-server.com", "path": "foo"}
+	for i := 0; i < 3; i++ {
+		creds, err := credCache.Fill(input)
+		if err != nil {
+			t.Fatalf("fill %d: %s", i, err)
+		}
+		if creds["password"] != "monkey" {
+			t.Errorf("fill %d: bad password %q", i, creds["password"])
+		}
+	}
+
+	creds, _ := credCache.Fill(input)
+	credCache.Approve(creds)
+	credCache.Approve(creds)
+
+	if (*calls)["fill"] != 1 {
+		t.Errorf("expected 1 fill, got %d", (*calls)["fill"])
+	}
+	if (*calls)["approve"] != 1 {
+		t.Errorf("expected 1 approve, got %d", (*calls)["approve"])
+	}
+}
+
+func TestCredentialCacheNeverReusesRejected(t *testing.T) {
+	calls := stubCredentialCalls()
+	defer restoreCredentialCalls()
+
+	input := Creds{"protocol": "https", "host": "git-server.com"}
+	creds, err := credCache.Fill(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	credCache.Reject(creds)
+
+	creds, err = credCache.Fill(input)
+	if err == nil {
+		t.Errorf("expected an error filling rejected creds, got %v", creds)
+	}
+
+	if (*calls)["fill"] != 2 {
+		t.Errorf("expected 2 fills, got %d", (*calls)["fill"])
+	}
+	if (*calls)["reject"] != 1 {
+		t.Errorf("expected 1 reject, got %d", (*calls)["reject"])
+	}
+}
+
+func TestCredentialCacheUseHttpPath(t *testing.T) {
+	calls := stubCredentialCalls()
+	defer restoreCredentialCalls()
+	defer Config.ResetConfig()
+
+	Config.SetConfig("credential.https://git-server.com/scoped.usehttppath", "true")
+
+	credCache.Fill(Creds{"protocol": "https", "host": "git-server.com", "path": "a"})
+	credCache.Fill(Creds{"protocol": "https", "host": "git-server.com", "path": "b"})
+	if (*calls)["fill"] != 1 {
+		t.Errorf("expected 1 fill without useHttpPath, got %d", (*calls)["fill"])
+	}
+
+	credCache.Fill(Creds{"protocol": "https", "host": "git-server.com", "path": "scoped/a"})
+	credCache.Fill(Creds{"protocol": "https", "host": "git-server.com", "path": "scoped/b"})
+	credCache.Fill(Creds{"protocol": "https", "host": "git-server.com", "path": "scoped/b"})
+	if (*calls)["fill"] != 3 {
+		t.Errorf("expected 3 fills with scoped useHttpPath, got %d", (*calls)["fill"])
+	}
+
+	Config.SetConfig("credential.usehttppath", "true")
+	credCache.Fill(Creds{"protocol": "https", "host": "other-server.com", "path": "a"})
+	credCache.Fill(Creds{"protocol": "https", "host": "other-server.com", "path": "b"})
+	if (*calls)["fill"] != 5 {
+		t.Errorf("expected 5 fills with global useHttpPath, got %d", (*calls)["fill"])
+	}
+}
+
+// stubCredentialCalls resets the credential cache and counts the
+// 'git credential' subcommands run by it.
+func stubCredentialCalls() *map[string]int {
+	calls := make(map[string]int)
+	credCache = newCredentialCache()
+	execCreds = func(input Creds, subCommand string) (Creds, error) {
+		calls[subCommand] += 1
+		return testExecCreds(input, subCommand)
+	}
+	return &calls
+}
+
+func restoreCredentialCalls() {
+	credCache = newCredentialCache()
+	execCreds = testExecCreds
+}
+
 type getCredentialCheck struct {
 	Desc          string
 	Config        map[string]string
@@ -248,15 +349,75 @@ func (c *getCredentialCheck) ExpectCreds() bool {
 }
 
 func init() {
-	execCreds = func(input Creds, subCommand string) (Creds, error) {
-		output := make(Creds)
-		for key, value := range input {
-			output[key] = value
-		}
-		if _, ok := output["username"]; !ok {
-			output["username"] = input["host"]
-		}
-		output["password"] = "monkey"
-		return output, nil
+	execCreds = testExecCreds
+}
+
+func testExecCreds(input Creds, subCommand string) (Creds, error) {
+	output := make(Creds)
+	for key, value := range input {
+		output[key] = value
+	}
+	if _, ok := output["username"]; !ok {
+		output["username"] = input["host"]
+	}
+	output["password"] = "monkey"
+	return output, nil
+}
+
+func TestGetCredentialsFromNetrc(t *testing.T) {
+	home, err := ioutil.TempDir("", "lfs-netrc-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+
+	netrc := "machine netrc-server.com login netrcuser password netrcpass\n" +
+		"machine other-server.com\n  login otheruser\n  password otherpass\n"
+	if err := ioutil.WriteFile(filepath.Join(home, netrcName()), []byte(netrc), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	oldHome := Config.Getenv("HOME")
+	Config.Setenv("HOME", home)
+	defer Config.Setenv("HOME", oldHome)
+
+	checkGetCredentials(t, getCreds, []*getCredentialCheck{
+		{
+			Desc:          "netrc machine",
+			Method:        "GET",
+			Href:          "https://netrc-server.com/foo",
+			Authorization: "Basic " + base64.URLEncoding.EncodeToString([]byte("netrcuser:netrcpass")),
+		},
+		{
+			Desc:          "netrc machine with port",
+			Method:        "GET",
+			Href:          "https://other-server.com:8080/foo",
+			Authorization: "Basic " + base64.URLEncoding.EncodeToString([]byte("otheruser:otherpass")),
+		},
+		{
+			Desc:     "no netrc machine",
+			Method:   "GET",
+			Href:     "https://git-server.com/foo",
+			Protocol: "https",
+			Host:     "git-server.com",
+			Username: "git-server.com",
+			Password: "monkey",
+		},
+		{
+			Desc:     "netrc login mismatch",
+			Method:   "GET",
+			Href:     "https://someone@netrc-server.com/foo",
+			Protocol: "https",
+			Host:     "netrc-server.com",
+			Username: "someone",
+			Password: "monkey",
+		},
+	})
+}
+
+func netrcName() string {
+	if IsWindows() {
+		return "_netrc"
 	}
+	return ".netrc"
 }
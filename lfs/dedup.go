@@ -0,0 +1,137 @@
+package lfs
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/rubyist/tracerx"
+)
+
+// DedupResult is what DedupMediaFile did with a working file.
+type DedupResult int
+
+const (
+	// The working file was replaced with a clone of the stored object.
+	DedupCloned = DedupResult(iota)
+	// The working file doesn't match its pointer, and was left alone.
+	DedupModified = DedupResult(iota)
+	// The working file was left alone because it can't be cloned, such as
+	// when the object isn't stored uncompressed in the local store.
+	DedupSkipped = DedupResult(iota)
+	// The working file already shares its storage with the stored object,
+	// as a clone or a hard link.
+	DedupShared = DedupResult(iota)
+	// The working file doesn't exist.
+	DedupMissing = DedupResult(iota)
+)
+
+// DedupMediaFile replaces filename, a working file with the content of the
+// pointer, with a copy-on-write clone of the stored object, so that they share
+// their storage. It returns an error if the clone fails, such as when the
+// filesystem doesn't support reflinks.
+func DedupMediaFile(filename string, ptr *Pointer) (DedupResult, error) {
+	mediafile, ok := linkableMediaFile(ptr)
+	if !ok {
+		return DedupSkipped, nil
+	}
+
+	stat, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return DedupMissing, nil
+	}
+	if err != nil || !stat.Mode().IsRegular() || stat.Size() != ptr.Size {
+		return DedupModified, nil
+	}
+
+	if mediaStat, err := os.Stat(mediafile); err == nil && os.SameFile(stat, mediaStat) {
+		return DedupShared, nil
+	}
+
+	src, err := os.Open(mediafile)
+	if err != nil {
+		return DedupSkipped, err
+	}
+	defer src.Close()
+
+	if shared, err := workingFileSharesExtents(filename, src); err != nil {
+		tracerx.Printf("dedup: cannot compare the extents of %s: %v", filename, err)
+	} else if shared {
+		return DedupShared, nil
+	}
+
+	if matches, err := workingFileMatches(filename, ptr); err != nil {
+		return DedupSkipped, err
+	} else if !matches {
+		return DedupModified, nil
+	}
+
+	// Clone next to the working file, so that it can be renamed over it.
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), ".lfs-dedup-")
+	if err != nil {
+		return DedupSkipped, err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	err = reflinkFile(tmp, src)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return DedupSkipped, fmt.Errorf("cannot clone %s, the filesystem may not support copy-on-write clones: %v", mediafile, err)
+	}
+
+	if err := os.Chmod(tmp.Name(), stat.Mode().Perm()); err != nil {
+		return DedupSkipped, err
+	}
+	if err := os.Chtimes(tmp.Name(), stat.ModTime(), stat.ModTime()); err != nil {
+		return DedupSkipped, err
+	}
+
+	// Don't replace a file that was changed while it was checked.
+	if now, err := os.Stat(filename); err != nil || now.Size() != stat.Size() || !now.ModTime().Equal(stat.ModTime()) {
+		tracerx.Printf("dedup: %s changed, leaving it alone", filename)
+		return DedupModified, nil
+	}
+
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return DedupSkipped, err
+	}
+
+	return DedupCloned, nil
+}
+
+// workingFileSharesExtents returns whether the content of filename is stored in
+// the same extents as the stored object, so that cloning it again would not
+// reclaim any space.
+func workingFileSharesExtents(filename string, media *os.File) (bool, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	return sharesExtents(file, media)
+}
+
+// workingFileMatches returns whether the content of filename has the size and
+// OID of the pointer.
+func workingFileMatches(filename string, ptr *Pointer) (bool, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	verifier := newVerifyingWriter(ioutil.Discard, ptr.Oid, ptr.Size)
+	if _, err := io.Copy(verifier, file); err != nil {
+		if IsIntegrityError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return verifier.Verify() == nil, nil
+}
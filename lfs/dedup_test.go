@@ -0,0 +1,83 @@
+package lfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/technoweenie/assert"
+)
+
+func TestDedupMediaFile(t *testing.T) {
+	tmp := tempdir(t)
+	defer os.RemoveAll(tmp)
+
+	oldMediaDir := LocalMediaDir
+	LocalMediaDir = filepath.Join(tmp, "objects")
+	defer func() {
+		LocalMediaDir = oldMediaDir
+	}()
+
+	oid := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	mediafile, err := LocalStoreMediaPath(oid)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, ioutil.WriteFile(mediafile, []byte("test"), 0644))
+
+	ptr := NewPointer(oid, 4, nil)
+	work := filepath.Join(tmp, "work")
+	filename := filepath.Join(work, "a.dat")
+	assert.Equal(t, nil, os.MkdirAll(work, 0755))
+
+	// missing and modified files are left alone
+	result, err := DedupMediaFile(filename, ptr)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, DedupMissing, result)
+
+	assert.Equal(t, nil, ioutil.WriteFile(filename, []byte("tesT"), 0644))
+	result, err = DedupMediaFile(filename, ptr)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, DedupModified, result)
+	assertFileContent(t, "tesT", filename)
+
+	// objects that aren't stored can't be cloned
+	missing := NewPointer("60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752", 4, nil)
+	result, err = DedupMediaFile(filename, missing)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, DedupSkipped, result)
+
+	// hard links already share the stored object
+	assert.Equal(t, nil, os.Remove(filename))
+	assert.Equal(t, nil, os.Link(mediafile, filename))
+	result, err = DedupMediaFile(filename, ptr)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, DedupShared, result)
+
+	// matching files are cloned where the filesystem supports it, and are
+	// otherwise left as they are
+	assert.Equal(t, nil, os.Remove(filename))
+	assert.Equal(t, nil, ioutil.WriteFile(filename, []byte("test"), 0755))
+	result, err = DedupMediaFile(filename, ptr)
+	if err == nil {
+		assert.Equal(t, DedupCloned, result)
+	} else {
+		assert.Equal(t, DedupSkipped, result)
+	}
+	assertFileContent(t, "test", filename)
+	assertSameFile(t, false, filename, mediafile)
+
+	stat, err := os.Stat(filename)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, os.FileMode(0755), stat.Mode().Perm())
+
+	// no clones are left behind
+	names, err := ioutil.ReadDir(work)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, len(names))
+}
+
+func assertFileContent(t *testing.T, expected, filename string) {
+	by, err := ioutil.ReadFile(filename)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, expected, string(by))
+}
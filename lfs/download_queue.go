@@ -41,8 +41,8 @@ func (d *DownloadCheckable) SetObject(o *ObjectResource) {
 }
 
 // NewDownloadCheckQueue builds a checking queue, allowing `workers` concurrent check operations.
-func NewDownloadCheckQueue(files int, size int64, dryRun bool) *TransferQueue {
-	q := newTransferQueue(files, size, dryRun)
+func NewDownloadCheckQueue(files int, size int64, dryRun bool, ref string) *TransferQueue {
+	q := newTransferQueue(files, size, dryRun, ref)
 	// API operation is still download, but it will only perform the API call (check)
 	q.transferKind = "download"
 	return q
@@ -66,8 +66,9 @@ func (d *Downloadable) Transfer(cb CopyCallback) error {
 }
 
 // NewDownloadQueue builds a DownloadQueue, allowing `workers` concurrent downloads.
-func NewDownloadQueue(files int, size int64, dryRun bool) *TransferQueue {
-	q := newTransferQueue(files, size, dryRun)
+// The ref being fetched, if any, is sent with each batch API request.
+func NewDownloadQueue(files int, size int64, dryRun bool, ref string) *TransferQueue {
+	q := newTransferQueue(files, size, dryRun, ref)
 	q.transferKind = "download"
 	return q
 }
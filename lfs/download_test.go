@@ -84,6 +84,7 @@ func TestSuccessfulDownload(t *testing.T) {
 	})
 
 	defer Config.ResetConfig()
+	Config.SetConfig("lfs.legacyapi", "true")
 	Config.SetConfig("lfs.batch", "false")
 	Config.SetConfig("lfs.url", server.URL+"/media")
 
@@ -215,6 +216,7 @@ func TestSuccessfulDownloadWithRedirects(t *testing.T) {
 	})
 
 	defer Config.ResetConfig()
+	Config.SetConfig("lfs.legacyapi", "true")
 	Config.SetConfig("lfs.batch", "false")
 	Config.SetConfig("lfs.url", server.URL+"/redirect")
 
@@ -322,6 +324,7 @@ func TestSuccessfulDownloadWithAuthorization(t *testing.T) {
 	})
 
 	defer Config.ResetConfig()
+	Config.SetConfig("lfs.legacyapi", "true")
 	Config.SetConfig("lfs.batch", "false")
 	Config.SetConfig("lfs.url", server.URL+"/media")
 	reader, size, err := Download("oid", 0)
@@ -423,6 +426,7 @@ func TestSuccessfulDownloadFromSeparateHost(t *testing.T) {
 	})
 
 	defer Config.ResetConfig()
+	Config.SetConfig("lfs.legacyapi", "true")
 	Config.SetConfig("lfs.batch", "false")
 	Config.SetConfig("lfs.url", server.URL+"/media")
 	reader, size, err := Download("oid", 0)
@@ -555,6 +559,7 @@ func TestSuccessfulDownloadFromSeparateRedirectedHost(t *testing.T) {
 	})
 
 	defer Config.ResetConfig()
+	Config.SetConfig("lfs.legacyapi", "true")
 	Config.SetConfig("lfs.batch", "false")
 	Config.SetConfig("lfs.url", server.URL+"/media")
 
@@ -593,6 +598,7 @@ func TestDownloadAPIError(t *testing.T) {
 	})
 
 	defer Config.ResetConfig()
+	Config.SetConfig("lfs.legacyapi", "true")
 	Config.SetConfig("lfs.batch", "false")
 	Config.SetConfig("lfs.url", server.URL+"/media")
 	_, _, err := Download("oid", 0)
@@ -662,6 +668,7 @@ func TestDownloadStorageError(t *testing.T) {
 	})
 
 	defer Config.ResetConfig()
+	Config.SetConfig("lfs.legacyapi", "true")
 	Config.SetConfig("lfs.batch", "false")
 	Config.SetConfig("lfs.url", server.URL+"/media")
 	_, _, err := Download("oid", 0)
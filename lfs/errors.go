@@ -189,6 +189,34 @@ func IsDownloadDeclinedError(err error) bool {
 	return false
 }
 
+// IsObjectNotFoundError indicates that the server does not have the requested
+// object (e.g. a 404 error for an object in a batch response).
+func IsObjectNotFoundError(err error) bool {
+	if e, ok := err.(interface {
+		ObjectNotFoundError() bool
+	}); ok {
+		return e.ObjectNotFoundError()
+	}
+	if e, ok := err.(errorWrapper); ok {
+		return IsObjectNotFoundError(e.InnerError())
+	}
+	return false
+}
+
+// IsIntegrityError indicates that the content of an object did not match its
+// OID or size while it was transferred. Retrying the transfer will not help.
+func IsIntegrityError(err error) bool {
+	if e, ok := err.(interface {
+		IntegrityError() bool
+	}); ok {
+		return e.IntegrityError()
+	}
+	if e, ok := err.(errorWrapper); ok {
+		return IsIntegrityError(e.InnerError())
+	}
+	return false
+}
+
 // IsRetriableError indicates the low level transfer had an error but the
 // caller may retry the operation.
 func IsRetriableError(err error) bool {
@@ -533,6 +561,46 @@ func newDownloadDeclinedError(err error) error {
 	return downloadDeclinedError{newWrappedError(err, "File missing and download is not allowed")}
 }
 
+// Definitions for IsObjectNotFoundError()
+
+type objectNotFoundError struct {
+	errorWrapper
+}
+
+func (e objectNotFoundError) InnerError() error {
+	return e.errorWrapper
+}
+
+func (e objectNotFoundError) ObjectNotFoundError() bool {
+	return true
+}
+
+func newObjectNotFoundError(err error, oid string) error {
+	e := objectNotFoundError{newWrappedError(err, "Object not found")}
+	ErrorSetContext(e, "OID", oid)
+	return e
+}
+
+// Definitions for IsIntegrityError()
+
+type integrityError struct {
+	errorWrapper
+}
+
+func (e integrityError) InnerError() error {
+	return e.errorWrapper
+}
+
+func (e integrityError) IntegrityError() bool {
+	return true
+}
+
+func newIntegrityError(err error, oid string) error {
+	e := integrityError{newWrappedError(err, "Integrity check failed")}
+	ErrorSetContext(e, "OID", oid)
+	return e
+}
+
 // Definitions for IsRetriableError()
 
 type retriableError struct {
@@ -2,7 +2,6 @@ package lfs
 
 import (
 	"bytes"
-	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"hash"
@@ -11,6 +10,8 @@ import (
 	"os/exec"
 	"sort"
 	"strings"
+
+	"github.com/github/git-lfs/git"
 )
 
 // An Extension describes how to manipulate files during smudge and clean.
@@ -20,6 +21,7 @@ type Extension struct {
 	Clean    string
 	Smudge   string
 	Priority int
+	Process  string // Runs once and is sent every file, see extension_process.go
 }
 
 type pipeRequest struct {
@@ -27,6 +29,7 @@ type pipeRequest struct {
 	reader     io.Reader
 	fileName   string
 	extensions []Extension
+	hash       *HashAlgorithm // computes the OIDs in the results
 }
 
 type pipeResponse struct {
@@ -41,11 +44,11 @@ type pipeExtResult struct {
 }
 
 type extCommand struct {
-	cmd    *exec.Cmd
-	out    io.WriteCloser
-	err    *bytes.Buffer
-	hasher hash.Hash
-	result *pipeExtResult
+	cmd     *exec.Cmd
+	process *extensionProcess // set instead of cmd for process extensions
+	err     *bytes.Buffer
+	hasher  hash.Hash
+	result  *pipeExtResult
 }
 
 // SortExtensions sorts a map of extensions in ascending order by Priority
@@ -72,107 +75,162 @@ func SortExtensions(m map[string]Extension) ([]Extension, error) {
 	return result, nil
 }
 
+// ExtensionsForPath returns the configured extensions that apply to the file at
+// path, sorted by priority. The lfs-ext attribute of the file lists the names
+// of the extensions that apply to it, separated by commas. All of them apply
+// if it isn't given, and none if it is unset.
+func ExtensionsForPath(path string) ([]Extension, error) {
+	all := Config.Extensions()
+	if len(all) == 0 {
+		return nil, nil
+	}
+
+	attr, err := git.CheckAttr("lfs-ext", path)
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := selectExtensions(all, attr)
+	if err != nil {
+		return nil, fmt.Errorf("%s in the lfs-ext attribute of %s", err, path)
+	}
+	return SortExtensions(selected)
+}
+
+// selectExtensions returns the extensions named by the value of an lfs-ext
+// attribute, as returned by git.CheckAttr.
+func selectExtensions(all map[string]Extension, attr string) (map[string]Extension, error) {
+	switch attr {
+	case "unspecified", "set":
+		return all, nil
+	case "unset":
+		return nil, nil
+	}
+
+	selected := make(map[string]Extension)
+	for _, name := range strings.Split(attr, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if len(name) == 0 {
+			continue
+		}
+
+		ext, ok := all[name]
+		if !ok {
+			return nil, fmt.Errorf("Extension '%s' is not configured", name)
+		}
+		selected[name] = ext
+	}
+	return selected, nil
+}
+
 func pipeExtensions(request *pipeRequest) (response pipeResponse, err error) {
 	var extcmds []*extCommand
 	for _, e := range request.extensions {
-		var pieces []string
+		var command string
 		switch request.action {
 		case "clean":
-			pieces = strings.Split(e.Clean, " ")
+			command = e.Clean
 		case "smudge":
-			pieces = strings.Split(e.Smudge, " ")
+			command = e.Smudge
 		default:
 			err = fmt.Errorf("Invalid action: " + request.action)
 			return
 		}
+
+		ec := &extCommand{hasher: request.hash.New(), result: &pipeExtResult{name: e.Name}}
+		extcmds = append(extcmds, ec)
+
+		if len(e.Process) > 0 {
+			if ec.process, err = startExtensionProcess(e); err != nil {
+				return
+			}
+			continue
+		}
+
+		pieces := strings.Split(command, " ")
 		name := strings.Trim(pieces[0], " ")
 		var args []string
 		for _, value := range pieces[1:] {
 			arg := strings.Replace(value, "%f", request.fileName, -1)
 			args = append(args, arg)
 		}
-		cmd := exec.Command(name, args...)
-		ec := &extCommand{cmd: cmd, result: &pipeExtResult{name: e.Name}}
-		extcmds = append(extcmds, ec)
+		ec.cmd = exec.Command(name, args...)
+		ec.err = &bytes.Buffer{}
+		ec.cmd.Stderr = ec.err
 	}
 
-	hasher := sha256.New()
-	pipeReader, pipeWriter := io.Pipe()
-	multiWriter := io.MultiWriter(hasher, pipeWriter)
-
-	var input io.Reader
-	var output io.WriteCloser
-	input = pipeReader
-	extcmds[0].cmd.Stdin = input
 	if response.file, err = TempFile(""); err != nil {
 		return
 	}
 	defer response.file.Close()
-	output = response.file
 
+	// Each extension reads the output of the one before it through a pipe,
+	// and the first error stops the others.
+	errs := make(chan error, len(extcmds))
+	pipeReader, pipeWriter := io.Pipe()
+	input := pipeReader
 	last := len(extcmds) - 1
 	for i, ec := range extcmds {
-		ec.hasher = sha256.New()
-
-		if i == last {
-			ec.cmd.Stdout = io.MultiWriter(ec.hasher, output)
-			ec.out = output
-			continue
-		}
-
-		nextec := extcmds[i+1]
-		var nextStdin io.WriteCloser
-		var stdout io.ReadCloser
-		if nextStdin, err = nextec.cmd.StdinPipe(); err != nil {
-			return
-		}
-		if stdout, err = ec.cmd.StdoutPipe(); err != nil {
-			return
+		var nextInput *io.PipeReader
+		var output *io.PipeWriter
+		var out io.Writer = response.file
+		if i < last {
+			nextInput, output = io.Pipe()
+			out = output
 		}
 
-		ec.cmd.Stdin = input
-		ec.cmd.Stdout = io.MultiWriter(ec.hasher, nextStdin)
-		ec.out = nextStdin
-
-		input = stdout
+		go func(ec *extCommand, input *io.PipeReader, output *io.PipeWriter, out io.Writer) {
+			err := ec.run(request.action, request.fileName, input, io.MultiWriter(ec.hasher, out))
+			input.Close()
+			if output != nil {
+				output.CloseWithError(err)
+			}
+			errs <- err
+		}(ec, input, output, out)
 
-		var errBuff bytes.Buffer
-		ec.err = &errBuff
-		ec.cmd.Stderr = ec.err
+		input = nextInput
 	}
 
-	for _, ec := range extcmds {
-		if err = ec.cmd.Start(); err != nil {
-			return
+	hasher := request.hash.New()
+	_, copyErr := io.Copy(io.MultiWriter(hasher, pipeWriter), request.reader)
+	pipeWriter.CloseWithError(copyErr)
+
+	for range extcmds {
+		if ecErr := <-errs; ecErr != nil && err == nil {
+			err = ecErr
 		}
 	}
-
-	if _, err = io.Copy(multiWriter, request.reader); err != nil {
-		return
+	if err == nil {
+		err = copyErr
 	}
-	if err = pipeWriter.Close(); err != nil {
+	if err != nil {
 		return
 	}
 
-	for _, ec := range extcmds {
-		if err = ec.cmd.Wait(); err != nil {
-			if ec.err != nil {
-				errStr := ec.err.String()
-				err = fmt.Errorf("Extension '%s' failed with: %s", ec.result.name, errStr)
-			}
-			return
-		}
-		if err = ec.out.Close(); err != nil {
-			return
-		}
-	}
-
-	oid := hex.EncodeToString(hasher.Sum(nil))
+	oid := request.hash.ObjectID(hex.EncodeToString(hasher.Sum(nil)))
 	for _, ec := range extcmds {
 		ec.result.oidIn = oid
-		oid = hex.EncodeToString(ec.hasher.Sum(nil))
+		oid = request.hash.ObjectID(hex.EncodeToString(ec.hasher.Sum(nil)))
 		ec.result.oidOut = oid
 		response.results = append(response.results, ec.result)
 	}
 	return
 }
+
+// run runs the extension on the content read from input, and writes the result
+// to output.
+func (ec *extCommand) run(action, fileName string, input io.Reader, output io.Writer) error {
+	if ec.process != nil {
+		return ec.process.filter(action, fileName, input, output)
+	}
+
+	ec.cmd.Stdin = input
+	ec.cmd.Stdout = output
+	if err := ec.cmd.Start(); err != nil {
+		return err
+	}
+	if err := ec.cmd.Wait(); err != nil {
+		return fmt.Errorf("Extension '%s' failed with: %s", ec.result.name, ec.err.String())
+	}
+	return nil
+}
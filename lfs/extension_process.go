@@ -0,0 +1,341 @@
+package lfs
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/rubyist/tracerx"
+)
+
+// Extensions with a process command are started once, and are sent every file
+// to clean or smudge over their stdin and stdout, instead of running the clean
+// or smudge command once per file. Messages are framed in pkt-lines, as in
+// Git's long-running filter protocol: a packet is 4 hex digits with the length
+// of the packet, including themselves, followed by the data. "0000" is a flush
+// packet, which ends a list of text packets or the content of a file.
+//
+// Git LFS starts with a handshake:
+//
+//   git-lfs -> "git-lfs-extension-client\n" "version=1\n" flush
+//   extension -> "git-lfs-extension-server\n" "version=1\n" flush
+//   git-lfs -> "capability=clean\n" "capability=smudge\n" flush
+//   extension -> the capabilities it supports, flush
+//
+// And then sends each file:
+//
+//   git-lfs -> "command=clean\n" "pathname=path/to/file\n" flush
+//   git-lfs -> content, flush
+//   extension -> "status=success\n" flush
+//   extension -> content, flush
+//   extension -> flush, or "status=error\n" flush if it failed after all
+//
+// An extension that can't process a file responds with "status=error\n" and a
+// flush instead, and no content. Either way it reads all of the content first,
+// though it may start responding before that.
+
+const (
+	maxPacketSize     = 65520
+	maxPacketDataSize = maxPacketSize - 4
+)
+
+var (
+	extensionProcesses   = make(map[string]*extensionProcess)
+	extensionProcessesMu sync.Mutex
+)
+
+// An extensionProcess is a running extension process. It processes one file at
+// a time.
+type extensionProcess struct {
+	name         string
+	cmd          *exec.Cmd
+	in           io.WriteCloser
+	out          *bufio.Reader
+	capabilities map[string]bool
+	mu           sync.Mutex
+	killed       sync.Once
+}
+
+// startExtensionProcess returns the running process of the extension, starting
+// it if it isn't running.
+func startExtensionProcess(e Extension) (*extensionProcess, error) {
+	extensionProcessesMu.Lock()
+	defer extensionProcessesMu.Unlock()
+
+	if p, ok := extensionProcesses[e.Name]; ok {
+		return p, nil
+	}
+
+	pieces := strings.Split(e.Process, " ")
+	cmd := exec.Command(strings.Trim(pieces[0], " "), pieces[1:]...)
+	cmd.Stderr = os.Stderr
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	tracerx.Printf("extension: starting %s process: %s", e.Name, e.Process)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	p := &extensionProcess{name: e.Name, cmd: cmd, in: in, out: bufio.NewReader(out)}
+	if err := p.handshake(); err != nil {
+		p.kill()
+		return nil, fmt.Errorf("Extension '%s' failed to start: %s", e.Name, err)
+	}
+
+	extensionProcesses[e.Name] = p
+	return p, nil
+}
+
+func (p *extensionProcess) handshake() error {
+	if err := writePacketList(p.in, "git-lfs-extension-client", "version=1"); err != nil {
+		return err
+	}
+
+	welcome, err := readPacketList(p.out)
+	if err != nil {
+		return err
+	}
+	if len(welcome) != 2 || welcome[0] != "git-lfs-extension-server" || welcome[1] != "version=1" {
+		return fmt.Errorf("unexpected handshake %q", welcome)
+	}
+
+	if err := writePacketList(p.in, "capability=clean", "capability=smudge"); err != nil {
+		return err
+	}
+
+	capabilities, err := readPacketList(p.out)
+	if err != nil {
+		return err
+	}
+
+	p.capabilities = make(map[string]bool, len(capabilities))
+	for _, c := range capabilities {
+		p.capabilities[strings.TrimPrefix(c, "capability=")] = true
+	}
+	return nil
+}
+
+// filter sends the content read from in to the extension, and writes what it
+// sends back to out. The process is stopped if it stops following the
+// protocol, and started again for the next file.
+func (p *extensionProcess) filter(action, fileName string, in io.Reader, out io.Writer) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.capabilities[action] {
+		return fmt.Errorf("Extension '%s' does not support %s", p.name, action)
+	}
+
+	outErr, err := p.exchange(action, fileName, in, out)
+	switch {
+	case err == errExtensionStatus:
+		return fmt.Errorf("Extension '%s' failed to %s %s", p.name, action, fileName)
+	case err != nil:
+		p.stop()
+		return fmt.Errorf("Extension '%s' failed with: %s", p.name, err)
+	}
+	return outErr
+}
+
+var errExtensionStatus = errors.New("extension status error")
+
+// exchange sends one file to the extension and reads the response, like
+// readResponse. The error is errExtensionStatus if the extension reports an
+// error but is still following the protocol.
+func (p *extensionProcess) exchange(action, fileName string, in io.Reader, out io.Writer) (outErr error, err error) {
+	if err := writePacketList(p.in, "command="+action, "pathname="+fileName); err != nil {
+		return nil, err
+	}
+
+	// The content is sent while the response is read, so extensions can
+	// respond before they have read the whole file.
+	written := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(&packetWriter{p.in}, in)
+		if err == nil {
+			err = writeFlush(p.in)
+		}
+		if err != nil {
+			// Unblock the reader, the extension is waiting for
+			// content that won't come.
+			p.kill()
+		}
+		written <- err
+	}()
+
+	outErr, err = p.readResponse(out)
+	if err != nil && err != errExtensionStatus {
+		// Unblock the writer if the extension stopped reading.
+		p.kill()
+	}
+
+	if werr := <-written; werr != nil && (err == nil || err == errExtensionStatus) {
+		err = werr
+	}
+	return outErr, err
+}
+
+// readResponse reads the response to a file, and writes its content to out.
+// It keeps reading after out fails, so that the protocol stays in sync, and
+// returns that error separately.
+func (p *extensionProcess) readResponse(out io.Writer) (outErr error, err error) {
+	status, err := readPacketList(p.out)
+	if err != nil {
+		return nil, err
+	}
+	if !statusSuccess(status, false) {
+		return nil, errExtensionStatus
+	}
+
+	for {
+		data, err := readPacket(p.out)
+		if err != nil {
+			return nil, err
+		}
+		if data == nil {
+			break
+		}
+		if outErr == nil {
+			_, outErr = out.Write(data)
+		}
+	}
+
+	status, err = readPacketList(p.out)
+	if err != nil {
+		return nil, err
+	}
+	if !statusSuccess(status, true) {
+		return nil, errExtensionStatus
+	}
+	return outErr, nil
+}
+
+// statusSuccess returns whether a status list reports success. An empty list
+// after the content keeps the status from before it.
+func statusSuccess(status []string, afterContent bool) bool {
+	if afterContent && len(status) == 0 {
+		return true
+	}
+	for _, s := range status {
+		if s == "status=success" {
+			return true
+		}
+	}
+	return false
+}
+
+// stop kills the process, so that the extension is started again the next
+// time it is used.
+func (p *extensionProcess) stop() {
+	p.kill()
+
+	extensionProcessesMu.Lock()
+	if extensionProcesses[p.name] == p {
+		delete(extensionProcesses, p.name)
+	}
+	extensionProcessesMu.Unlock()
+}
+
+func (p *extensionProcess) kill() {
+	p.killed.Do(func() {
+		p.cmd.Process.Kill()
+		p.cmd.Wait()
+	})
+}
+
+// packetWriter writes its data as content packets.
+type packetWriter struct {
+	w io.Writer
+}
+
+func (pw *packetWriter) Write(data []byte) (int, error) {
+	var n int
+	for len(data) > 0 {
+		size := len(data)
+		if size > maxPacketDataSize {
+			size = maxPacketDataSize
+		}
+		if err := writePacket(pw.w, data[:size]); err != nil {
+			return n, err
+		}
+		n += size
+		data = data[size:]
+	}
+	return n, nil
+}
+
+func writePacket(w io.Writer, data []byte) error {
+	if _, err := fmt.Fprintf(w, "%04x", len(data)+4); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+func writeFlush(w io.Writer) error {
+	_, err := io.WriteString(w, "0000")
+	return err
+}
+
+// writePacketList writes each line as a text packet, followed by a flush.
+func writePacketList(w io.Writer, lines ...string) error {
+	for _, line := range lines {
+		if err := writePacket(w, []byte(line+"\n")); err != nil {
+			return err
+		}
+	}
+	return writeFlush(w)
+}
+
+// readPacket reads the data of a packet, or nil for a flush packet.
+func readPacket(r io.Reader) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	size, err := strconv.ParseUint(string(header[:]), 16, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid packet length %q", header)
+	}
+	if size == 0 {
+		return nil, nil
+	}
+	if size <= 4 || size > maxPacketSize {
+		return nil, fmt.Errorf("invalid packet length %q", header)
+	}
+
+	data := make([]byte, size-4)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// readPacketList reads text packets up to a flush, without their newlines.
+func readPacketList(r io.Reader) ([]string, error) {
+	var lines []string
+	for {
+		data, err := readPacket(r)
+		if err != nil {
+			return nil, err
+		}
+		if data == nil {
+			return lines, nil
+		}
+		lines = append(lines, strings.TrimSuffix(string(data), "\n"))
+	}
+}
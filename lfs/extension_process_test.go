@@ -0,0 +1,60 @@
+package lfs
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/technoweenie/assert"
+)
+
+func TestWritePacketList(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Equal(t, nil, writePacketList(&buf, "command=clean", "pathname=a.dat"))
+	assert.Equal(t, "0012command=clean\n0013pathname=a.dat\n0000", buf.String())
+
+	lines, err := readPacketList(&buf)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"command=clean", "pathname=a.dat"}, lines)
+}
+
+func TestPacketWriterSplitsContent(t *testing.T) {
+	content := strings.Repeat("a", maxPacketDataSize+10)
+
+	var buf bytes.Buffer
+	n, err := io.Copy(&packetWriter{&buf}, strings.NewReader(content))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(len(content)), n)
+	assert.Equal(t, nil, writeFlush(&buf))
+
+	var sizes []int
+	var read bytes.Buffer
+	for {
+		data, err := readPacket(&buf)
+		assert.Equal(t, nil, err)
+		if data == nil {
+			break
+		}
+		sizes = append(sizes, len(data))
+		read.Write(data)
+	}
+
+	assert.Equal(t, []int{maxPacketDataSize, 10}, sizes)
+	assert.Equal(t, content, read.String())
+}
+
+func TestReadPacketInvalid(t *testing.T) {
+	for _, packet := range []string{"zzzz", "0004", "0003", "fff1", "0010abc"} {
+		_, err := readPacket(strings.NewReader(packet))
+		assert.NotEqual(t, nil, err, packet)
+	}
+}
+
+func TestStatusSuccess(t *testing.T) {
+	assert.Equal(t, true, statusSuccess([]string{"status=success"}, false))
+	assert.Equal(t, false, statusSuccess([]string{"status=error"}, false))
+	assert.Equal(t, false, statusSuccess(nil, false))
+	assert.Equal(t, true, statusSuccess(nil, true))
+	assert.Equal(t, false, statusSuccess([]string{"status=error"}, true))
+}
@@ -13,18 +13,21 @@ func TestSortExtensions(t *testing.T) {
 			"baz-clean %f",
 			"baz-smudge %f",
 			2,
+			"",
 		},
 		"foo": Extension{
 			"foo",
 			"foo-clean %f",
 			"foo-smudge %f",
 			0,
+			"",
 		},
 		"bar": Extension{
 			"bar",
 			"bar-clean %f",
 			"bar-smudge %f",
 			1,
+			"",
 		},
 	}
 
@@ -50,12 +53,14 @@ func TestSortExtensionsDuplicatePriority(t *testing.T) {
 			"foo-clean %f",
 			"foo-smudge %f",
 			0,
+			"",
 		},
 		"bar": Extension{
 			"bar",
 			"bar-clean %f",
 			"bar-smudge %f",
 			0,
+			"",
 		},
 	}
 
@@ -64,3 +69,31 @@ func TestSortExtensionsDuplicatePriority(t *testing.T) {
 	assert.NotEqual(t, err, nil)
 	assert.Equal(t, len(sorted), 0)
 }
+
+func TestSelectExtensions(t *testing.T) {
+	m := map[string]Extension{
+		"foo": Extension{Name: "foo", Priority: 0},
+		"bar": Extension{Name: "bar", Priority: 1},
+	}
+
+	selected, err := selectExtensions(m, "unspecified")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 2, len(selected))
+
+	selected, err = selectExtensions(m, "set")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 2, len(selected))
+
+	selected, err = selectExtensions(m, "unset")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(selected))
+
+	selected, err = selectExtensions(m, "Bar,")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, len(selected))
+	assert.Equal(t, "bar", selected["bar"].Name)
+
+	selected, err = selectExtensions(m, "foo,baz")
+	assert.Equal(t, "Extension 'baz' is not configured", err.Error())
+	assert.Equal(t, 0, len(selected))
+}
@@ -0,0 +1,205 @@
+package lfs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// A FilterProcessScanner reads the files that Git sends to a long-running
+// filter, configured with filter.<driver>.process, and writes the responses.
+// The protocol is described in gitattributes(5). It uses the same pkt-lines as
+// the extension process protocol, and starts with a handshake:
+//
+//   git -> "git-filter-client\n" "version=2\n" flush
+//   git-lfs -> "git-filter-server\n" "version=2\n" flush
+//   git -> "capability=clean\n" "capability=smudge\n" flush
+//   git-lfs -> "capability=clean\n" "capability=smudge\n" flush
+//
+// Git then sends each file as a list of headers, such as "command=smudge\n" and
+// "pathname=path/to/file\n", followed by its content, and reads the response
+// once it has sent all of the content. Git stops the filter by closing its
+// stdin.
+type FilterProcessScanner struct {
+	r       *bufio.Reader
+	w       *bufio.Writer
+	content *packetReader // Content of the current file
+}
+
+// NewFilterProcessScanner returns a FilterProcessScanner that reads from r and
+// writes to w, which are usually stdin and stdout.
+func NewFilterProcessScanner(r io.Reader, w io.Writer) *FilterProcessScanner {
+	return &FilterProcessScanner{r: bufio.NewReader(r), w: bufio.NewWriter(w)}
+}
+
+// Init performs the handshake, agreeing to clean and smudge files.
+func (s *FilterProcessScanner) Init() error {
+	welcome, err := readPacketList(s.r)
+	if err != nil {
+		return err
+	}
+	if len(welcome) < 1 || welcome[0] != "git-filter-client" || !containsString(welcome[1:], "version=2") {
+		return fmt.Errorf("unexpected handshake %q", welcome)
+	}
+
+	if err := writePacketList(s.w, "git-filter-server", "version=2"); err != nil {
+		return err
+	}
+	if err := s.w.Flush(); err != nil {
+		return err
+	}
+
+	capabilities, err := readPacketList(s.r)
+	if err != nil {
+		return err
+	}
+
+	var supported []string
+	for _, c := range []string{"capability=clean", "capability=smudge"} {
+		if containsString(capabilities, c) {
+			supported = append(supported, c)
+		}
+	}
+
+	if err := writePacketList(s.w, supported...); err != nil {
+		return err
+	}
+	return s.w.Flush()
+}
+
+// Next reads the headers of the next file, by their keys. It returns io.EOF
+// once Git has no more files to filter. The content of the file must be read
+// from Content before responding.
+func (s *FilterProcessScanner) Next() (map[string]string, error) {
+	lines, err := readPacketList(s.r)
+	if err != nil {
+		return nil, err
+	}
+
+	s.content = &packetReader{r: s.r}
+
+	headers := make(map[string]string, len(lines))
+	for _, line := range lines {
+		if parts := strings.SplitN(line, "=", 2); len(parts) == 2 {
+			headers[parts[0]] = parts[1]
+		}
+	}
+	return headers, nil
+}
+
+// Content returns a reader for the content of the file, up to the flush that
+// ends it.
+func (s *FilterProcessScanner) Content() io.Reader {
+	return s.content
+}
+
+// Respond writes the response to the file, with the content that fn writes.
+// If fn fails before writing anything, the file is reported as failed without
+// any content. If it fails after, the content is sent and then reported as
+// failed. Either way, the rest of the file's content is read first, so that
+// the next file can be read, and the error from fn is returned.
+func (s *FilterProcessScanner) Respond(fn func(w io.Writer) error) error {
+	w := &filterResponseWriter{w: s.w}
+	err := fn(w)
+
+	if _, derr := io.Copy(ioutil.Discard, s.content); derr != nil {
+		return derr
+	}
+
+	var werr error
+	switch {
+	case err != nil && !w.started:
+		werr = writePacketList(s.w, "status=error")
+	case err != nil:
+		if werr = w.start(); werr == nil {
+			if werr = writeFlush(s.w); werr == nil {
+				werr = writePacketList(s.w, "status=error")
+			}
+		}
+	default:
+		if werr = w.start(); werr == nil {
+			if werr = writeFlush(s.w); werr == nil {
+				// An empty list keeps the status from before the
+				// content.
+				werr = writeFlush(s.w)
+			}
+		}
+	}
+
+	if werr == nil {
+		werr = s.w.Flush()
+	}
+	if werr != nil {
+		return werr
+	}
+	return err
+}
+
+// filterResponseWriter writes the success status before the first of the
+// content it is given, in content packets.
+type filterResponseWriter struct {
+	w       *bufio.Writer
+	started bool
+}
+
+func (w *filterResponseWriter) start() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	return writePacketList(w.w, "status=success")
+}
+
+func (w *filterResponseWriter) Write(data []byte) (int, error) {
+	if len(data) == 0 {
+		return 0, nil
+	}
+	if err := w.start(); err != nil {
+		return 0, err
+	}
+	return (&packetWriter{w.w}).Write(data)
+}
+
+// packetReader reads the data of content packets, up to a flush. Once it has
+// read the flush, it returns io.EOF.
+type packetReader struct {
+	r    io.Reader
+	buf  []byte
+	done bool
+}
+
+func (pr *packetReader) Read(p []byte) (int, error) {
+	for len(pr.buf) == 0 {
+		if pr.done {
+			return 0, io.EOF
+		}
+
+		data, err := readPacket(pr.r)
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		if data == nil {
+			pr.done = true
+			return 0, io.EOF
+		}
+		pr.buf = data
+	}
+
+	n := copy(p, pr.buf)
+	pr.buf = pr.buf[n:]
+	return n, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package lfs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/technoweenie/assert"
+)
+
+func TestFilterProcessScanner(t *testing.T) {
+	var in bytes.Buffer
+	writePacketList(&in, "git-filter-client", "version=2")
+	writePacketList(&in, "capability=clean", "capability=smudge", "capability=delay")
+	for _, content := range []string{"abc", "fail", "partial", ""} {
+		writePacketList(&in, "command=clean", "pathname="+content+".dat")
+		(&packetWriter{&in}).Write([]byte(content))
+		writeFlush(&in)
+	}
+
+	var out bytes.Buffer
+	s := NewFilterProcessScanner(&in, &out)
+	assert.Equal(t, nil, s.Init())
+
+	for {
+		headers, err := s.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "clean", headers["command"])
+
+		err = s.Respond(func(w io.Writer) error {
+			switch headers["pathname"] {
+			case "fail.dat":
+				return errors.New("failed")
+			case "partial.dat":
+				// leaves the content unread
+				io.WriteString(w, "part")
+				return errors.New("failed")
+			}
+
+			data, err := ioutil.ReadAll(s.Content())
+			if err != nil {
+				return err
+			}
+			_, err = w.Write([]byte(strings.ToUpper(string(data))))
+			return err
+		})
+
+		if headers["pathname"] == "abc.dat" || headers["pathname"] == ".dat" {
+			assert.Equal(t, nil, err)
+		} else {
+			assert.NotEqual(t, nil, err)
+		}
+	}
+
+	var expected bytes.Buffer
+	writePacketList(&expected, "git-filter-server", "version=2")
+	writePacketList(&expected, "capability=clean", "capability=smudge")
+	// abc.dat
+	writePacketList(&expected, "status=success")
+	writePacket(&expected, []byte("ABC"))
+	writeFlush(&expected)
+	writeFlush(&expected)
+	// fail.dat
+	writePacketList(&expected, "status=error")
+	// partial.dat
+	writePacketList(&expected, "status=success")
+	writePacket(&expected, []byte("part"))
+	writeFlush(&expected)
+	writePacketList(&expected, "status=error")
+	// .dat, which is empty
+	writePacketList(&expected, "status=success")
+	writeFlush(&expected)
+	writeFlush(&expected)
+
+	assert.Equal(t, expected.String(), out.String())
+}
+
+func TestFilterProcessScannerInvalidHandshake(t *testing.T) {
+	var in bytes.Buffer
+	writePacketList(&in, "git-filter-client", "version=1")
+
+	var out bytes.Buffer
+	s := NewFilterProcessScanner(&in, &out)
+	assert.NotEqual(t, nil, s.Init())
+	assert.Equal(t, 0, out.Len())
+}
@@ -0,0 +1,143 @@
+package lfs
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Object IDs are the hex hash of an object's content, computed with one of the
+// registered hash algorithms. SHA-256 is the default, and its object IDs are
+// bare hex, as they have always been. Object IDs of other algorithms are tagged
+// with the algorithm's name, like "sha512:<hex>", the same as in the "oid" line
+// of a pointer file, so that code that passes object IDs around works the same
+// for any algorithm. Their objects are stored in a directory named after the
+// algorithm inside LocalMediaDir.
+
+// A HashAlgorithm is a hash function that object IDs can be computed with.
+type HashAlgorithm struct {
+	// Name is the name of the algorithm in pointer files, such as "sha256".
+	Name string
+	// New returns a new hash.Hash computing the algorithm.
+	New     func() hash.Hash
+	hexSize int
+}
+
+var (
+	hashAlgorithms       = make(map[string]*HashAlgorithm)
+	hashAlgorithmNameRE  = regexp.MustCompile(`\A[a-z0-9-]+\z`)
+	hexRE                = regexp.MustCompile(`\A[[:alnum:]]+\z`)
+	defaultHashAlgorithm = RegisterHashAlgorithm("sha256", sha256.New)
+)
+
+func init() {
+	RegisterHashAlgorithm("sha512", sha512.New)
+}
+
+// RegisterHashAlgorithm makes a hash algorithm available to pointers and the
+// local store under the given name. It panics if the name is invalid or
+// already registered, and is meant to be called from init functions.
+func RegisterHashAlgorithm(name string, newHash func() hash.Hash) *HashAlgorithm {
+	if !hashAlgorithmNameRE.MatchString(name) {
+		panic("invalid hash algorithm name: " + name)
+	}
+	if _, ok := hashAlgorithms[name]; ok {
+		panic("hash algorithm registered twice: " + name)
+	}
+
+	a := &HashAlgorithm{Name: name, New: newHash, hexSize: newHash().Size() * 2}
+	hashAlgorithms[name] = a
+	return a
+}
+
+// LookupHashAlgorithm returns the registered hash algorithm with the given
+// name, or nil if there is none.
+func LookupHashAlgorithm(name string) *HashAlgorithm {
+	return hashAlgorithms[name]
+}
+
+// ObjectHashAlgorithm returns the hash algorithm of the given object ID, or nil
+// if it is tagged with an algorithm that isn't registered.
+func ObjectHashAlgorithm(oid string) *HashAlgorithm {
+	a, _ := splitObjectID(oid)
+	return a
+}
+
+// ObjectID returns the object ID of an object whose content hashes to hex with
+// this algorithm.
+func (a *HashAlgorithm) ObjectID(hex string) string {
+	if a == defaultHashAlgorithm {
+		return hex
+	}
+	return a.Name + ":" + hex
+}
+
+func (a *HashAlgorithm) validHex(hex string) bool {
+	return len(hex) == a.hexSize && hexRE.MatchString(hex)
+}
+
+// splitObjectID returns the hash algorithm and hex hash of an object ID. The
+// algorithm is nil if it isn't registered.
+func splitObjectID(oid string) (*HashAlgorithm, string) {
+	if i := strings.Index(oid, ":"); i >= 0 {
+		return hashAlgorithms[oid[:i]], oid[i+1:]
+	}
+	return defaultHashAlgorithm, oid
+}
+
+// objectIDHex returns the hex hash of an object ID, without any algorithm tag.
+func objectIDHex(oid string) string {
+	_, hex := splitObjectID(oid)
+	return hex
+}
+
+// ValidObjectID returns whether oid is the ID of an object hashed with a
+// registered algorithm. SHA-256 object IDs must not be tagged.
+func ValidObjectID(oid string) bool {
+	a, hex := splitObjectID(oid)
+	return a != nil && a.validHex(hex) && a.ObjectID(hex) == oid
+}
+
+// objectRelPath returns the path of an object relative to the objects
+// directory of a store.
+func objectRelPath(oid string) string {
+	a, hex := splitObjectID(oid)
+	path := filepath.Join(hex[0:2], hex[2:4], hex)
+	if a != defaultHashAlgorithm {
+		return filepath.Join(oidAlgorithmName(oid), path)
+	}
+	return path
+}
+
+// oidAlgorithmName returns the algorithm tag of an object ID, or "" if it has
+// none.
+func oidAlgorithmName(oid string) string {
+	if i := strings.Index(oid, ":"); i >= 0 {
+		return oid[:i]
+	}
+	return ""
+}
+
+// objectIDFromPath returns the ID of the object stored at path, in a store laid
+// out by objectRelPath.
+func objectIDFromPath(path string) string {
+	hex := filepath.Base(path)
+	dir := filepath.Base(filepath.Dir(filepath.Dir(filepath.Dir(path))))
+	if a := hashAlgorithms[dir]; a != nil && a != defaultHashAlgorithm && a.validHex(hex) {
+		return a.ObjectID(hex)
+	}
+	return hex
+}
+
+// objectStoreDir returns the objects directory of the store that the object at
+// path is in.
+func objectStoreDir(path string) string {
+	dir := filepath.Dir(filepath.Dir(filepath.Dir(path)))
+	if objectIDFromPath(path) != filepath.Base(path) {
+		return filepath.Dir(dir)
+	}
+	return dir
+}
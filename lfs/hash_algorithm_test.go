@@ -0,0 +1,103 @@
+package lfs
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/technoweenie/assert"
+)
+
+// sha512 of "test"
+const sha512Hex = "ee26b0dd4af7e749aa1a8ee3c10ae9923f618980772e473f8819a5d4940e0db27ac185f8a0e1d5f84f88bc887fd67b143732c304cc5fa9ad8e6f57f50028a8ff"
+
+func TestHashAlgorithmObjectID(t *testing.T) {
+	sha512 := LookupHashAlgorithm("sha512")
+	assert.Equal(t, "sha512", sha512.Name)
+	assert.Equal(t, "sha512:"+sha512Hex, sha512.ObjectID(sha512Hex))
+	assert.Equal(t, verifyingWriterOid, defaultHashAlgorithm.ObjectID(verifyingWriterOid))
+	assert.Equal(t, (*HashAlgorithm)(nil), LookupHashAlgorithm("md5"))
+
+	assert.Equal(t, sha512, ObjectHashAlgorithm("sha512:"+sha512Hex))
+	assert.Equal(t, defaultHashAlgorithm, ObjectHashAlgorithm(verifyingWriterOid))
+	assert.Equal(t, (*HashAlgorithm)(nil), ObjectHashAlgorithm("md5:abc"))
+}
+
+func TestValidObjectID(t *testing.T) {
+	tests := map[string]bool{
+		verifyingWriterOid:                  true,
+		"sha256:" + verifyingWriterOid:      false,
+		"sha512:" + sha512Hex:               true,
+		sha512Hex:                           false,
+		"sha512:" + verifyingWriterOid:      false,
+		"md5:" + verifyingWriterOid:         false,
+		verifyingWriterOid[1:]:              false,
+		verifyingWriterOid + "0":            false,
+		strings.Repeat("$", 64):             false,
+		"sha512:" + sha512Hex[1:] + "/":     false,
+		"sha512:" + strings.Repeat("a", 64): false,
+	}
+
+	for oid, expected := range tests {
+		assert.Equalf(t, expected, ValidObjectID(oid), "oid %q", oid)
+	}
+}
+
+func TestObjectPaths(t *testing.T) {
+	objects := filepath.Join("lfs", "objects")
+
+	path := filepath.Join(objects, objectRelPath(verifyingWriterOid))
+	assert.Equal(t, filepath.Join(objects, "9f", "86", verifyingWriterOid), path)
+	assert.Equal(t, verifyingWriterOid, objectIDFromPath(path))
+	assert.Equal(t, objects, objectStoreDir(path))
+
+	path = filepath.Join(objects, objectRelPath("sha512:"+sha512Hex))
+	assert.Equal(t, filepath.Join(objects, "sha512", "ee", "26", sha512Hex), path)
+	assert.Equal(t, "sha512:"+sha512Hex, objectIDFromPath(path))
+	assert.Equal(t, objects, objectStoreDir(path))
+}
+
+func TestLocalMediaPathNamespacesAlgorithms(t *testing.T) {
+	tmp := tempdir(t)
+	defer os.RemoveAll(tmp)
+
+	oldMediaDir := LocalMediaDir
+	LocalMediaDir = tmp
+	defer func() {
+		LocalMediaDir = oldMediaDir
+	}()
+
+	path, err := LocalMediaPath("sha512:" + sha512Hex)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, filepath.Join(tmp, "sha512", "ee", "26", sha512Hex), path)
+	assert.Equal(t, nil, ioutil.WriteFile(path, []byte("test"), 0644))
+
+	path, err = LocalMediaPath(verifyingWriterOid)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, filepath.Join(tmp, "9f", "86", verifyingWriterOid), path)
+	assert.Equal(t, nil, ioutil.WriteFile(path, []byte("test"), 0644))
+
+	assert.Equal(t, true, ObjectExistsOfSize("sha512:"+sha512Hex, 4))
+
+	oids := make(map[string]int64)
+	for _, p := range AllLocalObjects() {
+		oids[p.Oid] = p.Size
+	}
+	assert.Equal(t, map[string]int64{"sha512:" + sha512Hex: 4, verifyingWriterOid: 4}, oids)
+}
+
+func TestVerifyingWriterSha512(t *testing.T) {
+	w := newVerifyingWriter(ioutil.Discard, "sha512:"+sha512Hex, 4)
+	_, err := io.Copy(w, strings.NewReader("test"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, w.Verify())
+
+	// the same hex hash, but of another algorithm
+	w = newVerifyingWriter(ioutil.Discard, sha512Hex, 4)
+	_, err = io.Copy(w, strings.NewReader("test"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, IsIntegrityError(w.Verify()))
+}
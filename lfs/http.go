@@ -40,7 +40,7 @@ var (
 )
 
 func LogTransfer(key string, res *http.Response) {
-	if Config.isLoggingStats {
+	if Config.collectingStats() {
 		transferBucketsLock.Lock()
 		transferBuckets[key] = append(transferBuckets[key], res)
 		transferBucketsLock.Unlock()
@@ -49,6 +49,7 @@ func LogTransfer(key string, res *http.Response) {
 
 type HttpClient struct {
 	*http.Client
+	proxy *proxyConfig
 }
 
 func (c *HttpClient) Do(req *http.Request) (*http.Response, error) {
@@ -63,6 +64,9 @@ func (c *HttpClient) Do(req *http.Request) (*http.Response, error) {
 
 	start := time.Now()
 	res, err := c.Client.Do(req)
+	if c.proxy != nil {
+		c.proxy.saveCredentials(req, res, err)
+	}
 	if err != nil {
 		return res, err
 	}
@@ -72,7 +76,7 @@ func (c *HttpClient) Do(req *http.Request) (*http.Response, error) {
 	cresp := countingResponse(res)
 	res.Body = cresp
 
-	if Config.isLoggingStats {
+	if Config.collectingStats() {
 		reqHeaderSize := 0
 		resHeaderSize := 0
 
@@ -103,8 +107,9 @@ func (c *Configuration) HttpClient() *HttpClient {
 		return c.httpClient
 	}
 
+	proxy := c.proxyConfig()
 	tr := &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
+		Proxy: proxy.Proxy,
 		Dial: (&net.Dialer{
 			Timeout:   5 * time.Second,
 			KeepAlive: 30 * time.Second,
@@ -118,7 +123,8 @@ func (c *Configuration) HttpClient() *HttpClient {
 	}
 
 	c.httpClient = &HttpClient{
-		&http.Client{Transport: tr, CheckRedirect: checkRedirect},
+		Client: &http.Client{Transport: tr, CheckRedirect: checkRedirect, Jar: c.cookieJar()},
+		proxy:  proxy,
 	}
 
 	return c.httpClient
@@ -199,7 +205,18 @@ func traceHttpDump(direction string, dump []byte) {
 	}
 }
 
+// isTraceableContent returns whether the body with the given headers should be
+// traced. Encoded bodies, such as gzipped batch API responses, are traced after
+// they are decoded instead.
 func isTraceableContent(h http.Header) bool {
+	if encoding := h.Get("Content-Encoding"); len(encoding) > 0 && !strings.EqualFold(encoding, "identity") {
+		return false
+	}
+
+	return isTraceableContentType(h)
+}
+
+func isTraceableContentType(h http.Header) bool {
 	ctype := strings.ToLower(strings.SplitN(h.Get("Content-Type"), ";", 2)[0])
 	for _, tracedType := range tracedTypes {
 		if strings.Contains(ctype, tracedType) {
@@ -255,7 +272,7 @@ func (c *countingReadCloser) Read(b []byte) (int, error) {
 		}
 	}
 
-	if err == io.EOF && Config.isLoggingStats {
+	if err == io.EOF && Config.collectingStats() {
 		// This transfer is done, we're checking it this way so we can also
 		// catch transfers where the caller forgets to Close() the Body.
 		if c.response != nil {
@@ -0,0 +1,102 @@
+package lfs
+
+import (
+	"fmt"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/rubyist/tracerx"
+)
+
+// legacyApiAdapter asks the legacy API how to transfer the objects of a
+// TransferQueue, making one POST call per object. It is only used if
+// lfs.legacyapi is enabled, and the server does not support the batch API, or
+// lfs.batch is false.
+type legacyApiAdapter struct {
+	q    *TransferQueue
+	apic chan Transferable // Channel for processing individual API requests
+}
+
+func newLegacyApiAdapter(q *TransferQueue) *legacyApiAdapter {
+	tracerx.Printf("tq: running as individual queue")
+
+	a := &legacyApiAdapter{
+		q:    q,
+		apic: make(chan Transferable, batchSize),
+	}
+
+	a.launchApiRoutines()
+
+	return a
+}
+
+func (a *legacyApiAdapter) Add(t Transferable) {
+	a.apic <- t
+}
+
+// Flush is a no-op, since each Transferable is sent to the API as it is added.
+func (a *legacyApiAdapter) Flush() {}
+
+func (a *legacyApiAdapter) Close() {
+	close(a.apic)
+}
+
+// launchApiRoutines first launches a single api worker. When it receives the
+// first successful api request it launches workers - 1 more workers. This
+// prevents being prompted for credentials multiple times at once when they're
+// needed.
+func (a *legacyApiAdapter) launchApiRoutines() {
+	go func() {
+		apiWaiter := make(chan interface{})
+		go a.apiRoutine(apiWaiter)
+
+		<-apiWaiter
+
+		for i := 0; i < a.q.workers-1; i++ {
+			go a.apiRoutine(nil)
+		}
+	}()
+}
+
+// apiRoutine processes the queue of transfers one at a time by making a POST
+// call for each object, feeding the results to the transfer workers. If
+// configured, the object transfers can still happen concurrently, the
+// sequential nature here is only for the meta POST calls.
+func (a *legacyApiAdapter) apiRoutine(apiWaiter chan interface{}) {
+	q := a.q
+
+	for t := range a.apic {
+		obj, err := t.Check()
+		if err != nil {
+			if q.canRetry(err) {
+				q.retry(t)
+			} else {
+				q.errorc <- err
+			}
+			q.wait.Done()
+			continue
+		}
+
+		if apiWaiter != nil { // Signal to launch more individual api workers
+			q.meter.Start()
+			select {
+			case apiWaiter <- 1:
+			default:
+			}
+		}
+
+		if obj != nil {
+			t.SetObject(obj)
+			q.meter.Add(t.Name())
+			q.transferc <- t
+		} else {
+			q.meter.Skip(t.Size())
+			q.wait.Done()
+		}
+	}
+}
+
+// legacyApiDisabledError is returned when the legacy API would be used, but
+// lfs.legacyapi is not enabled.
+func legacyApiDisabledError(e Endpoint) error {
+	return Error(fmt.Errorf("The Git LFS server at %s does not support the batch API.\n"+
+		"To use its legacy API, run: git config lfs.legacyapi true", e.Url))
+}
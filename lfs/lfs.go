@@ -50,24 +50,59 @@ func ResetTempDir() error {
 }
 
 func localMediaDirNoCreate(sha string) string {
-	return filepath.Join(LocalMediaDir, sha[0:2], sha[2:4])
+	return filepath.Dir(localMediaPathNoCreate(sha))
 }
 func localMediaPathNoCreate(sha string) string {
-	return filepath.Join(localMediaDirNoCreate(sha), sha)
+	return filepath.Join(LocalMediaDir, objectRelPath(sha))
 }
 
+// LocalMediaPath returns the path to read the object from. This is the object
+// in the local store, or in one of the read-only alternate stores if only an
+// alternate has it. If neither has it, it is the path in the local store that
+// the object will be written to. Objects with SHA-256 OIDs are stored in
+// LocalMediaDir, and objects of other hash algorithms in a directory named
+// after the algorithm inside it.
 func LocalMediaPath(sha string) (string, error) {
+	path, err := LocalStoreMediaPath(sha)
+	if err != nil {
+		return "", err
+	}
+
+	if !localObjectExists(path) {
+		if alternate := alternateMediaPath(sha); len(alternate) > 0 {
+			return alternate, nil
+		}
+	}
+
+	return path, nil
+}
+
+// LocalStoreMediaPath returns the path of the object in the local store, where
+// new objects are written, ignoring any alternate stores.
+func LocalStoreMediaPath(sha string) (string, error) {
 	path := localMediaDirNoCreate(sha)
 	if err := os.MkdirAll(path, localMediaDirPerms); err != nil {
 		return "", fmt.Errorf("Error trying to create local media directory in '%s': %s", path, err)
 	}
 
-	return filepath.Join(path, sha), nil
+	return localMediaPathNoCreate(sha), nil
 }
 
+// ObjectExistsOfSize returns whether the object is in the local store, or one
+// of the alternate stores, with the given size.
 func ObjectExistsOfSize(sha string, size int64) bool {
 	path := localMediaPathNoCreate(sha)
-	return FileExistsOfSize(path, size)
+	if localObjectExistsOfSize(path, size) {
+		return true
+	}
+
+	for _, dir := range LocalMediaAlternates() {
+		if localObjectExistsOfSize(filepath.Join(dir, objectRelPath(sha)), size) {
+			return true
+		}
+	}
+
+	return false
 }
 
 func Environ() []string {
@@ -105,6 +140,14 @@ func ResolveDirs() {
 		LocalMediaDir = filepath.Join(LocalGitStorageDir, "lfs", "objects")
 		LocalLogDir = filepath.Join(LocalMediaDir, "logs")
 		TempDir = filepath.Join(LocalGitDir, "lfs", "tmp") // temp files per worktree
+
+		if storageDir := resolveLfsStorageDir(LocalGitStorageDir); len(storageDir) > 0 {
+			LocalMediaDir = filepath.Join(storageDir, "objects")
+			LocalLogDir = filepath.Join(LocalMediaDir, "logs")
+			// Temp files are renamed into the store, so keep them on the
+			// same filesystem.
+			TempDir = filepath.Join(storageDir, "tmp")
+		}
 		if err := os.MkdirAll(LocalMediaDir, localMediaDirPerms); err != nil {
 			panic(fmt.Errorf("Error trying to create objects directory in '%s': %s", LocalMediaDir, err))
 		}
@@ -140,6 +183,29 @@ func init() {
 	)
 }
 
+// SharedLocalStorage returns true if lfs.storage puts the local store outside
+// .git/lfs, where other clones may share it. Only the current clone's refs are
+// known, so objects in a shared store must not be deleted.
+func SharedLocalStorage() bool {
+	return len(LocalGitStorageDir) > 0 &&
+		LocalMediaDir != filepath.Join(LocalGitStorageDir, "lfs", "objects")
+}
+
+// resolveLfsStorageDir returns the directory set by lfs.storage, which holds
+// the LFS objects instead of .git/lfs. A relative path is relative to the Git
+// storage dir. It returns "" if lfs.storage is not set.
+func resolveLfsStorageDir(gitStorageDir string) string {
+	dir := git.Config.Find("lfs.storage")
+	if len(dir) == 0 {
+		return ""
+	}
+
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(gitStorageDir, dir)
+	}
+	return filepath.Clean(dir)
+}
+
 func resolveGitDir() (string, string, error) {
 	gitDir := Config.Getenv("GIT_DIR")
 	workTree := Config.Getenv("GIT_WORK_TREE")
@@ -296,12 +362,14 @@ func AllLocalObjectsChan() <-chan *Pointer {
 	go func() {
 		defer close(ret)
 
-		scanStorageDir(LocalMediaDir, ret)
+		scanStorageDir(LocalMediaDir, defaultHashAlgorithm, ret)
 	}()
 	return ret
 }
 
-func scanStorageDir(dir string, c chan *Pointer) {
+// scanStorageDir sends the objects stored in dir to c, as objects hashed with
+// alg. Objects of other algorithms are in directories named after them.
+func scanStorageDir(dir string, alg *HashAlgorithm, c chan *Pointer) {
 	// ioutil.ReadDir and filepath.Walk do sorting which is unnecessary & inefficient
 	dirf, err := os.Open(dir)
 	if err != nil {
@@ -317,11 +385,30 @@ func scanStorageDir(dir string, c chan *Pointer) {
 	for _, dirfi := range direntries {
 		if dirfi.IsDir() {
 			subpath := filepath.Join(dir, dirfi.Name())
-			scanStorageDir(subpath, c)
+			subalg := alg
+			if a := LookupHashAlgorithm(dirfi.Name()); a != nil && alg == defaultHashAlgorithm {
+				subalg = a
+			}
+			scanStorageDir(subpath, subalg, c)
 		} else {
 			// Make sure it's really an object file & not .DS_Store etc
-			if oidRE.MatchString(dirfi.Name()) {
-				c <- NewPointer(dirfi.Name(), dirfi.Size(), nil)
+			name := dirfi.Name()
+			if alg.validHex(name) {
+				c <- NewPointer(alg.ObjectID(name), dirfi.Size(), nil)
+			} else if ext := filepath.Ext(name); (ext == compressedObjectExt || ext == chunkManifestExt) && alg.validHex(strings.TrimSuffix(name, ext)) {
+				// A compressed or chunked object, unless another
+				// form is stored too and was reported instead.
+				hex := strings.TrimSuffix(name, ext)
+				path := filepath.Join(dir, hex)
+				if StoredObjectFile(path) != filepath.Join(dir, name) {
+					continue
+				}
+				size, err := StatLocalObject(path)
+				if err != nil {
+					tracerx.Printf("Problem with stored object %v: %v", name, err)
+					continue
+				}
+				c <- NewPointer(alg.ObjectID(hex), size, nil)
 			}
 		}
 	}
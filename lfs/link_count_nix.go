@@ -0,0 +1,21 @@
+// +build !windows
+
+package lfs
+
+import (
+	"os"
+	"syscall"
+)
+
+// linkCount returns the number of hard links to the file at path.
+func linkCount(path string) (uint64, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+
+	if sys, ok := stat.Sys().(*syscall.Stat_t); ok {
+		return uint64(sys.Nlink), nil
+	}
+	return 1, nil
+}
@@ -42,15 +42,27 @@ func shouldDeleteTempObject(path string) bool {
 
 	base := filepath.Base(path)
 	parts := strings.SplitN(base, "-", 2)
-	oid := parts[0]
-	if len(parts) < 2 || len(oid) != 64 {
+
+	// Temp objects are prefixed with the hex hash of the object, without
+	// the tag of its hash algorithm, so it may be of any algorithm with
+	// hashes of that length.
+	var oids []string
+	for _, a := range hashAlgorithms {
+		if a.validHex(parts[0]) {
+			oids = append(oids, a.ObjectID(parts[0]))
+		}
+	}
+
+	if len(parts) < 2 || len(oids) == 0 {
 		tracerx.Printf("Removing invalid tmp object file: %s", path)
 		return true
 	}
 
-	if FileExists(localMediaPathNoCreate(oid)) {
-		tracerx.Printf("Removing existing tmp object file: %s", path)
-		return true
+	for _, oid := range oids {
+		if localObjectExists(localMediaPathNoCreate(oid)) {
+			tracerx.Printf("Removing existing tmp object file: %s", path)
+			return true
+		}
 	}
 
 	if time.Since(info.ModTime()) > time.Hour {
@@ -0,0 +1,171 @@
+package lfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/rubyist/tracerx"
+)
+
+// httpStatsSummary is the JSON body sent to the metrics endpoint. It totals the
+// transfer stats of the command by transfer key, such as "lfs.api.batch" or
+// "lfs.data.download". See docs/api/http-v1-metrics.md.
+type httpStatsSummary struct {
+	Version             string                     `json:"version"`
+	Command             string                     `json:"command"`
+	ConcurrentTransfers int                        `json:"concurrent_transfers"`
+	Batch               bool                       `json:"batch"`
+	Time                int64                      `json:"time"`
+	Transfers           map[string]*httpStatsTotal `json:"transfers"`
+}
+
+type httpStatsTotal struct {
+	Count               int            `json:"count"`
+	RequestHeaderBytes  int64          `json:"request_header_bytes"`
+	RequestBodyBytes    int64          `json:"request_body_bytes"`
+	ResponseHeaderBytes int64          `json:"response_header_bytes"`
+	ResponseBodyBytes   int64          `json:"response_body_bytes"`
+	ResponseTimeMs      int64          `json:"response_time_ms"`
+	StatusCodes         map[string]int `json:"status_codes"`
+}
+
+// ReportHttpStats is intended to be called after all HTTP operations for the
+// command have finished. If metrics are enabled for the current LFS endpoint
+// with lfs.<url>.metrics, it POSTs a summary of the command's transfers to the
+// endpoint's metrics URL. Errors are traced, but never fail the command.
+func ReportHttpStats() {
+	transferBucketsLock.Lock()
+	logged := len(transferBuckets)
+	transferBucketsLock.Unlock()
+
+	// Stats are only logged if they're being collected, so this skips loading
+	// the config for commands that don't make HTTP requests.
+	if logged == 0 || !Config.MetricsEnabled() {
+		return
+	}
+
+	summary := summarizeHttpStats()
+	if len(summary.Transfers) == 0 {
+		return
+	}
+
+	if err := sendHttpStats(summary); err != nil {
+		tracerx.Printf("metrics: error sending metrics: %s", err)
+	}
+}
+
+func summarizeHttpStats() *httpStatsSummary {
+	summary := &httpStatsSummary{
+		Version:             Version,
+		Command:             metricsCommandName(),
+		ConcurrentTransfers: Config.ConcurrentTransfers(),
+		Batch:               Config.BatchTransfer(),
+		Time:                time.Now().Unix(),
+		Transfers:           make(map[string]*httpStatsTotal),
+	}
+
+	transferBucketsLock.Lock()
+	defer transferBucketsLock.Unlock()
+	transfersLock.Lock()
+	defer transfersLock.Unlock()
+
+	for key, responses := range transferBuckets {
+		total := &httpStatsTotal{StatusCodes: make(map[string]int)}
+		for _, response := range responses {
+			stats, ok := transfers[response]
+			if !ok {
+				continue
+			}
+
+			total.Count += 1
+			total.RequestHeaderBytes += int64(stats.requestStats.HeaderSize)
+			total.RequestBodyBytes += int64(stats.requestStats.BodySize)
+			total.ResponseHeaderBytes += int64(stats.responseStats.HeaderSize)
+			total.ResponseBodyBytes += int64(stats.responseStats.BodySize)
+			if !stats.responseStats.Stop.IsZero() {
+				total.ResponseTimeMs += int64(stats.responseStats.Stop.Sub(stats.responseStats.Start) / time.Millisecond)
+			}
+			total.StatusCodes[strconv.Itoa(response.StatusCode)] += 1
+		}
+
+		if total.Count > 0 {
+			summary.Transfers[key] = total
+		}
+	}
+
+	return summary
+}
+
+func sendHttpStats(summary *httpStatsSummary) error {
+	by, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+
+	req, err := newMetricsApiRequest()
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", mediaType)
+	req.Header.Set("Content-Length", strconv.Itoa(len(by)))
+	req.ContentLength = int64(len(by))
+	req.Body = &byteCloser{bytes.NewReader(by)}
+
+	tracerx.Printf("metrics: sending %d transfer keys", len(summary.Transfers))
+	res, err := doAPIRequest(req, Config.PrivateAccess())
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	return nil
+}
+
+// newMetricsApiRequest builds the POST request for "<lfs url>/metrics".
+func newMetricsApiRequest() (*http.Request, error) {
+	endpoint := Config.Endpoint()
+
+	res, err := sshAuthenticate(endpoint, "download", "")
+	if err != nil {
+		tracerx.Printf("ssh: metrics attempted with %s.  Error: %s",
+			endpoint.SshUserAndHost, err.Error(),
+		)
+	}
+
+	if len(res.Href) > 0 {
+		endpoint.Url = res.Href
+	}
+
+	u, err := url.Parse(endpoint.Url)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = path.Join(u.Path, "metrics")
+
+	req, err := newClientRequest("POST", u.String(), res.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", mediaType)
+	return req, nil
+}
+
+// metricsCommandName returns the name of the git-lfs command being run, such
+// as "fetch" or "pre-push".
+func metricsCommandName() string {
+	for _, arg := range os.Args[1:] {
+		if !strings.HasPrefix(arg, "-") {
+			return arg
+		}
+	}
+	return ""
+}
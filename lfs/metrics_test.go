@@ -0,0 +1,83 @@
+package lfs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/technoweenie/assert"
+)
+
+func TestMetricsEnabled(t *testing.T) {
+	config := &Configuration{
+		gitConfig: map[string]string{
+			"lfs.url": "https://example.com/repo.git/info/lfs",
+		},
+	}
+	assert.Equal(t, false, config.MetricsEnabled())
+
+	config.gitConfig["lfs.https://example.com/repo.git/info/lfs.metrics"] = "true"
+	assert.Equal(t, true, config.MetricsEnabled())
+
+	config.gitConfig["lfs.metrics"] = "false"
+	assert.Equal(t, false, config.MetricsEnabled())
+}
+
+func TestReportHttpStats(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var summary *httpStatsSummary
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			w.WriteHeader(405)
+			return
+		}
+
+		summary = &httpStatsSummary{}
+		if err := json.NewDecoder(r.Body).Decode(summary); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(200)
+	})
+
+	Config.SetConfig("lfs.url", server.URL)
+	Config.SetConfig("lfs."+server.URL+".metrics", "true")
+	defer Config.ResetConfig()
+
+	start := time.Now()
+	for i, status := range []int{200, 200, 404} {
+		res := &http.Response{StatusCode: status}
+		transfers[res] = &transfer{
+			requestStats:  &transferStats{HeaderSize: 10, BodySize: 100},
+			responseStats: &transferStats{HeaderSize: 20, BodySize: 200, Start: start, Stop: start.Add(time.Duration(i+1) * time.Second)},
+		}
+		transferBuckets["lfs.data.download"] = append(transferBuckets["lfs.data.download"], res)
+	}
+	defer func() {
+		transfers = make(map[*http.Response]*transfer)
+		transferBuckets = make(map[string][]*http.Response)
+	}()
+
+	ReportHttpStats()
+
+	if summary == nil {
+		t.Fatal("no metrics sent")
+	}
+
+	assert.Equal(t, Version, summary.Version)
+	assert.Equal(t, 1, len(summary.Transfers))
+
+	total := summary.Transfers["lfs.data.download"]
+	assert.Equal(t, 3, total.Count)
+	assert.Equal(t, int64(30), total.RequestHeaderBytes)
+	assert.Equal(t, int64(300), total.RequestBodyBytes)
+	assert.Equal(t, int64(60), total.ResponseHeaderBytes)
+	assert.Equal(t, int64(600), total.ResponseBodyBytes)
+	assert.Equal(t, int64(6000), total.ResponseTimeMs)
+	assert.Equal(t, 2, total.StatusCodes["200"])
+	assert.Equal(t, 1, total.StatusCodes["404"])
+}
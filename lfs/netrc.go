@@ -0,0 +1,181 @@
+package lfs
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/rubyist/tracerx"
+)
+
+// netrcMachine is a single "machine" (or "default") entry from a .netrc file.
+type netrcMachine struct {
+	Name     string // empty for the "default" entry
+	Login    string
+	Password string
+}
+
+// setRequestAuthFromNetrc sets the request's Authorization header from the
+// user's .netrc file if it has an entry for the given URL's host. NTLM
+// requests are skipped, since they need the full credentials from
+// 'git credential'.
+func setRequestAuthFromNetrc(req *http.Request, u *url.URL) bool {
+	if Config.NtlmAccess() {
+		return false
+	}
+
+	machine := findNetrcMachine(u)
+	if machine == nil {
+		return false
+	}
+
+	tracerx.Printf("netrc: using credentials for %s", u.Host)
+	setRequestAuth(req, machine.Login, machine.Password)
+	return true
+}
+
+// findNetrcMachine looks up the first .netrc file in the user's home directory
+// and returns the entry matching the URL's host, falling back to the "default"
+// entry. If the URL contains a username, the entry's login must match it.
+func findNetrcMachine(u *url.URL) *netrcMachine {
+	for _, path := range netrcPaths() {
+		file, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+
+		machines := parseNetrc(file)
+		file.Close()
+		return matchNetrcMachine(machines, u)
+	}
+
+	return nil
+}
+
+// netrcPaths returns the possible locations of the user's netrc file, in
+// order of preference. Like curl and Git's netrc credential helper, Windows
+// prefers "_netrc" in %HOME%, falling back to %USERPROFILE%.
+func netrcPaths() []string {
+	homes := []string{Config.Getenv("HOME")}
+	names := []string{".netrc", "_netrc"}
+	if IsWindows() {
+		homes = append(homes, Config.Getenv("USERPROFILE"))
+		names = []string{"_netrc", ".netrc"}
+	}
+
+	paths := make([]string, 0, len(homes)*len(names))
+	for _, home := range homes {
+		if len(home) == 0 {
+			continue
+		}
+
+		for _, name := range names {
+			paths = append(paths, filepath.Join(home, name))
+		}
+	}
+
+	return paths
+}
+
+func matchNetrcMachine(machines []*netrcMachine, u *url.URL) *netrcMachine {
+	hostname := u.Host
+	if host, _, err := net.SplitHostPort(u.Host); err == nil {
+		hostname = host
+	}
+
+	username := ""
+	if u.User != nil {
+		username = u.User.Username()
+	}
+
+	var def *netrcMachine
+	for _, m := range machines {
+		if len(username) > 0 && m.Login != username {
+			continue
+		}
+
+		if len(m.Name) == 0 {
+			if def == nil {
+				def = m
+			}
+			continue
+		}
+
+		if strings.EqualFold(m.Name, u.Host) || strings.EqualFold(m.Name, hostname) {
+			return m
+		}
+	}
+
+	return def
+}
+
+// parseNetrc reads the machine and default entries from a netrc file. Tokens
+// may be split across lines, so a keyword's value can be on the line after it.
+// Macro definitions are skipped, and lines beginning with '#' are treated as
+// comments.
+func parseNetrc(r io.Reader) []*netrcMachine {
+	var machines []*netrcMachine
+	var current *netrcMachine
+	key := "" // keyword waiting for its value
+	inMacro := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if inMacro {
+			// a macro definition ends at the first empty line
+			inMacro = len(strings.TrimSpace(line)) > 0
+			continue
+		}
+
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		tokens := strings.Fields(line)
+		for i := 0; i < len(tokens); i++ {
+			token := tokens[i]
+			if len(key) > 0 {
+				switch key {
+				case "machine":
+					current.Name = token
+				case "login":
+					current.Login = token
+				case "password":
+					current.Password = token
+				}
+				key = ""
+				continue
+			}
+
+			switch token {
+			case "machine":
+				current = &netrcMachine{}
+				machines = append(machines, current)
+				key = token
+			case "default":
+				current = &netrcMachine{}
+				machines = append(machines, current)
+			case "login", "password", "account":
+				if current != nil {
+					key = token
+				}
+			case "macdef":
+				inMacro = true
+				i = len(tokens)
+			}
+		}
+	}
+
+	// a machine without a name isn't a default entry
+	if key == "machine" {
+		machines = machines[:len(machines)-1]
+	}
+
+	return machines
+}
@@ -0,0 +1,42 @@
+package lfs
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/technoweenie/assert"
+)
+
+func TestParseNetrcAcrossLines(t *testing.T) {
+	netrc := "# comment\n" +
+		"machine\n" +
+		"  netrc-server.com\n" +
+		"  login netrcuser\n" +
+		"  password\n" +
+		"    netrcpass\n" +
+		"macdef init\n" +
+		"machine inside-macro.com login macrouser\n" +
+		"\n" +
+		"machine other-server.com login otheruser password otherpass\n"
+
+	machines := parseNetrc(strings.NewReader(netrc))
+	assert.Equal(t, 2, len(machines))
+	assert.Equal(t, "netrc-server.com", machines[0].Name)
+	assert.Equal(t, "netrcuser", machines[0].Login)
+	assert.Equal(t, "netrcpass", machines[0].Password)
+	assert.Equal(t, "other-server.com", machines[1].Name)
+	assert.Equal(t, "otheruser", machines[1].Login)
+	assert.Equal(t, "otherpass", machines[1].Password)
+
+	u, _ := url.Parse("https://unknown-server.com/foo")
+	assert.Equal(t, (*netrcMachine)(nil), matchNetrcMachine(machines, u))
+}
+
+func TestParseNetrcMachineWithoutName(t *testing.T) {
+	machines := parseNetrc(strings.NewReader("machine netrc-server.com login netrcuser\nmachine\n"))
+	assert.Equal(t, 1, len(machines))
+
+	u, _ := url.Parse("https://unknown-server.com/foo")
+	assert.Equal(t, (*netrcMachine)(nil), matchNetrcMachine(machines, u))
+}
@@ -20,7 +20,7 @@ var (
 		"https://git-lfs.github.com/spec/v1", // public launch
 	}
 	latest      = "https://git-lfs.github.com/spec/v1"
-	oidType     = "sha256"
+	latestV2    = "https://git-lfs.github.com/spec/v2" // allows any registered hash algorithm
 	oidRE       = regexp.MustCompile(`\A[[:alnum:]]{64}`)
 	matcherRE   = regexp.MustCompile("git-media|hawser|git-lfs")
 	extRE       = regexp.MustCompile(`\Aext-\d{1}-\w+`)
@@ -49,12 +49,40 @@ func (p ByPriority) Len() int           { return len(p) }
 func (p ByPriority) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
 func (p ByPriority) Less(i, j int) bool { return p[i].Priority < p[j].Priority }
 
+// NewPointer returns a pointer to the object with the given ID, which is
+// tagged with its hash algorithm unless it is SHA-256.
 func NewPointer(oid string, size int64, exts []*PointerExtension) *Pointer {
-	return &Pointer{latest, oid, size, oidType, exts}
+	p := &Pointer{latest, oid, size, oidTypeOf(oid), exts}
+	p.Version = p.specVersion()
+	return p
 }
 
 func NewPointerExtension(name string, priority int, oid string) *PointerExtension {
-	return &PointerExtension{name, priority, oid, oidType}
+	return &PointerExtension{name, priority, oid, oidTypeOf(oid)}
+}
+
+func oidTypeOf(oid string) string {
+	if name := oidAlgorithmName(oid); len(name) > 0 {
+		return name
+	}
+	return defaultHashAlgorithm.Name
+}
+
+// specVersion returns the version of the pointer spec the pointer is written
+// with. New pointers that only use SHA-256 are written as v1 pointers, so that
+// their blobs are the same as before other hash algorithms were supported, and
+// older clients can read them. A decoded v2 pointer stays v2, so that
+// re-encoding it doesn't change its blob.
+func (p *Pointer) specVersion() string {
+	if p.Version == latestV2 || p.OidType != defaultHashAlgorithm.Name {
+		return latestV2
+	}
+	for _, ext := range p.Extensions {
+		if ext.OidType != defaultHashAlgorithm.Name {
+			return latestV2
+		}
+	}
+	return latest
 }
 
 func (p *Pointer) Smudge(writer io.Writer, workingfile string, download bool, cb CopyCallback) error {
@@ -67,11 +95,11 @@ func (p *Pointer) Encode(writer io.Writer) (int, error) {
 
 func (p *Pointer) Encoded() string {
 	var buffer bytes.Buffer
-	buffer.WriteString(fmt.Sprintf("version %s\n", latest))
+	buffer.WriteString(fmt.Sprintf("version %s\n", p.specVersion()))
 	for _, ext := range p.Extensions {
-		buffer.WriteString(fmt.Sprintf("ext-%d-%s %s:%s\n", ext.Priority, ext.Name, ext.OidType, ext.Oid))
+		buffer.WriteString(fmt.Sprintf("ext-%d-%s %s:%s\n", ext.Priority, ext.Name, ext.OidType, objectIDHex(ext.Oid)))
 	}
-	buffer.WriteString(fmt.Sprintf("oid %s:%s\n", p.OidType, p.Oid))
+	buffer.WriteString(fmt.Sprintf("oid %s:%s\n", p.OidType, objectIDHex(p.Oid)))
 	buffer.WriteString(fmt.Sprintf("size %d\n", p.Size))
 	return buffer.String()
 }
@@ -125,6 +153,10 @@ func verifyVersion(version string) error {
 		}
 	}
 
+	if version == latestV2 {
+		return nil
+	}
+
 	return errors.New("Invalid version: " + version)
 }
 
@@ -175,7 +207,14 @@ func decodeKV(data []byte) (*Pointer, error) {
 		sort.Sort(ByPriority(extensions))
 	}
 
-	return NewPointer(oid, size, extensions), nil
+	p := NewPointer(oid, size, extensions)
+	if kvps["version"] == latestV2 {
+		p.Version = latestV2
+	} else if p.Version != latest {
+		return nil, errors.New("Invalid Oid type: only sha256 OIDs are allowed in v1 pointers")
+	}
+
+	return p, nil
 }
 
 func parseOid(value string) (string, error) {
@@ -183,14 +222,15 @@ func parseOid(value string) (string, error) {
 	if len(parts) != 2 {
 		return "", errors.New("Invalid Oid value: " + value)
 	}
-	if parts[0] != oidType {
+	alg := LookupHashAlgorithm(parts[0])
+	if alg == nil {
 		return "", errors.New("Invalid Oid type: " + parts[0])
 	}
 	oid := parts[1]
-	if !oidRE.Match([]byte(oid)) {
+	if !alg.validHex(oid) {
 		return "", errors.New("Invalid Oid: " + oid)
 	}
-	return oid, nil
+	return alg.ObjectID(oid), nil
 }
 
 func parsePointerExtension(key string, value string) (*PointerExtension, error) {
@@ -0,0 +1,213 @@
+package lfs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var lowerHexRE = regexp.MustCompile(`\A[0-9a-f]+\z`)
+
+// A PointerProblem is one way in which a pointer blob differs from the
+// canonical encoding of its pointer, which is what Git LFS writes.
+type PointerProblem struct {
+	Line    int // Line number, or 0 if the problem is with the whole blob
+	Message string
+}
+
+func (p *PointerProblem) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("line %d: %s", p.Line, p.Message)
+	}
+	return p.Message
+}
+
+// CheckPointer decodes the pointer blob in data, and returns all the ways in
+// which it isn't the canonical encoding of the pointer. Git LFS reads some
+// pointers that aren't canonical, but different encodings of the same pointer
+// are different Git blobs, so tools that rewrite pointers change their blob
+// OIDs. The error is set if Git LFS can't read the pointer at all, in which
+// case the problems explain why where they can.
+func CheckPointer(data []byte) (*Pointer, []*PointerProblem, error) {
+	var problems []*PointerProblem
+	add := func(line int, format string, args ...interface{}) {
+		problems = append(problems, &PointerProblem{Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	p, err := DecodePointer(bytes.NewReader(data))
+
+	if len(data) >= blobSizeCutoff {
+		add(0, "pointer is %d bytes, pointers must be smaller than %d bytes", len(data), blobSizeCutoff)
+	}
+
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		add(0, "missing newline at the end")
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	var prevKey string
+	for i, line := range lines {
+		n := i + 1
+
+		if strings.HasSuffix(line, "\r") {
+			add(n, "line ends with a carriage return")
+			line = strings.TrimSuffix(line, "\r")
+		}
+
+		if len(strings.TrimSpace(line)) == 0 {
+			add(n, "empty line")
+			continue
+		}
+
+		if strings.TrimSpace(line) != line {
+			add(n, "leading or trailing whitespace")
+			line = strings.TrimSpace(line)
+		}
+
+		if prevKey == "size" {
+			add(n, "trailing data after the size line")
+			break
+		}
+
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) != 2 {
+			add(n, "not a key and value separated by a space")
+			continue
+		}
+
+		key, value := parts[0], parts[1]
+		if strings.TrimSpace(value) != value {
+			add(n, "more than one space between key and value")
+			value = strings.TrimSpace(value)
+		}
+
+		switch {
+		case key == "version":
+			if i > 0 {
+				add(n, "version is not the first key")
+			}
+		case i == 0:
+			add(n, "first key is %q, not %q", key, "version")
+		case key == prevKey:
+			add(n, "duplicate key %q", key)
+		case key < prevKey:
+			add(n, "key %q is not in order, it comes before %q", key, prevKey)
+		}
+
+		switch {
+		case key == "version":
+			if p != nil && value != p.Version {
+				add(n, "version should be %q, not %q", p.Version, value)
+			}
+		case key == "oid" || extRE.MatchString(key):
+			if j := strings.Index(value, ":"); j >= 0 && !lowerHexRE.MatchString(value[j+1:]) {
+				add(n, "%s %q is not lowercase hex", key, value[j+1:])
+			}
+		case key == "size":
+			if size, err := strconv.ParseInt(value, 10, 64); err == nil && strconv.FormatInt(size, 10) != value {
+				add(n, "size should be %q, not %q", strconv.FormatInt(size, 10), value)
+			}
+		default:
+			add(n, "unknown key %q", key)
+		}
+
+		if key != "version" {
+			prevKey = key
+		}
+	}
+
+	if err == nil && len(problems) == 0 && string(data) != p.Encoded() {
+		add(0, "differs from the canonical pointer")
+	}
+
+	return p, problems, err
+}
+
+// A NonCanonicalPointer is a blob that looks like a pointer, but isn't the
+// canonical encoding of one.
+type NonCanonicalPointer struct {
+	Sha1     string
+	Name     string
+	Problems []*PointerProblem
+	Err      error // Why Git LFS can't read the pointer, if it can't
+}
+
+// ScanNonCanonicalPointers checks every blob in the history of ref that looks
+// like a pointer, because it starts with the version line of a pointer, and
+// returns the ones that aren't canonical. Blobs too large to be pointers are
+// not checked.
+func ScanNonCanonicalPointers(ref string) ([]*NonCanonicalPointer, error) {
+	opt := NewScanRefsOptions()
+	revs, err := revListShas(ref, "", opt)
+	if err != nil {
+		return nil, err
+	}
+
+	smallShas, err := catFileBatchCheck(revs)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd, err := startCommand("git", "cat-file", "--batch")
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for r := range smallShas {
+			cmd.Stdin.Write([]byte(r + "\n"))
+		}
+		cmd.Stdin.Close()
+	}()
+
+	var pointers []*NonCanonicalPointer
+	for {
+		l, err := cmd.Stdout.ReadBytes('\n')
+		if err != nil {
+			break
+		}
+
+		// Line is formatted:
+		// <sha1> <type> <size>
+		fields := bytes.Fields(l)
+		s, _ := strconv.Atoi(string(fields[2]))
+
+		data := make([]byte, s)
+		if _, err := io.ReadFull(cmd.Stdout, data); err != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+			return nil, err
+		}
+
+		if _, err := cmd.Stdout.ReadBytes('\n'); err != nil { // Extra \n inserted by cat-file
+			cmd.Process.Kill()
+			cmd.Wait()
+			return nil, err
+		}
+
+		if !looksLikePointer(data) {
+			continue
+		}
+
+		if _, problems, err := CheckPointer(data); len(problems) > 0 || err != nil {
+			sha1 := string(fields[0])
+			name, _ := opt.GetName(sha1)
+			pointers = append(pointers, &NonCanonicalPointer{Sha1: sha1, Name: name, Problems: problems, Err: err})
+		}
+	}
+
+	return pointers, cmd.Wait()
+}
+
+// looksLikePointer returns whether data starts with the version line of a
+// pointer, from any version of the spec.
+func looksLikePointer(data []byte) bool {
+	line := bytes.TrimSpace(data)
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	return bytes.HasPrefix(line, []byte("version ")) && matcherRE.Match(line)
+}
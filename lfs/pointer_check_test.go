@@ -0,0 +1,125 @@
+package lfs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/technoweenie/assert"
+)
+
+const checkOid = "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393"
+
+func TestCheckPointerCanonical(t *testing.T) {
+	data := "version https://git-lfs.github.com/spec/v1\n" +
+		"oid sha256:" + checkOid + "\n" +
+		"size 12345\n"
+
+	p, problems, err := CheckPointer([]byte(data))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(problems))
+	assert.Equal(t, checkOid, p.Oid)
+}
+
+func TestCheckPointerProblems(t *testing.T) {
+	version := "version https://git-lfs.github.com/spec/v1\n"
+	oid := "oid sha256:" + checkOid + "\n"
+	upperOid := strings.ToUpper(checkOid)
+
+	examples := map[string]struct {
+		data     string
+		problems []string
+	}{
+		"crlf": {
+			strings.Replace(version+oid+"size 12345\n", "\n", "\r\n", -1),
+			[]string{
+				"line 1: line ends with a carriage return",
+				"line 2: line ends with a carriage return",
+				"line 3: line ends with a carriage return",
+			},
+		},
+		"no newline": {
+			version + oid + "size 12345",
+			[]string{"missing newline at the end"},
+		},
+		"trailing data": {
+			version + "size 12345\n" + oid,
+			[]string{"line 3: trailing data after the size line"},
+		},
+		"ext order": {
+			version + oid + "ext-0-foo sha256:" + checkOid + "\nsize 12345\n",
+			[]string{`line 3: key "ext-0-foo" is not in order, it comes before "oid"`},
+		},
+		"unknown key": {
+			version + "foo bar\n" + oid + "size 12345\n",
+			[]string{`line 2: unknown key "foo"`},
+		},
+		"no value": {
+			version + oid + "size\n",
+			[]string{"line 3: not a key and value separated by a space"},
+		},
+		"uppercase": {
+			version + "oid sha256:" + upperOid + "\nsize 12345\n",
+			[]string{`line 2: oid "` + upperOid + `" is not lowercase hex`},
+		},
+		"size sign": {
+			version + oid + "size +12345\n",
+			[]string{`line 3: size should be "12345", not "+12345"`},
+		},
+		"size zeros": {
+			version + oid + "size 012345\n",
+			[]string{`line 3: size should be "12345", not "012345"`},
+		},
+		"spaces": {
+			version + "oid  sha256:" + checkOid + " \nsize 12345\n",
+			[]string{
+				"line 2: leading or trailing whitespace",
+				"line 2: more than one space between key and value",
+			},
+		},
+		"old version": {
+			"version https://hawser.github.com/spec/v1\n" + oid + "size 12345\n",
+			[]string{`line 1: version should be "https://git-lfs.github.com/spec/v1", not "https://hawser.github.com/spec/v1"`},
+		},
+		"v2 with sha256": {
+			"version https://git-lfs.github.com/spec/v2\n" + oid + "size 12345\n",
+			[]string{},
+		},
+	}
+
+	// Some of these aren't valid pointers either, but the problems are the
+	// same either way.
+	for name, example := range examples {
+		_, problems, _ := CheckPointer([]byte(example.data))
+		messages := make([]string, len(problems))
+		for i, problem := range problems {
+			messages[i] = problem.String()
+		}
+		assert.Equalf(t, strings.Join(example.problems, "\n"), strings.Join(messages, "\n"), "%s", name)
+	}
+}
+
+func TestCheckPointerTooLarge(t *testing.T) {
+	data := "version https://git-lfs.github.com/spec/v1\n" +
+		"oid sha256:" + checkOid + "\n" +
+		"size 12345\n" +
+		strings.Repeat("\n", blobSizeCutoff)
+
+	_, problems, _ := CheckPointer([]byte(data))
+	assert.Equal(t, true, len(problems) > 0)
+	assert.Equal(t, "pointer is 1154 bytes, pointers must be smaller than 1024 bytes", problems[0].String())
+}
+
+func TestCheckPointerInvalid(t *testing.T) {
+	_, problems, err := CheckPointer([]byte("not a pointer\n"))
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, 2, len(problems))
+	assert.Equal(t, `line 1: first key is "not", not "version"`, problems[0].String())
+	assert.Equal(t, `line 1: unknown key "not"`, problems[1].String())
+}
+
+func TestLooksLikePointer(t *testing.T) {
+	assert.Equal(t, true, looksLikePointer([]byte("version https://git-lfs.github.com/spec/v1\nsize 1\n")))
+	assert.Equal(t, true, looksLikePointer([]byte("\r\nversion https://hawser.github.com/spec/v1\r\n")))
+	assert.Equal(t, false, looksLikePointer([]byte("version 1.0\ngit-lfs\n")))
+	assert.Equal(t, false, looksLikePointer([]byte("not a pointer")))
+}
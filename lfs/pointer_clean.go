@@ -2,7 +2,6 @@ package lfs
 
 import (
 	"bytes"
-	"crypto/sha256"
 	"encoding/hex"
 	"io"
 	"os"
@@ -13,8 +12,16 @@ type cleanedAsset struct {
 	*Pointer
 }
 
+// PointerClean stores the content of reader in a temp file, and returns it with
+// its pointer. The OID is computed with the hash algorithm set by
+// lfs.hashalgorithm, and the content is run through the extensions that apply
+// to fileName.
 func PointerClean(reader io.Reader, fileName string, fileSize int64, cb CopyCallback) (*cleanedAsset, error) {
-	extensions, err := SortExtensions(Config.Extensions())
+	return pointerClean(reader, fileName, fileSize, LookupHashAlgorithm(Config.HashAlgorithm()), cb)
+}
+
+func pointerClean(reader io.Reader, fileName string, fileSize int64, alg *HashAlgorithm, cb CopyCallback) (*cleanedAsset, error) {
+	extensions, err := ExtensionsForPath(fileName)
 	if err != nil {
 		return nil, err
 	}
@@ -24,7 +31,7 @@ func PointerClean(reader io.Reader, fileName string, fileSize int64, cb CopyCall
 	var tmp *os.File
 	var exts []*PointerExtension
 	if len(extensions) > 0 {
-		request := &pipeRequest{"clean", reader, fileName, extensions}
+		request := &pipeRequest{"clean", reader, fileName, extensions, alg}
 
 		var response pipeResponse
 		if response, err = pipeExtensions(request); err != nil {
@@ -46,7 +53,7 @@ func PointerClean(reader io.Reader, fileName string, fileSize int64, cb CopyCall
 			}
 		}
 	} else {
-		oid, size, tmp, err = copyToTemp(reader, fileSize, cb)
+		oid, size, tmp, err = copyToTemp(reader, fileSize, alg, cb)
 		if err != nil {
 			return nil, err
 		}
@@ -56,7 +63,7 @@ func PointerClean(reader io.Reader, fileName string, fileSize int64, cb CopyCall
 	return &cleanedAsset{tmp.Name(), pointer}, err
 }
 
-func copyToTemp(reader io.Reader, fileSize int64, cb CopyCallback) (oid string, size int64, tmp *os.File, err error) {
+func copyToTemp(reader io.Reader, fileSize int64, alg *HashAlgorithm, cb CopyCallback) (oid string, size int64, tmp *os.File, err error) {
 	tmp, err = TempFile("")
 	if err != nil {
 		return
@@ -64,7 +71,7 @@ func copyToTemp(reader io.Reader, fileSize int64, cb CopyCallback) (oid string,
 
 	defer tmp.Close()
 
-	oidHash := sha256.New()
+	oidHash := alg.New()
 	writer := io.MultiWriter(oidHash, tmp)
 
 	if fileSize == 0 {
@@ -84,7 +91,7 @@ func copyToTemp(reader io.Reader, fileSize int64, cb CopyCallback) (oid string,
 		return
 	}
 
-	oid = hex.EncodeToString(oidHash.Sum(nil))
+	oid = alg.ObjectID(hex.EncodeToString(oidHash.Sum(nil)))
 	return
 }
 
@@ -1,10 +1,7 @@
 package lfs
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
 	"fmt"
-	"hash"
 	"io"
 	"io/ioutil"
 	"os"
@@ -14,13 +11,33 @@ import (
 	"github.com/github/git-lfs/vendor/_nuts/github.com/rubyist/tracerx"
 )
 
+// PointerSmudgeToFile writes the content of the pointer to filename. The
+// content is copied from the stored object, or linked to it as set by
+// lfs.checkout.linkmode.
 func PointerSmudgeToFile(filename string, ptr *Pointer, download bool, cb CopyCallback) error {
 	os.MkdirAll(filepath.Dir(filename), 0755)
+
+	if err := unlinkWorkingFile(filename, ptr); err != nil {
+		return fmt.Errorf("Could not remove working directory file: %v", err)
+	}
+
+	linkMode := Config.CheckoutLinkMode()
+	if linkMode == linkModeHardlink && hardlinkMediaFile(filename, ptr) {
+		recordObjectAccess(ptr.Oid)
+		return nil
+	}
+
 	file, err := os.Create(filename)
 	if err != nil {
 		return fmt.Errorf("Could not create working directory file: %v", err)
 	}
 	defer file.Close()
+
+	if linkMode == linkModeReflink && reflinkMediaFile(file, ptr) {
+		recordObjectAccess(ptr.Oid)
+		return nil
+	}
+
 	if err := PointerSmudge(file, ptr, filename, download, cb); err != nil {
 		if IsDownloadDeclinedError(err) {
 			// write placeholder data instead
@@ -40,17 +57,22 @@ func PointerSmudge(writer io.Writer, ptr *Pointer, workingfile string, download
 		return err
 	}
 
-	stat, statErr := os.Stat(mediafile)
-	if statErr == nil && stat != nil {
-		fileSize := stat.Size()
+	fileSize, statErr := StatLocalObject(mediafile)
+	if statErr == nil {
 		if fileSize == 0 || fileSize != ptr.Size {
+			// Alternate stores are read-only, so only the local
+			// store's copy is removed, and the object is
+			// downloaded to the local store.
+			if mediafile, err = LocalStoreMediaPath(ptr.Oid); err != nil {
+				return err
+			}
 			tracerx.Printf("Removing %s, size %d is invalid", mediafile, fileSize)
-			os.RemoveAll(mediafile)
-			stat = nil
+			RemoveLocalObject(mediafile)
+			statErr = os.ErrNotExist
 		}
 	}
 
-	if statErr != nil || stat == nil {
+	if statErr != nil {
 		if download {
 			err = downloadFile(writer, ptr, workingfile, mediafile, cb)
 		} else {
@@ -75,17 +97,22 @@ func PointerSmudgeObject(ptr *Pointer, obj *ObjectResource, cb CopyCallback) err
 		return err
 	}
 
-	stat, statErr := os.Stat(mediafile)
-	if statErr == nil && stat != nil {
-		fileSize := stat.Size()
+	fileSize, statErr := StatLocalObject(mediafile)
+	if statErr == nil {
 		if fileSize == 0 || fileSize != obj.Size {
+			// Alternate stores are read-only, so only the local
+			// store's copy is removed, and the object is
+			// downloaded to the local store.
+			if mediafile, err = LocalStoreMediaPath(obj.Oid); err != nil {
+				return err
+			}
 			tracerx.Printf("Removing %s, size %d is invalid", mediafile, fileSize)
-			os.RemoveAll(mediafile)
-			stat = nil
+			RemoveLocalObject(mediafile)
+			statErr = os.ErrNotExist
 		}
 	}
 
-	if statErr != nil || stat == nil {
+	if statErr != nil {
 		err := downloadObject(ptr, obj, mediafile, cb)
 
 		if err != nil {
@@ -97,6 +124,10 @@ func PointerSmudgeObject(ptr *Pointer, obj *ObjectResource, cb CopyCallback) err
 }
 
 func downloadObject(ptr *Pointer, obj *ObjectResource, mediafile string, cb CopyCallback) error {
+	if len(obj.Chunks) > 0 && Config.Chunking() {
+		return downloadChunkedObject(obj, mediafile, cb)
+	}
+
 	reader, size, err := DownloadObject(obj)
 	if reader != nil {
 		defer reader.Close()
@@ -119,13 +150,13 @@ func downloadObject(ptr *Pointer, obj *ObjectResource, mediafile string, cb Copy
 
 func downloadFile(writer io.Writer, ptr *Pointer, workingfile, mediafile string, cb CopyCallback) error {
 	fmt.Fprintf(os.Stderr, "Downloading %s (%s)\n", workingfile, pb.FormatBytes(ptr.Size))
-	reader, size, err := Download(filepath.Base(mediafile), ptr.Size)
+	reader, size, err := downloadFromRemotes(ptr.Oid, ptr.Size)
 	if reader != nil {
 		defer reader.Close()
 	}
 
 	if err != nil {
-		return Errorf(err, "Error downloading %s: %s", filepath.Base(mediafile), err)
+		return Errorf(err, "Error downloading %s: %s", ptr.Oid, err)
 	}
 
 	if ptr.Size == 0 {
@@ -140,21 +171,24 @@ func downloadFile(writer io.Writer, ptr *Pointer, workingfile, mediafile string,
 }
 
 // Writes the content of reader to filename atomically by writing to a temp file
-// first, and confirming the content SHA-256 is valid. This is basically a copy
-// of atomic.WriteFile() at:
+// first, and confirming the content size and OID are valid with a
+// verifyingWriter. This is basically a copy of atomic.WriteFile() at:
 //
 //   https://github.com/natefinch/atomic/blob/a62ce929ffcc871a51e98c6eba7b20321e3ed62d/atomic.go#L12-L17
 //
-// filename - Absolute path to a file to write, with the filename a 64 character
-//            SHA-256 hex signature.
+// The verified content is stored with StoreLocalObject, so it is chunked or
+// compressed as set by lfs.chunking and lfs.compression.
+//
+// filename - Absolute path to a file to write, in a store laid out by
+//            LocalMediaPath, so that the object's OID is known from it.
 // reader   - Any io.Reader
-// size     - Expected byte size of the content. Used for the progress bar in
-//            the optional CopyCallback.
+// size     - Expected byte size of the content, or -1 if unknown. Also used for
+//            the progress bar in the optional CopyCallback.
 // cb       - Optional CopyCallback object for providing download progress to
 //            external Git LFS tools.
-func bufferDownloadedFile(filename string, reader io.Reader, size int64, cb CopyCallback) error {
-	oid := filepath.Base(filename)
-	f, err := ioutil.TempFile(LocalObjectTempDir, oid+"-")
+func bufferDownloadedFile(filename string, reader io.Reader, size int64, cb CopyCallback) (err error) {
+	oid := objectIDFromPath(filename)
+	f, err := ioutil.TempFile(LocalObjectTempDir, filepath.Base(filename)+"-")
 	if err != nil {
 		return fmt.Errorf("cannot create temp file: %v", err)
 	}
@@ -166,22 +200,24 @@ func bufferDownloadedFile(filename string, reader io.Reader, size int64, cb Copy
 		}
 	}()
 
-	hasher := newHashingReader(reader)
+	verifier := newVerifyingWriter(f, oid, size)
 
 	// ensure we always close f. Note that this does not conflict with  the
 	// close below, as close is idempotent.
 	defer f.Close()
 	name := f.Name()
-	written, err := CopyWithCallback(f, hasher, size, cb)
-	if err != nil {
+	if _, err = CopyWithCallback(verifier, reader, size, cb); err != nil {
+		if IsIntegrityError(err) {
+			return err
+		}
 		return fmt.Errorf("cannot write data to tempfile %q: %v", name, err)
 	}
-	if err := f.Close(); err != nil {
+	if err = f.Close(); err != nil {
 		return fmt.Errorf("can't close tempfile %q: %v", name, err)
 	}
 
-	if actual := hasher.Hash(); actual != oid {
-		return fmt.Errorf("Expected OID %s, got %s after %d bytes written", oid, actual, written)
+	if err = verifier.Verify(); err != nil {
+		return err
 	}
 
 	// get the file mode from the original file and use that for the replacement
@@ -189,31 +225,32 @@ func bufferDownloadedFile(filename string, reader io.Reader, size int64, cb Copy
 	info, err := os.Stat(filename)
 	if os.IsNotExist(err) {
 		// no original file
+		err = nil
 	} else if err != nil {
 		return err
 	} else {
-		if err := os.Chmod(name, info.Mode()); err != nil {
+		if err = os.Chmod(name, info.Mode()); err != nil {
 			return fmt.Errorf("can't set filemode on tempfile %q: %v", name, err)
 		}
 	}
 
-	if err := os.Rename(name, filename); err != nil {
+	if err = StoreLocalObject(name, filename); err != nil {
 		return fmt.Errorf("cannot replace %q with tempfile %q: %v", filename, name, err)
 	}
 	return nil
 }
 
 func readLocalFile(writer io.Writer, ptr *Pointer, mediafile string, workingfile string, cb CopyCallback) error {
-	reader, err := os.Open(mediafile)
+	reader, size, err := OpenLocalObject(mediafile)
 	if err != nil {
 		return Errorf(err, "Error opening media file.")
 	}
 	defer reader.Close()
 
+	recordObjectAccess(ptr.Oid)
+
 	if ptr.Size == 0 {
-		if stat, _ := os.Stat(mediafile); stat != nil {
-			ptr.Size = stat.Size()
-		}
+		ptr.Size = size
 	}
 
 	if len(ptr.Extensions) > 0 {
@@ -240,7 +277,7 @@ func readLocalFile(writer io.Writer, ptr *Pointer, mediafile string, workingfile
 			extsR = append(extsR, ext)
 		}
 
-		request := &pipeRequest{"smudge", reader, workingfile, extsR}
+		request := &pipeRequest{"smudge", reader, workingfile, extsR, ObjectHashAlgorithm(ptr.Oid)}
 
 		response, err := pipeExtensions(request)
 		if err != nil {
@@ -272,11 +309,12 @@ func readLocalFile(writer io.Writer, ptr *Pointer, mediafile string, workingfile
 		}
 
 		// setup reader
-		reader, err = os.Open(response.file.Name())
+		smudged, err := os.Open(response.file.Name())
 		if err != nil {
 			return Errorf(err, "Error opening smudged file: %s", err)
 		}
-		defer reader.Close()
+		defer smudged.Close()
+		reader = smudged
 	}
 
 	_, err = CopyWithCallback(writer, reader, ptr.Size, cb)
@@ -286,28 +324,3 @@ func readLocalFile(writer io.Writer, ptr *Pointer, mediafile string, workingfile
 
 	return nil
 }
-
-type hashingReader struct {
-	reader io.Reader
-	hasher hash.Hash
-}
-
-func newHashingReader(r io.Reader) *hashingReader {
-	return &hashingReader{r, sha256.New()}
-}
-
-func (r *hashingReader) Hash() string {
-	return hex.EncodeToString(r.hasher.Sum(nil))
-}
-
-func (r *hashingReader) Read(b []byte) (int, error) {
-	w, err := r.reader.Read(b)
-	if err == nil || err == io.EOF {
-		_, e := r.hasher.Write(b[0:w])
-		if e != nil && err == nil {
-			return w, e
-		}
-	}
-
-	return w, err
-}
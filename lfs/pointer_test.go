@@ -279,3 +279,90 @@ size 12345`,
 func assertEqualWithExample(t *testing.T, example string, expected, actual interface{}) {
 	assert.Equalf(t, expected, actual, "Example:\n%s", strings.TrimSpace(example))
 }
+
+func TestEncodeV2(t *testing.T) {
+	var buf bytes.Buffer
+	exts := []*PointerExtension{
+		NewPointerExtension("foo", 0, "sha512:foo_oid"),
+	}
+	pointer := NewPointer("sha512:main_oid", 12345, exts)
+	assert.Equal(t, latestV2, pointer.Version)
+	assert.Equal(t, "sha512", pointer.OidType)
+	_, err := EncodePointer(&buf, pointer)
+	assert.Equal(t, nil, err)
+
+	bufReader := bufio.NewReader(&buf)
+	assertLine(t, bufReader, "version https://git-lfs.github.com/spec/v2\n")
+	assertLine(t, bufReader, "ext-0-foo sha512:foo_oid\n")
+	assertLine(t, bufReader, "oid sha512:main_oid\n")
+	assertLine(t, bufReader, "size 12345\n")
+
+	line, err := bufReader.ReadString('\n')
+	if err == nil {
+		t.Fatalf("More to read: %s", line)
+	}
+	assert.Equal(t, "EOF", err.Error())
+}
+
+func TestDecodeV2(t *testing.T) {
+	ex := `version https://git-lfs.github.com/spec/v2
+ext-0-foo sha256:ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff
+oid sha512:ee26b0dd4af7e749aa1a8ee3c10ae9923f618980772e473f8819a5d4940e0db27ac185f8a0e1d5f84f88bc887fd67b143732c304cc5fa9ad8e6f57f50028a8ff
+size 12345`
+
+	p, err := DecodePointer(bytes.NewBufferString(ex))
+	assertEqualWithExample(t, ex, nil, err)
+	assertEqualWithExample(t, ex, latestV2, p.Version)
+	assertEqualWithExample(t, ex, "sha512:ee26b0dd4af7e749aa1a8ee3c10ae9923f618980772e473f8819a5d4940e0db27ac185f8a0e1d5f84f88bc887fd67b143732c304cc5fa9ad8e6f57f50028a8ff", p.Oid)
+	assertEqualWithExample(t, ex, "sha512", p.OidType)
+	assertEqualWithExample(t, ex, int64(12345), p.Size)
+	assertEqualWithExample(t, ex, "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", p.Extensions[0].Oid)
+	assertEqualWithExample(t, ex, "sha256", p.Extensions[0].OidType)
+	assertEqualWithExample(t, ex, ex+"\n", p.Encoded())
+
+	// v2 pointers that only use SHA-256 stay v2
+	ex = `version https://git-lfs.github.com/spec/v2
+oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393
+size 12345`
+
+	p, err = DecodePointer(bytes.NewBufferString(ex))
+	assertEqualWithExample(t, ex, nil, err)
+	assertEqualWithExample(t, ex, latestV2, p.Version)
+	assertEqualWithExample(t, ex, "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393", p.Oid)
+	assertEqualWithExample(t, ex, ex+"\n", p.Encoded())
+
+	// while new SHA-256 pointers are written as v1 pointers
+	assertEqualWithExample(t, ex, latest, NewPointer(p.Oid, p.Size, nil).Version)
+}
+
+func TestDecodeInvalidV2(t *testing.T) {
+	examples := []string{
+		// sha512 in a v1 pointer
+		`version https://git-lfs.github.com/spec/v1
+oid sha512:ee26b0dd4af7e749aa1a8ee3c10ae9923f618980772e473f8819a5d4940e0db27ac185f8a0e1d5f84f88bc887fd67b143732c304cc5fa9ad8e6f57f50028a8ff
+size 12345`,
+
+		// sha512 ext in a v1 pointer
+		`version https://git-lfs.github.com/spec/v1
+ext-0-foo sha512:ee26b0dd4af7e749aa1a8ee3c10ae9923f618980772e473f8819a5d4940e0db27ac185f8a0e1d5f84f88bc887fd67b143732c304cc5fa9ad8e6f57f50028a8ff
+oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393
+size 12345`,
+
+		// sha256 length oid for sha512
+		`version https://git-lfs.github.com/spec/v2
+oid sha512:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393
+size 12345`,
+
+		// unknown oid type
+		`version https://git-lfs.github.com/spec/v2
+oid md5:4d7a214614ab2935c943f9e0ff69d22e
+size 12345`,
+	}
+
+	for _, ex := range examples {
+		p, err := DecodePointer(bytes.NewBufferString(ex))
+		if err == nil {
+			t.Errorf("No error decoding: %v\nFrom:\n%s", p, strings.TrimSpace(ex))
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package lfs
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/rubyist/tracerx"
+)
+
+// proxyConfig chooses the proxy for each request the way Git does through
+// curl. The http.proxy Git config (or https.proxy for HTTPS URLs) wins over the
+// http_proxy, https_proxy, and all_proxy environment variables, and hosts that
+// match NO_PROXY are always reached directly.
+type proxyConfig struct {
+	httpProxy  string
+	httpsProxy string
+	noProxy    string
+
+	mu    sync.Mutex
+	creds map[string]Creds // Proxy credentials from Git's credential helpers
+}
+
+func (c *Configuration) proxyConfig() *proxyConfig {
+	pc := &proxyConfig{creds: make(map[string]Creds)}
+
+	gitProxy, _ := c.GitConfig("http.proxy")
+	allProxy := firstNonEmpty(c.Getenv("all_proxy"), c.Getenv("ALL_PROXY"))
+
+	// Like curl, only the lower case http_proxy is used, since HTTP_PROXY
+	// can be set by a CGI request header.
+	pc.httpProxy = firstNonEmpty(gitProxy, c.Getenv("http_proxy"), allProxy)
+
+	httpsProxy, _ := c.GitConfig("https.proxy")
+	pc.httpsProxy = firstNonEmpty(httpsProxy, gitProxy,
+		c.Getenv("https_proxy"), c.Getenv("HTTPS_PROXY"), allProxy)
+
+	pc.noProxy = firstNonEmpty(c.Getenv("no_proxy"), c.Getenv("NO_PROXY"))
+
+	return pc
+}
+
+// Proxy returns the proxy URL for the request, or nil if the request should
+// not be proxied. It can be used as the Proxy of an http.Transport.
+func (pc *proxyConfig) Proxy(req *http.Request) (*url.URL, error) {
+	proxy, err := pc.proxyURL(req.URL)
+	if proxy == nil || err != nil {
+		return proxy, err
+	}
+
+	// A proxy URL with a user but no password gets the password from Git's
+	// credential helpers, like Git does.
+	if proxy.User != nil && len(proxy.User.Username()) > 0 {
+		if _, ok := proxy.User.Password(); !ok {
+			creds, err := pc.fillCredentials(proxy)
+			if err != nil {
+				return nil, err
+			}
+			proxy.User = url.UserPassword(creds["username"], creds["password"])
+		}
+	}
+
+	return proxy, nil
+}
+
+// proxyURL returns the configured proxy for u, without any credentials from
+// the credential helpers.
+func (pc *proxyConfig) proxyURL(u *url.URL) (*url.URL, error) {
+	proxy := pc.httpProxy
+	if u.Scheme == "https" {
+		proxy = pc.httpsProxy
+	}
+
+	if len(proxy) == 0 || noProxyMatches(pc.noProxy, u.Host) {
+		return nil, nil
+	}
+
+	// Git assumes an HTTP proxy if the scheme is missing.
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+
+	return url.Parse(proxy)
+}
+
+func (pc *proxyConfig) fillCredentials(proxy *url.URL) (Creds, error) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if creds, ok := pc.creds[proxy.Host]; ok {
+		return creds, nil
+	}
+
+	input := Creds{"protocol": proxy.Scheme, "host": proxy.Host, "username": proxy.User.Username()}
+	creds, err := credCache.Fill(input)
+	if err != nil {
+		return nil, Errorf(err, "Git credentials for proxy %s not found", proxy.Host)
+	}
+	if creds == nil {
+		return nil, Errorf(nil, "Git credentials for proxy %s not found.", proxy.Host)
+	}
+
+	tracerx.Printf("Filled credentials for proxy %s", proxy.Host)
+	pc.creds[proxy.Host] = creds
+	return creds, nil
+}
+
+// saveCredentials approves the proxy credentials that were used for the
+// request if the proxy accepted them, or rejects them if the proxy responded
+// with 407 Proxy Authentication Required. Rejected credentials are filled
+// again for the next request.
+func (pc *proxyConfig) saveCredentials(req *http.Request, res *http.Response, err error) {
+	proxy, _ := pc.proxyURL(req.URL)
+	if proxy == nil {
+		return
+	}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	creds, ok := pc.creds[proxy.Host]
+	if !ok {
+		return
+	}
+
+	// Go reports a 407 from a CONNECT to the proxy as an error.
+	rejected := (res != nil && res.StatusCode == 407) ||
+		(err != nil && strings.Contains(err.Error(), "Proxy Authentication Required"))
+
+	if rejected {
+		credCache.Reject(creds)
+		delete(pc.creds, proxy.Host)
+	} else if err == nil {
+		credCache.Approve(creds)
+	}
+}
+
+// noProxyMatches returns whether host is excluded from proxying by noProxy, a
+// comma or space separated list of host names. Like curl, a single "*" matches
+// every host, and each name matches the host itself and any of its subdomains,
+// with or without a leading ".".
+func noProxyMatches(noProxy, host string) bool {
+	if len(noProxy) == 0 {
+		return false
+	}
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.Trim(host, "[]"))
+
+	for _, name := range strings.FieldsFunc(noProxy, isNoProxySeparator) {
+		if name == "*" {
+			return true
+		}
+
+		name = strings.ToLower(strings.TrimPrefix(name, "."))
+		if len(name) == 0 {
+			continue
+		}
+
+		if host == name || strings.HasSuffix(host, "."+name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isNoProxySeparator(r rune) bool {
+	return r == ',' || r == ' ' || r == '\t'
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if len(v) > 0 {
+			return v
+		}
+	}
+	return ""
+}
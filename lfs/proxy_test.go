@@ -0,0 +1,177 @@
+package lfs
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/technoweenie/assert"
+)
+
+func TestProxyConfigPrecedence(t *testing.T) {
+	type proxyTest struct {
+		GitConfig map[string]string
+		Env       map[string]string
+		Http      string
+		Https     string
+	}
+
+	tests := map[string]proxyTest{
+		"none": {nil, nil, "", ""},
+		"env": {
+			nil,
+			map[string]string{"http_proxy": "http-env:8080", "HTTPS_PROXY": "https-env:8080"},
+			"http://http-env:8080", "http://https-env:8080",
+		},
+		"upper case HTTP_PROXY is ignored": {
+			nil,
+			map[string]string{"HTTP_PROXY": "http-env:8080"},
+			"", "",
+		},
+		"all_proxy": {
+			nil,
+			map[string]string{"ALL_PROXY": "all-env:8080"},
+			"http://all-env:8080", "http://all-env:8080",
+		},
+		"git config wins": {
+			map[string]string{"http.proxy": "https://git-proxy:3128"},
+			map[string]string{"http_proxy": "http-env:8080", "https_proxy": "https-env:8080"},
+			"https://git-proxy:3128", "https://git-proxy:3128",
+		},
+		"https.proxy": {
+			map[string]string{"http.proxy": "git-proxy:3128", "https.proxy": "git-https-proxy:3128"},
+			nil,
+			"http://git-proxy:3128", "http://git-https-proxy:3128",
+		},
+	}
+
+	for desc, test := range tests {
+		config := &Configuration{gitConfig: test.GitConfig, envVars: test.Env}
+		if config.gitConfig == nil {
+			config.gitConfig = map[string]string{}
+		}
+		if config.envVars == nil {
+			config.envVars = map[string]string{}
+		}
+		for _, key := range []string{"http_proxy", "HTTP_PROXY", "https_proxy", "HTTPS_PROXY", "all_proxy", "ALL_PROXY", "no_proxy", "NO_PROXY"} {
+			if _, ok := config.envVars[key]; !ok {
+				config.envVars[key] = ""
+			}
+		}
+
+		pc := config.proxyConfig()
+		for scheme, expected := range map[string]string{"http": test.Http, "https": test.Https} {
+			proxy, err := pc.proxyURL(&url.URL{Scheme: scheme, Host: "git-server.com"})
+			if err != nil {
+				t.Errorf("%s (%s): %s", desc, scheme, err)
+				continue
+			}
+
+			actual := ""
+			if proxy != nil {
+				actual = proxy.String()
+			}
+
+			if actual != expected {
+				t.Errorf("%s (%s): expected proxy %q, got %q", desc, scheme, expected, actual)
+			}
+		}
+	}
+}
+
+func TestNoProxyMatches(t *testing.T) {
+	tests := []struct {
+		NoProxy string
+		Host    string
+		Matches bool
+	}{
+		{"", "git-server.com", false},
+		{"*", "git-server.com", true},
+		{"git-server.com", "git-server.com", true},
+		{"git-server.com", "GIT-SERVER.com:8080", true},
+		{"git-server.com", "lfs.git-server.com", true},
+		{".git-server.com", "lfs.git-server.com", true},
+		{".git-server.com", "git-server.com", true},
+		{"git-server.com", "notgit-server.com", false},
+		{"other.com, git-server.com", "git-server.com", true},
+		{"other.com git-server.com", "git-server.com", true},
+		{"other.com,*.git-server.com", "git-server.com", false},
+		{"127.0.0.1", "127.0.0.1:8080", true},
+		{"::1", "[::1]:8080", true},
+	}
+
+	for _, test := range tests {
+		if actual := noProxyMatches(test.NoProxy, test.Host); actual != test.Matches {
+			t.Errorf("NO_PROXY=%q with %q: expected %v, got %v", test.NoProxy, test.Host, test.Matches, actual)
+		}
+	}
+}
+
+func TestProxyCredentialsFromHelper(t *testing.T) {
+	expectedAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte("proxyuser:proxypass"))
+	requests := 0
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests += 1
+		if r.Header.Get("Proxy-Authorization") != expectedAuth {
+			w.WriteHeader(407)
+			return
+		}
+
+		if r.URL.Host != "git-server.com" {
+			t.Errorf("unexpected proxied host: %s", r.URL.Host)
+		}
+		w.Write([]byte("proxied"))
+	}))
+	defer proxy.Close()
+
+	oldExecCreds := execCreds
+	credCache = newCredentialCache()
+	filled := 0
+	approved := 0
+	execCreds = func(input Creds, subCommand string) (Creds, error) {
+		switch subCommand {
+		case "fill":
+			filled += 1
+			assert.Equal(t, "proxyuser", input["username"])
+			return Creds{"protocol": input["protocol"], "host": input["host"], "username": "proxyuser", "password": "proxypass"}, nil
+		case "approve":
+			approved += 1
+		}
+		return input, nil
+	}
+	defer func() {
+		execCreds = oldExecCreds
+		credCache = newCredentialCache()
+	}()
+
+	proxyUrl, _ := url.Parse(proxy.URL)
+	config := &Configuration{
+		gitConfig: map[string]string{"http.proxy": "http://proxyuser@" + proxyUrl.Host},
+		envVars:   map[string]string{"no_proxy": "", "NO_PROXY": "", "all_proxy": "", "ALL_PROXY": ""},
+	}
+
+	client := config.HttpClient()
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest("GET", "http://git-server.com/foo", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		by, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		assert.Equal(t, 200, res.StatusCode)
+		assert.Equal(t, "proxied", string(by))
+	}
+
+	assert.Equal(t, 2, requests)
+	assert.Equal(t, 1, filled)
+	assert.Equal(t, 1, approved)
+}
@@ -0,0 +1,112 @@
+// +build linux
+
+package lfs
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// ficlone is the FICLONE ioctl, which shares the extents of one file with
+// another on filesystems that support it, like Btrfs and XFS.
+const ficlone = 0x40049409
+
+// reflinkFile makes dst a copy-on-write clone of src.
+func reflinkFile(dst, src *os.File) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dst.Fd(), ficlone, src.Fd())
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// fiemap is the FS_IOC_FIEMAP ioctl, which lists where the extents of a file
+// are stored.
+const fiemap = 0xc020660b
+
+const (
+	fiemapFlagSync = 0x1 // Flushes the file before mapping it
+
+	fiemapExtentLast = 0x1 // The last extent of the file
+	// Extents whose physical location doesn't identify their data: unknown
+	// or not yet allocated, encoded, or stored inline with the metadata.
+	fiemapExtentUnshareable = 0x2 | 0x4 | 0x8 | 0x200 | 0x400
+)
+
+// fiemapExtentCount is the number of extents mapped by each ioctl call.
+const fiemapExtentCount = 64
+
+type fiemapExtent struct {
+	Logical    uint64
+	Physical   uint64
+	Length     uint64
+	reserved64 [2]uint64
+	Flags      uint32
+	reserved   [3]uint32
+}
+
+type fiemapRequest struct {
+	Start         uint64
+	Length        uint64
+	Flags         uint32
+	MappedExtents uint32
+	ExtentCount   uint32
+	reserved      uint32
+	Extents       [fiemapExtentCount]fiemapExtent
+}
+
+// sharesExtents returns whether the content of a and b is stored in the same
+// extents, as after one is cloned from the other.
+func sharesExtents(a, b *os.File) (bool, error) {
+	aExtents, err := fileExtents(a)
+	if err != nil || len(aExtents) == 0 {
+		return false, err
+	}
+
+	bExtents, err := fileExtents(b)
+	if err != nil || len(aExtents) != len(bExtents) {
+		return false, err
+	}
+
+	for i, e := range aExtents {
+		f := bExtents[i]
+		if e.Flags&fiemapExtentUnshareable != 0 || e.Logical != f.Logical || e.Physical != f.Physical || e.Length != f.Length {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// fileExtents lists the extents of file, with FIEMAP.
+func fileExtents(file *os.File) ([]fiemapExtent, error) {
+	var extents []fiemapExtent
+	var start uint64
+
+	for {
+		req := fiemapRequest{
+			Start:       start,
+			Length:      ^uint64(0) - start,
+			Flags:       fiemapFlagSync,
+			ExtentCount: fiemapExtentCount,
+		}
+
+		_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), fiemap, uintptr(unsafe.Pointer(&req)))
+		if errno != 0 {
+			return nil, errno
+		}
+
+		if req.MappedExtents == 0 {
+			return extents, nil
+		}
+
+		mapped := req.Extents[:req.MappedExtents]
+		extents = append(extents, mapped...)
+
+		last := mapped[len(mapped)-1]
+		if last.Flags&fiemapExtentLast != 0 {
+			return extents, nil
+		}
+		start = last.Logical + last.Length
+	}
+}
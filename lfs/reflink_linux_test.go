@@ -0,0 +1,48 @@
+package lfs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/technoweenie/assert"
+)
+
+func TestSharesExtents(t *testing.T) {
+	tmp := tempdir(t)
+	defer os.RemoveAll(tmp)
+
+	content := bytes.Repeat([]byte("extents"), 10000)
+	original := filepath.Join(tmp, "original")
+	copied := filepath.Join(tmp, "copied")
+	linked := filepath.Join(tmp, "linked")
+	assert.Equal(t, nil, ioutil.WriteFile(original, content, 0644))
+	assert.Equal(t, nil, ioutil.WriteFile(copied, content, 0644))
+	assert.Equal(t, nil, os.Link(original, linked))
+
+	a, err := os.Open(original)
+	assert.Equal(t, nil, err)
+	defer a.Close()
+
+	if _, err := fileExtents(a); err != nil {
+		t.Skipf("FIEMAP is not supported in %s: %v", tmp, err)
+	}
+
+	b, err := os.Open(linked)
+	assert.Equal(t, nil, err)
+	defer b.Close()
+
+	c, err := os.Open(copied)
+	assert.Equal(t, nil, err)
+	defer c.Close()
+
+	shared, err := sharesExtents(a, b)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, shared)
+
+	shared, err = sharesExtents(a, c)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, false, shared)
+}
@@ -0,0 +1,20 @@
+// +build !linux
+
+package lfs
+
+import (
+	"errors"
+	"os"
+)
+
+// reflinkFile makes dst a copy-on-write clone of src. It is only supported on
+// Linux.
+func reflinkFile(dst, src *os.File) error {
+	return errors.New("reflinks are not supported on this platform")
+}
+
+// sharesExtents returns whether the content of a and b is stored in the same
+// extents. Since files can only be cloned on Linux, they never are elsewhere.
+func sharesExtents(a, b *os.File) (bool, error) {
+	return false, nil
+}
@@ -34,7 +34,7 @@ var (
 	// Arguments to append to a git log call which will limit the output to
 	// lfs changes and format the output suitable for parseLogOutput.. method(s)
 	logLfsSearchArgs = []string{
-		"-G", "oid sha256:", // only diffs which include an lfs file SHA change
+		"-G", "oid [a-z0-9-]+:", // only diffs which include an lfs file SHA change
 		"-p",   // include diff so we can read the SHA
 		"-U12", // Make sure diff context is always big enough to support 10 extension lines to get whole pointer
 		`--format=lfs-commit-sha: %H %P`, // just a predictable commit header we can detect
@@ -756,7 +756,7 @@ func parseLogOutputToPointers(log io.Reader, dir LogDiffDirection,
 	commitHeaderRegex := regexp.MustCompile(`^lfs-commit-sha: ([A-Fa-f0-9]{40})(?: ([A-Fa-f0-9]{40}))*`)
 	fileHeaderRegex := regexp.MustCompile(`diff --git a\/(.+?)\s+b\/(.+)`)
 	fileMergeHeaderRegex := regexp.MustCompile(`diff --cc (.+)`)
-	pointerDataRegex := regexp.MustCompile(`^([\+\- ])(version https://git-lfs|oid sha256|size|ext-).*$`)
+	pointerDataRegex := regexp.MustCompile(`^([\+\- ])(version https://git-lfs|oid [a-z0-9-]+:|size|ext-).*$`)
 	var pointerData bytes.Buffer
 	var currentFilename string
 	currentFileIncluded := true
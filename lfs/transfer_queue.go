@@ -4,12 +4,15 @@ import (
 	"sync"
 	"sync/atomic"
 
-	"github.com/github/git-lfs/git"
 	"github.com/github/git-lfs/vendor/_nuts/github.com/rubyist/tracerx"
 )
 
 const (
 	batchSize = 100
+
+	// batchLookahead is the number of batches that can be resolved with the
+	// API while the transfers of an earlier batch are still being queued.
+	batchLookahead = 2
 )
 
 type Transferable interface {
@@ -22,17 +25,37 @@ type Transferable interface {
 	SetObject(*ObjectResource)
 }
 
+// A chunkedTransferable is a Transferable stored in chunks. Its chunks are sent
+// with batch API requests, so that the server can ask for only the chunks it
+// doesn't have.
+type chunkedTransferable interface {
+	Chunks() []*ObjectResource
+}
+
+// An apiAdapter asks a Git LFS API how to transfer the objects added to a
+// TransferQueue, and hands the objects that need transferring to the queue's
+// transfer workers.
+type apiAdapter interface {
+	// Add queues the Transferable for an API request.
+	Add(Transferable)
+	// Flush makes the API requests for any queued Transferables. Add may
+	// still be called afterwards, for retries.
+	Flush()
+	// Close stops the adapter once the queue has finished.
+	Close()
+}
+
 // TransferQueue provides a queue that will allow concurrent transfers.
 type TransferQueue struct {
 	retrying      uint32
 	meter         *ProgressMeter
 	workers       int // Number of transfer workers to spawn
 	transferKind  string
+	ref           string // Ref sent with batch API requests
 	errors        []error
 	transferables map[string]Transferable
 	retries       []Transferable
-	batcher       *Batcher
-	apic          chan Transferable // Channel for processing individual API requests
+	adapter       apiAdapter        // Asks the API which objects to transfer
 	transferc     chan Transferable // Channel for processing transfers
 	retriesc      chan Transferable // Channel for processing retries
 	errorc        chan error        // Channel for processing errors
@@ -43,10 +66,10 @@ type TransferQueue struct {
 }
 
 // newTransferQueue builds a TransferQueue, allowing `workers` concurrent transfers.
-func newTransferQueue(files int, size int64, dryRun bool) *TransferQueue {
+func newTransferQueue(files int, size int64, dryRun bool, ref string) *TransferQueue {
 	q := &TransferQueue{
+		ref:           ref,
 		meter:         NewProgressMeter(files, size, dryRun),
-		apic:          make(chan Transferable, batchSize),
 		transferc:     make(chan Transferable, batchSize),
 		retriesc:      make(chan Transferable, batchSize),
 		errorc:        make(chan error),
@@ -66,22 +89,14 @@ func newTransferQueue(files int, size int64, dryRun bool) *TransferQueue {
 func (q *TransferQueue) Add(t Transferable) {
 	q.wait.Add(1)
 	q.transferables[t.Oid()] = t
-
-	if q.batcher != nil {
-		q.batcher.Add(t)
-		return
-	}
-
-	q.apic <- t
+	q.adapter.Add(t)
 }
 
 // Wait waits for the queue to finish processing all transfers. Once Wait is
 // called, Add will no longer add transferables to the queue. Any failed
 // transfers will be automatically retried once.
 func (q *TransferQueue) Wait() {
-	if q.batcher != nil {
-		q.batcher.Exit()
-	}
+	q.adapter.Flush()
 
 	q.wait.Wait()
 
@@ -95,15 +110,13 @@ func (q *TransferQueue) Wait() {
 		for _, t := range q.retries {
 			q.Add(t)
 		}
-		if q.batcher != nil {
-			q.batcher.Exit()
-		}
+		q.adapter.Flush()
 		q.wait.Wait()
 	}
 
 	atomic.StoreUint32(&q.retrying, 0)
 
-	close(q.apic)
+	q.adapter.Close()
 	close(q.transferc)
 	close(q.errorc)
 
@@ -123,134 +136,6 @@ func (q *TransferQueue) Watch() chan string {
 	return c
 }
 
-// individualApiRoutine processes the queue of transfers one at a time by making
-// a POST call for each object, feeding the results to the transfer workers.
-// If configured, the object transfers can still happen concurrently, the
-// sequential nature here is only for the meta POST calls.
-func (q *TransferQueue) individualApiRoutine(apiWaiter chan interface{}) {
-	for t := range q.apic {
-		obj, err := t.Check()
-		if err != nil {
-			if q.canRetry(err) {
-				q.retry(t)
-			} else {
-				q.errorc <- err
-			}
-			q.wait.Done()
-			continue
-		}
-
-		if apiWaiter != nil { // Signal to launch more individual api workers
-			q.meter.Start()
-			select {
-			case apiWaiter <- 1:
-			default:
-			}
-		}
-
-		if obj != nil {
-			t.SetObject(obj)
-			q.meter.Add(t.Name())
-			q.transferc <- t
-		} else {
-			q.meter.Skip(t.Size())
-			q.wait.Done()
-		}
-	}
-}
-
-// legacyFallback is used when a batch request is made to a server that does
-// not support the batch endpoint. When this happens, the Transferables are
-// fed from the batcher into apic to be processed individually.
-func (q *TransferQueue) legacyFallback(failedBatch []Transferable) {
-	tracerx.Printf("tq: batch api not implemented, falling back to individual")
-
-	q.launchIndividualApiRoutines()
-
-	for _, t := range failedBatch {
-		q.apic <- t
-	}
-
-	for {
-		batch := q.batcher.Next()
-		if batch == nil {
-			break
-		}
-
-		for _, t := range batch {
-			q.apic <- t
-		}
-	}
-}
-
-// batchApiRoutine processes the queue of transfers using the batch endpoint,
-// making only one POST call for all objects. The results are then handed
-// off to the transfer workers.
-func (q *TransferQueue) batchApiRoutine() {
-	var startProgress sync.Once
-
-	for {
-		batch := q.batcher.Next()
-		if batch == nil {
-			break
-		}
-
-		tracerx.Printf("tq: sending batch of size %d", len(batch))
-
-		transfers := make([]*ObjectResource, 0, len(batch))
-		for _, t := range batch {
-			transfers = append(transfers, &ObjectResource{Oid: t.Oid(), Size: t.Size()})
-		}
-
-		objects, err := Batch(transfers, q.transferKind)
-		if err != nil {
-			if IsNotImplementedError(err) {
-				git.Config.SetLocal("", "lfs.batch", "false")
-
-				go q.legacyFallback(batch)
-				return
-			}
-
-			if q.canRetry(err) {
-				for _, t := range batch {
-					q.retry(t)
-				}
-			} else {
-				q.errorc <- err
-			}
-
-			q.wait.Add(-len(transfers))
-			continue
-		}
-
-		startProgress.Do(q.meter.Start)
-
-		for _, o := range objects {
-			if o.Error != nil {
-				q.errorc <- Errorf(o.Error, "[%v] %v", o.Oid, o.Error.Message)
-				q.meter.Skip(o.Size)
-				q.wait.Done()
-				continue
-			}
-
-			if _, ok := o.Rel(q.transferKind); ok {
-				// This object needs to be transferred
-				if transfer, ok := q.transferables[o.Oid]; ok {
-					transfer.SetObject(o)
-					q.meter.Add(transfer.Name())
-					q.transferc <- transfer
-				} else {
-					q.meter.Skip(transfer.Size())
-					q.wait.Done()
-				}
-			} else {
-				q.meter.Skip(o.Size)
-				q.wait.Done()
-			}
-		}
-	}
-}
-
 // This goroutine collects errors returned from transfers
 func (q *TransferQueue) errorCollector() {
 	for err := range q.errorc {
@@ -293,25 +178,8 @@ func (q *TransferQueue) transferWorker() {
 	}
 }
 
-// launchIndividualApiRoutines first launches a single api worker. When it
-// receives the first successful api request it launches workers - 1 more
-// workers. This prevents being prompted for credentials multiple times at once
-// when they're needed.
-func (q *TransferQueue) launchIndividualApiRoutines() {
-	go func() {
-		apiWaiter := make(chan interface{})
-		go q.individualApiRoutine(apiWaiter)
-
-		<-apiWaiter
-
-		for i := 0; i < q.workers-1; i++ {
-			go q.individualApiRoutine(nil)
-		}
-	}()
-}
-
 // run starts the transfer queue, doing individual or batch transfers depending
-// on the Config.BatchTransfer() value. run will transfer files sequentially or
+// on the Config.BatchTransfer() and Config.LegacyApi() values. run will transfer files sequentially or
 // concurrently depending on the Config.ConcurrentTransfers() value.
 func (q *TransferQueue) run() {
 	go q.errorCollector()
@@ -322,13 +190,15 @@ func (q *TransferQueue) run() {
 		go q.transferWorker()
 	}
 
-	if Config.BatchTransfer() {
-		tracerx.Printf("tq: running as batched queue, batch size of %d", batchSize)
-		q.batcher = NewBatcher(batchSize)
-		go q.batchApiRoutine()
+	caps := EndpointCapabilities(Config.Endpoint())
+	if caps != nil && !caps.Batch {
+		tracerx.Printf("tq: server does not support the batch api")
+	}
+
+	if Config.BatchTransfer() && (caps == nil || caps.Batch || !Config.LegacyApi()) {
+		q.adapter = newBatchApiAdapter(q, caps)
 	} else {
-		tracerx.Printf("tq: running as individual queue")
-		q.launchIndividualApiRoutines()
+		q.adapter = newLegacyApiAdapter(q)
 	}
 }
 
@@ -0,0 +1,140 @@
+package lfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTransferQueueWaitWithoutTransfers(t *testing.T) {
+	// Wait sends an empty final batch, which used to race with closing the
+	// batch channel, so try it many times.
+	for i := 0; i < 100; i++ {
+		q := NewUploadQueue(0, 0, true, "")
+		q.Wait()
+
+		if errs := q.Errors(); len(errs) > 0 {
+			t.Fatalf("unexpected errors: %v", errs)
+		}
+	}
+}
+
+func TestTransferQueueConcurrentBatches(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var mu sync.Mutex
+	requests, inflight, maxInflight := 0, 0, 0
+
+	mux.HandleFunc("/media/objects/batch", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		inflight++
+		if inflight > maxInflight {
+			maxInflight = inflight
+		}
+		mu.Unlock()
+
+		// Gives the other requests time to start.
+		time.Sleep(50 * time.Millisecond)
+
+		// Responds with the objects without actions, as if the server
+		// has them all already.
+		body := struct {
+			Objects []*ObjectResource `json:"objects"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+
+		w.Header().Set("Content-Type", mediaType)
+		w.WriteHeader(200)
+		json.NewEncoder(w).Encode(body)
+
+		mu.Lock()
+		inflight--
+		mu.Unlock()
+	})
+
+	defer Config.ResetConfig()
+	Config.SetConfig("lfs.url", server.URL+"/media")
+	Config.SetConfig("lfs.batchmaxobjects", "1")
+	Config.SetConfig("lfs.concurrenttransfers", "3")
+
+	q := NewUploadQueue(8, 8, true, "")
+	for i := 0; i < 8; i++ {
+		q.Add(&Uploadable{oid: fmt.Sprintf("%064x", i), size: 1})
+	}
+	q.Wait()
+
+	if errs := q.Errors(); len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if requests != 8 {
+		t.Errorf("expected 8 batch requests, got %d", requests)
+	}
+
+	// The first request is made alone, and at most 3 at once after that.
+	if maxInflight < 2 || maxInflight > 3 {
+		t.Errorf("expected 2 or 3 batch requests at once, got %d", maxInflight)
+	}
+}
+
+func TestTransferQueueConcurrentBatchErrors(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var mu sync.Mutex
+	requests := 0
+
+	mux.HandleFunc("/media/objects/batch", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		first := requests == 1
+		mu.Unlock()
+
+		// Only the first batch succeeds, so that the others fail
+		// while they are resolved concurrently.
+		if !first {
+			w.WriteHeader(403)
+			return
+		}
+
+		body := struct {
+			Objects []*ObjectResource `json:"objects"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+
+		w.Header().Set("Content-Type", mediaType)
+		w.WriteHeader(200)
+		json.NewEncoder(w).Encode(body)
+	})
+
+	defer Config.ResetConfig()
+	Config.SetConfig("lfs.url", server.URL+"/media")
+	Config.SetConfig("lfs.batchmaxobjects", "1")
+	Config.SetConfig("lfs.concurrenttransfers", "3")
+
+	q := NewUploadQueue(8, 8, true, "")
+	for i := 0; i < 8; i++ {
+		q.Add(&Uploadable{oid: fmt.Sprintf("%064x", i), size: 1})
+	}
+	q.Wait()
+
+	// Each failed batch is reported once.
+	if errs := q.Errors(); len(errs) != 7 {
+		t.Errorf("expected 7 errors, got %d: %v", len(errs), errs)
+	}
+}
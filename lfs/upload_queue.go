@@ -12,6 +12,7 @@ type Uploadable struct {
 	OidPath  string
 	Filename string
 	size     int64
+	chunks   []*ObjectResource
 	object   *ObjectResource
 }
 
@@ -29,12 +30,18 @@ func NewUploadable(oid, filename string) (*Uploadable, error) {
 		}
 	}
 
-	fi, err := os.Stat(localMediaPath)
+	size, err := StatLocalObject(localMediaPath)
 	if err != nil {
 		return nil, Errorf(err, "Error uploading file %s (%s)", filename, oid)
 	}
 
-	return &Uploadable{oid: oid, OidPath: localMediaPath, Filename: filename, size: fi.Size()}, nil
+	// Hard linked objects may have been edited through the working
+	// directory, and must not be uploaded with the wrong content.
+	if err := VerifyLinkedObject(oid, localMediaPath); err != nil {
+		return nil, Errorf(err, "Error uploading file %s (%s): %s", filename, oid, err)
+	}
+
+	return &Uploadable{oid: oid, OidPath: localMediaPath, Filename: filename, size: size, chunks: localObjectChunks(localMediaPath)}, nil
 }
 
 func (u *Uploadable) Check() (*ObjectResource, error) {
@@ -66,13 +73,19 @@ func (u *Uploadable) Name() string {
 	return u.Filename
 }
 
+// Chunks returns the chunks of the object, if it is stored in chunks.
+func (u *Uploadable) Chunks() []*ObjectResource {
+	return u.chunks
+}
+
 func (u *Uploadable) SetObject(o *ObjectResource) {
 	u.object = o
 }
 
 // NewUploadQueue builds an UploadQueue, allowing `workers` concurrent uploads.
-func NewUploadQueue(files int, size int64, dryRun bool) *TransferQueue {
-	q := newTransferQueue(files, size, dryRun)
+// The ref being pushed, if any, is sent with each batch API request.
+func NewUploadQueue(files int, size int64, dryRun bool, ref string) *TransferQueue {
+	q := newTransferQueue(files, size, dryRun, ref)
 	q.transferKind = "upload"
 	return q
 }
@@ -80,11 +93,11 @@ func NewUploadQueue(files int, size int64, dryRun bool) *TransferQueue {
 // ensureFile makes sure that the cleanPath exists before pushing it.  If it
 // does not exist, it attempts to clean it by reading the file at smudgePath.
 func ensureFile(smudgePath, cleanPath string) error {
-	if _, err := os.Stat(cleanPath); err == nil {
+	if localObjectExists(cleanPath) {
 		return nil
 	}
 
-	expectedOid := filepath.Base(cleanPath)
+	expectedOid := objectIDFromPath(cleanPath)
 	localPath := filepath.Join(LocalWorkingDir, smudgePath)
 	file, err := os.Open(localPath)
 	if err != nil {
@@ -98,7 +111,7 @@ func ensureFile(smudgePath, cleanPath string) error {
 		return err
 	}
 
-	cleaned, err := PointerClean(file, file.Name(), stat.Size(), nil)
+	cleaned, err := pointerClean(file, file.Name(), stat.Size(), ObjectHashAlgorithm(expectedOid), nil)
 	if cleaned != nil {
 		cleaned.Teardown()
 	}
@@ -104,6 +104,7 @@ func TestExistingUpload(t *testing.T) {
 	})
 
 	defer Config.ResetConfig()
+	Config.SetConfig("lfs.legacyapi", "true")
 	Config.SetConfig("lfs.url", server.URL+"/media")
 
 	oidPath, _ := LocalMediaPath("988881adc9fc3655077dc2d4d757d480b5ea0e11")
@@ -228,6 +229,7 @@ func TestUploadWithRedirect(t *testing.T) {
 	})
 
 	defer Config.ResetConfig()
+	Config.SetConfig("lfs.legacyapi", "true")
 	Config.SetConfig("lfs.url", server.URL+"/redirect")
 
 	oidPath, _ := LocalMediaPath("988881adc9fc3655077dc2d4d757d480b5ea0e11")
@@ -402,6 +404,7 @@ func TestSuccessfulUploadWithVerify(t *testing.T) {
 	})
 
 	defer Config.ResetConfig()
+	Config.SetConfig("lfs.legacyapi", "true")
 	Config.SetConfig("lfs.url", server.URL+"/media")
 
 	oidPath, _ := LocalMediaPath("988881adc9fc3655077dc2d4d757d480b5ea0e11")
@@ -563,6 +566,7 @@ func TestSuccessfulUploadWithoutVerify(t *testing.T) {
 	})
 
 	defer Config.ResetConfig()
+	Config.SetConfig("lfs.legacyapi", "true")
 	Config.SetConfig("lfs.url", server.URL+"/media")
 
 	oidPath, _ := LocalMediaPath("988881adc9fc3655077dc2d4d757d480b5ea0e11")
@@ -608,6 +612,7 @@ func TestUploadApiError(t *testing.T) {
 	})
 
 	defer Config.ResetConfig()
+	Config.SetConfig("lfs.legacyapi", "true")
 	Config.SetConfig("lfs.url", server.URL+"/media")
 
 	oidPath, _ := LocalMediaPath("988881adc9fc3655077dc2d4d757d480b5ea0e11")
@@ -716,6 +721,7 @@ func TestUploadStorageError(t *testing.T) {
 	})
 
 	defer Config.ResetConfig()
+	Config.SetConfig("lfs.legacyapi", "true")
 	Config.SetConfig("lfs.url", server.URL+"/media")
 
 	oidPath, _ := LocalMediaPath("988881adc9fc3655077dc2d4d757d480b5ea0e11")
@@ -865,6 +871,7 @@ func TestUploadVerifyError(t *testing.T) {
 	})
 
 	defer Config.ResetConfig()
+	Config.SetConfig("lfs.legacyapi", "true")
 	Config.SetConfig("lfs.url", server.URL+"/media")
 
 	oidPath, _ := LocalMediaPath("988881adc9fc3655077dc2d4d757d480b5ea0e11")
@@ -901,3 +908,68 @@ func TestUploadVerifyError(t *testing.T) {
 		t.Errorf("verify not called")
 	}
 }
+
+func TestUploadChecksumMismatch(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	tmp := tempdir(t)
+	olddir := LocalMediaDir
+	LocalMediaDir = tmp
+	defer func() {
+		LocalMediaDir = olddir
+	}()
+	defer server.Close()
+	defer os.RemoveAll(tmp)
+
+	oid := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	putCalled := false
+
+	mux.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
+		putCalled = true
+
+		if r.Header.Get("X-Content-Sha256") != oid {
+			t.Errorf("Invalid X-Content-Sha256: %q", r.Header.Get("X-Content-Sha256"))
+		}
+
+		if r.ContentLength != 4 {
+			t.Errorf("Invalid Content-Length: %d", r.ContentLength)
+		}
+
+		io.Copy(ioutil.Discard, r.Body)
+		w.WriteHeader(422)
+	})
+
+	defer Config.ResetConfig()
+	Config.SetConfig("lfs.url", server.URL+"/media")
+	Config.SetConfig("lfs."+server.URL+"/media.checksumheader", "X-Content-Sha256")
+
+	oidPath, _ := LocalMediaPath(oid)
+	if err := ioutil.WriteFile(oidPath, []byte("test"), 0744); err != nil {
+		t.Fatal(err)
+	}
+
+	obj := &ObjectResource{
+		Oid:  oid,
+		Size: 4,
+		Actions: map[string]*linkRelation{
+			"upload": &linkRelation{Href: server.URL + "/upload"},
+		},
+	}
+
+	err := UploadObject(obj, nil)
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	if !IsIntegrityError(err) {
+		t.Errorf("Expected an integrity error: %s", err)
+	}
+
+	if IsRetriableError(err) {
+		t.Errorf("Integrity errors should not be retried: %s", err)
+	}
+
+	if !putCalled {
+		t.Errorf("PUT not called")
+	}
+}
@@ -0,0 +1,58 @@
+package lfs
+
+import (
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// verifyingWriter checks downloaded content against the OID and size of its
+// pointer as it is written. Every download passes through one before the
+// content is moved into LocalMediaDir, so content that doesn't match is
+// rejected with an integrity error instead of being stored.
+type verifyingWriter struct {
+	writer  io.Writer
+	hasher  hash.Hash
+	oid     string
+	size    int64 // Expected size, or -1 if unknown
+	written int64
+}
+
+// newVerifyingWriter returns a verifyingWriter for the object with the given
+// ID, hashing the content with the object's hash algorithm.
+func newVerifyingWriter(w io.Writer, oid string, size int64) *verifyingWriter {
+	alg := ObjectHashAlgorithm(oid)
+	if alg == nil {
+		// Nothing will match an unknown algorithm's OID.
+		alg = defaultHashAlgorithm
+	}
+	return &verifyingWriter{writer: w, hasher: alg.New(), oid: oid, size: size}
+}
+
+// Write writes b to the underlying writer. It fails without writing anything
+// if the content would be larger than the expected size.
+func (w *verifyingWriter) Write(b []byte) (int, error) {
+	if w.size >= 0 && w.written+int64(len(b)) > w.size {
+		return 0, newIntegrityError(Error(fmt.Errorf("Expected %d bytes for %s, got more", w.size, w.oid)), w.oid)
+	}
+
+	n, err := w.writer.Write(b)
+	w.hasher.Write(b[:n])
+	w.written += int64(n)
+	return n, err
+}
+
+// Verify returns an integrity error if the content written so far does not
+// have the expected size and OID.
+func (w *verifyingWriter) Verify() error {
+	if w.size >= 0 && w.written != w.size {
+		return newIntegrityError(Error(fmt.Errorf("Expected %d bytes for %s, got %d", w.size, w.oid, w.written)), w.oid)
+	}
+
+	if actual := hex.EncodeToString(w.hasher.Sum(nil)); actual != objectIDHex(w.oid) {
+		return newIntegrityError(Error(fmt.Errorf("Expected OID %s, got %s after %d bytes written", w.oid, actual, w.written)), w.oid)
+	}
+
+	return nil
+}
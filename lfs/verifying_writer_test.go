@@ -0,0 +1,91 @@
+package lfs
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/github/git-lfs/vendor/_nuts/github.com/technoweenie/assert"
+)
+
+// sha256 of "test"
+const verifyingWriterOid = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+func TestVerifyingWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := newVerifyingWriter(buf, verifyingWriterOid, 4)
+
+	_, err := io.Copy(w, strings.NewReader("test"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, w.Verify())
+	assert.Equal(t, "test", buf.String())
+}
+
+func TestVerifyingWriterUnknownSize(t *testing.T) {
+	w := newVerifyingWriter(ioutil.Discard, verifyingWriterOid, -1)
+
+	_, err := io.Copy(w, strings.NewReader("test"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, w.Verify())
+}
+
+func TestVerifyingWriterWrongOid(t *testing.T) {
+	w := newVerifyingWriter(ioutil.Discard, verifyingWriterOid, 4)
+
+	_, err := io.Copy(w, strings.NewReader("tEst"))
+	assert.Equal(t, nil, err)
+
+	err = w.Verify()
+	assert.Equal(t, true, IsIntegrityError(err))
+	assert.Equal(t, verifyingWriterOid, ErrorGetContext(err, "OID"))
+}
+
+func TestVerifyingWriterTooShort(t *testing.T) {
+	w := newVerifyingWriter(ioutil.Discard, verifyingWriterOid, 4)
+
+	_, err := io.Copy(w, strings.NewReader("tes"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, IsIntegrityError(w.Verify()))
+}
+
+func TestVerifyingWriterTooLong(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := newVerifyingWriter(buf, verifyingWriterOid, 4)
+
+	_, err := w.Write([]byte("tes"))
+	assert.Equal(t, nil, err)
+
+	_, err = w.Write([]byte("ts"))
+	assert.Equal(t, true, IsIntegrityError(err))
+	assert.Equal(t, "tes", buf.String())
+}
+
+func TestBufferDownloadedFileRejectsBadContent(t *testing.T) {
+	tmp := tempdir(t)
+	defer os.RemoveAll(tmp)
+
+	oldTempDir := LocalObjectTempDir
+	LocalObjectTempDir = tmp
+	defer func() {
+		LocalObjectTempDir = oldTempDir
+	}()
+
+	filename := filepath.Join(tmp, verifyingWriterOid)
+	err := bufferDownloadedFile(filename, strings.NewReader("bad!"), 4, nil)
+	assert.Equal(t, true, IsIntegrityError(err))
+
+	// neither the object nor the temp file is left behind
+	files, _ := ioutil.ReadDir(tmp)
+	assert.Equal(t, 0, len(files))
+
+	err = bufferDownloadedFile(filename, strings.NewReader("test"), 4, nil)
+	assert.Equal(t, nil, err)
+
+	by, err := ioutil.ReadFile(filename)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "test", string(by))
+}
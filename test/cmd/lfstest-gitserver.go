@@ -3,7 +3,9 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"compress/gzip"
 	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
@@ -24,6 +26,8 @@ import (
 var (
 	repoDir      string
 	largeObjects = newLfsStorage()
+	largeChunks  = newLfsStorage()
+	objectChunks = newLfsStorage() // JSON chunk lists of objects uploaded in chunks
 	server       *httptest.Server
 
 	// maps OIDs to content strings. Both the LFS and Storage test servers below
@@ -95,6 +99,7 @@ type lfsObject struct {
 	Oid     string             `json:"oid,omitempty"`
 	Size    int64              `json:"size,omitempty"`
 	Actions map[string]lfsLink `json:"actions,omitempty"`
+	Chunks  []lfsObject        `json:"chunks,omitempty"`
 	Err     *lfsError          `json:"error,omitempty"`
 }
 
@@ -123,11 +128,21 @@ func lfsHandler(w http.ResponseWriter, r *http.Request) {
 	case "POST":
 		if strings.HasSuffix(r.URL.String(), "batch") {
 			lfsBatchHandler(w, r, repo)
+		} else if strings.HasSuffix(r.URL.String(), "objects/verify") {
+			lfsVerifyHandler(w, r, repo)
+		} else if strings.HasSuffix(r.URL.String(), "objects/delete") {
+			lfsDeleteHandler(w, r, repo)
+		} else if strings.HasSuffix(r.URL.String(), "metrics") {
+			lfsMetricsHandler(w, r, repo)
 		} else {
 			lfsPostHandler(w, r, repo)
 		}
 	case "GET":
-		lfsGetHandler(w, r, repo)
+		if strings.HasSuffix(r.URL.String(), "capabilities") {
+			lfsCapabilitiesHandler(w, r, repo)
+		} else {
+			lfsGetHandler(w, r, repo)
+		}
 	default:
 		w.WriteHeader(405)
 	}
@@ -137,6 +152,10 @@ func lfsUrl(repo, oid string) string {
 	return server.URL + "/storage/" + oid + "?r=" + repo
 }
 
+func lfsChunkUrl(repo, oid string) string {
+	return lfsUrl(repo, oid) + "&chunk=1"
+}
+
 func lfsPostHandler(w http.ResponseWriter, r *http.Request, repo string) {
 	buf := &bytes.Buffer{}
 	tee := io.TeeReader(r.Body, buf)
@@ -239,12 +258,102 @@ func lfsGetHandler(w http.ResponseWriter, r *http.Request, repo string) {
 	w.Write(by)
 }
 
-func lfsBatchHandler(w http.ResponseWriter, r *http.Request, repo string) {
+// handles client transfer metrics by logging them
+func lfsMetricsHandler(w http.ResponseWriter, r *http.Request, repo string) {
+	by, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Println("METRICS")
+	log.Println(string(by))
+
+	w.WriteHeader(200)
+}
+
+// handles the capabilities probe. The "capabilities-legacy" repo reports that
+// it only supports the legacy API.
+func lfsCapabilitiesHandler(w http.ResponseWriter, r *http.Request, repo string) {
+	caps := map[string]interface{}{
+		"batch":           repo != "capabilities-legacy",
+		"operations":      []string{"upload", "download", "delete"},
+		"transfers":       []string{"basic"},
+		"hash_algorithms": []string{"sha256", "sha512"},
+		"locking":         false,
+		"max_batch_size":  50,
+	}
+
+	if repo == "capabilities-gzip" {
+		caps["content_encodings"] = []string{"gzip"}
+	}
+
+	by, err := json.Marshal(caps)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w.WriteHeader(200)
+	w.Write(by)
+}
+
+// handles the delete endpoint, which uses the batch request format with a
+// "delete" operation
+func lfsDeleteHandler(w http.ResponseWriter, r *http.Request, repo string) {
 	if repo == "batchunsupported" {
 		w.WriteHeader(404)
 		return
 	}
 
+	type deleteReq struct {
+		Operation string      `json:"operation"`
+		Objects   []lfsObject `json:"objects"`
+	}
+
+	buf := &bytes.Buffer{}
+	tee := io.TeeReader(r.Body, buf)
+	var objs deleteReq
+	err := json.NewDecoder(tee).Decode(&objs)
+	io.Copy(ioutil.Discard, r.Body)
+	r.Body.Close()
+
+	log.Println("REQUEST")
+	log.Println(buf.String())
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	res := []lfsObject{}
+	for _, obj := range objs.Objects {
+		o := lfsObject{Oid: obj.Oid, Size: obj.Size}
+		if largeObjects.Has(repo, obj.Oid) {
+			largeObjects.Delete(repo, obj.Oid)
+			log.Println("DELETE:", obj.Oid)
+		} else {
+			o.Err = &lfsError{Code: 404, Message: fmt.Sprintf("Object %v does not exist", obj.Oid)}
+		}
+		res = append(res, o)
+	}
+
+	by, err := json.Marshal(map[string][]lfsObject{"objects": res})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Println("RESPONSE: 200")
+	log.Println(string(by))
+
+	w.WriteHeader(200)
+	w.Write(by)
+}
+
+func lfsBatchHandler(w http.ResponseWriter, r *http.Request, repo string) {
+	if strings.HasPrefix(repo, "batchunsupported") {
+		w.WriteHeader(404)
+		return
+	}
+
 	if repo == "badbatch" {
 		w.WriteHeader(203)
 		return
@@ -253,10 +362,24 @@ func lfsBatchHandler(w http.ResponseWriter, r *http.Request, repo string) {
 	type batchReq struct {
 		Operation string      `json:"operation"`
 		Objects   []lfsObject `json:"objects"`
+		Chunking  bool        `json:"chunking"`
+		HashAlgo  string      `json:"hash_algo"`
+		Ref       struct {
+			Name string `json:"name"`
+		} `json:"ref"`
+	}
+
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			log.Fatal(err)
+		}
+		body = gz
 	}
 
 	buf := &bytes.Buffer{}
-	tee := io.TeeReader(r.Body, buf)
+	tee := io.TeeReader(body, buf)
 	var objs batchReq
 	err := json.NewDecoder(tee).Decode(&objs)
 	io.Copy(ioutil.Discard, r.Body)
@@ -279,6 +402,19 @@ func lfsBatchHandler(w http.ResponseWriter, r *http.Request, repo string) {
 			Size: obj.Size,
 		}
 
+		if len(objs.HashAlgo) > 0 && !validOid(obj.Oid, objs.HashAlgo) {
+			o.Err = &lfsError{Code: 422, Message: fmt.Sprintf("Invalid %q object %v", objs.HashAlgo, obj.Oid)}
+			res = append(res, o)
+			continue
+		}
+
+		// simulates a server that authorizes pushes by branch
+		if action == "upload" && objs.Ref.Name == "refs/heads/protected" {
+			o.Err = &lfsError{Code: 403, Message: fmt.Sprintf("Pushing to %s is not allowed", objs.Ref.Name)}
+			res = append(res, o)
+			continue
+		}
+
 		exists := largeObjects.Has(repo, obj.Oid)
 		addAction := true
 		if action == "download" {
@@ -313,6 +449,12 @@ func lfsBatchHandler(w http.ResponseWriter, r *http.Request, repo string) {
 					},
 				}
 			}
+
+			// "chunked" repos support transferring objects in
+			// chunks
+			if addAction && strings.HasPrefix(repo, "chunked") {
+				addChunkActions(&o, obj, action, repo, objs.Chunking)
+			}
 		}
 
 		if testingChunked {
@@ -332,10 +474,102 @@ func lfsBatchHandler(w http.ResponseWriter, r *http.Request, repo string) {
 	log.Println("RESPONSE: 200")
 	log.Println(string(by))
 
+	if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(200)
+		gz := gzip.NewWriter(w)
+		gz.Write(by)
+		gz.Close()
+		return
+	}
+
 	w.WriteHeader(200)
 	w.Write(by)
 }
 
+// addChunkActions asks for the chunks of an object the client uploads in
+// chunks that the server doesn't have, instead of the whole object, and lists
+// the chunks of objects that were uploaded in chunks for clients that ask for
+// them.
+func addChunkActions(o *lfsObject, obj lfsObject, action, repo string, chunking bool) {
+	if action == "upload" {
+		if len(obj.Chunks) == 0 {
+			return
+		}
+
+		o.Actions = map[string]lfsLink{
+			"verify": lfsLink{Href: server.URL + "/" + repo + ".git/info/lfs/objects/verify"},
+		}
+		for _, c := range obj.Chunks {
+			chunk := lfsObject{Oid: c.Oid, Size: c.Size}
+			if !largeChunks.Has(repo, c.Oid) {
+				chunk.Actions = map[string]lfsLink{
+					"upload": lfsLink{Href: lfsChunkUrl(repo, c.Oid)},
+				}
+			}
+			o.Chunks = append(o.Chunks, chunk)
+		}
+		return
+	}
+
+	by, ok := objectChunks.Get(repo, obj.Oid)
+	if !chunking || !ok {
+		return
+	}
+
+	var chunks []lfsObject
+	if err := json.Unmarshal(by, &chunks); err != nil {
+		log.Fatal(err)
+	}
+	for _, c := range chunks {
+		c.Actions = map[string]lfsLink{
+			"download": lfsLink{Href: lfsChunkUrl(repo, c.Oid)},
+		}
+		o.Chunks = append(o.Chunks, c)
+	}
+}
+
+// handles the verify action of objects uploaded in chunks, by putting the
+// object together from its chunks
+func lfsVerifyHandler(w http.ResponseWriter, r *http.Request, repo string) {
+	obj := &lfsObject{}
+	err := json.NewDecoder(r.Body).Decode(obj)
+	io.Copy(ioutil.Discard, r.Body)
+	r.Body.Close()
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	hash := sha256.New()
+	buf := &bytes.Buffer{}
+	for _, c := range obj.Chunks {
+		by, ok := largeChunks.Get(repo, c.Oid)
+		if !ok {
+			log.Printf("VERIFY: %s is missing chunk %s\n", obj.Oid, c.Oid)
+			w.WriteHeader(422)
+			return
+		}
+		io.Copy(io.MultiWriter(hash, buf), bytes.NewReader(by))
+	}
+
+	if oid := hex.EncodeToString(hash.Sum(nil)); oid != obj.Oid || int64(buf.Len()) != obj.Size {
+		log.Printf("VERIFY: %s put together from %d chunks is %s\n", obj.Oid, len(obj.Chunks), oid)
+		w.WriteHeader(422)
+		return
+	}
+
+	chunks, err := json.Marshal(obj.Chunks)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	largeObjects.Set(repo, obj.Oid, buf.Bytes())
+	objectChunks.Set(repo, obj.Oid, chunks)
+	log.Printf("VERIFY: %s from %d chunks\n", obj.Oid, len(obj.Chunks))
+	w.WriteHeader(200)
+}
+
 // handles any /storage/{oid} requests
 func storageHandler(w http.ResponseWriter, r *http.Request) {
 	repo := r.URL.Query().Get("r")
@@ -343,6 +577,12 @@ func storageHandler(w http.ResponseWriter, r *http.Request) {
 	oid := parts[len(parts)-1]
 
 	log.Printf("storage %s %s repo: %s\n", r.Method, oid, repo)
+
+	storage := largeObjects
+	if len(r.URL.Query().Get("chunk")) > 0 {
+		storage = largeChunks
+	}
+
 	switch r.Method {
 	case "PUT":
 		switch oidHandlers[oid] {
@@ -377,21 +617,41 @@ func storageHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		hash := sha256.New()
+		if len(oid) == sha512.Size*2 {
+			hash = sha512.New()
+		}
 		buf := &bytes.Buffer{}
 		io.Copy(io.MultiWriter(hash, buf), r.Body)
 		oid := hex.EncodeToString(hash.Sum(nil))
+
+		// "checksum" repos require the client to send the SHA-256 of
+		// the content, and "checksum-mismatch" pretends the content was
+		// corrupted on the way.
+		if strings.HasPrefix(repo, "checksum") {
+			checksum := r.Header.Get("X-Content-Sha256")
+			if len(checksum) == 0 {
+				w.WriteHeader(400)
+				return
+			}
+
+			if checksum != oid || repo == "checksum-mismatch" {
+				w.WriteHeader(422)
+				return
+			}
+		}
+
 		if !strings.HasSuffix(r.URL.Path, "/"+oid) {
 			w.WriteHeader(403)
 			return
 		}
 
-		largeObjects.Set(repo, oid, buf.Bytes())
+		storage.Set(repo, oid, buf.Bytes())
 
 	case "GET":
 		parts := strings.Split(r.URL.Path, "/")
 		oid := parts[len(parts)-1]
 
-		if by, ok := largeObjects.Get(repo, oid); ok {
+		if by, ok := storage.Get(repo, oid); ok {
 			w.Write(by)
 			return
 		}
@@ -465,6 +725,21 @@ func redirect307Handler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(307)
 }
 
+// validOid returns whether oid is a hex hash of the given algorithm.
+func validOid(oid, algo string) bool {
+	size := sha256.Size
+	switch algo {
+	case "sha256":
+	case "sha512":
+		size = sha512.Size
+	default:
+		return false
+	}
+
+	_, err := hex.DecodeString(oid)
+	return err == nil && len(oid) == size*2
+}
+
 func testingChunkedTransferEncoding(r *http.Request) bool {
 	return strings.HasPrefix(r.URL.String(), "/test-chunked-transfer-encoding")
 }
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// lfstest-rot13ext is a Git LFS extension that ROT13s files, for both clean
+// and smudge. With --process, it speaks the extension process protocol
+// instead of filtering one file from stdin to stdout. It appends a line with
+// its pid and arguments to $LFSTEST_EXT_LOG each time it starts, and with the
+// headers of each file in process mode.
+func main() {
+	logLine("start " + strings.Join(os.Args[1:], " "))
+
+	if len(os.Args) > 1 && os.Args[1] == "--process" {
+		if err := serve(bufio.NewReader(os.Stdin), os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "lfstest-rot13ext: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lfstest-rot13ext: %s\n", err)
+		os.Exit(1)
+	}
+	os.Stdout.Write(rot13(data))
+}
+
+func serve(r *bufio.Reader, w io.Writer) error {
+	welcome, err := readList(r)
+	if err != nil {
+		return err
+	}
+	if len(welcome) < 1 || welcome[0] != "git-lfs-extension-client" {
+		return fmt.Errorf("unexpected handshake %q", welcome)
+	}
+	writeList(w, "git-lfs-extension-server", "version=1")
+
+	if _, err := readList(r); err != nil {
+		return err
+	}
+	writeList(w, "capability=clean", "capability=smudge")
+
+	for {
+		headers, err := readList(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var content bytes.Buffer
+		for {
+			data, err := readPacket(r)
+			if err != nil {
+				return err
+			}
+			if data == nil {
+				break
+			}
+			content.Write(data)
+		}
+
+		logLine(strings.Join(headers, " "))
+
+		if bytes.Contains(content.Bytes(), []byte("fail")) {
+			writeList(w, "status=error")
+			continue
+		}
+
+		writeList(w, "status=success")
+		writePacket(w, rot13(content.Bytes()))
+		io.WriteString(w, "0000")
+		writeList(w) // keeps the status
+	}
+}
+
+func rot13(data []byte) []byte {
+	out := make([]byte, len(data))
+	for i, b := range data {
+		switch {
+		case b >= 'a' && b <= 'z':
+			b = 'a' + (b-'a'+13)%26
+		case b >= 'A' && b <= 'Z':
+			b = 'A' + (b-'A'+13)%26
+		}
+		out[i] = b
+	}
+	return out
+}
+
+func logLine(line string) {
+	path := os.Getenv("LFSTEST_EXT_LOG")
+	if len(path) == 0 {
+		return
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(f, os.Getpid(), line)
+	f.Close()
+}
+
+func writePacket(w io.Writer, data []byte) {
+	for len(data) > 0 {
+		size := len(data)
+		if size > 65516 {
+			size = 65516
+		}
+		fmt.Fprintf(w, "%04x", size+4)
+		w.Write(data[:size])
+		data = data[size:]
+	}
+}
+
+func writeList(w io.Writer, lines ...string) {
+	for _, line := range lines {
+		writePacket(w, []byte(line+"\n"))
+	}
+	io.WriteString(w, "0000")
+}
+
+func readPacket(r io.Reader) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	size, err := strconv.ParseUint(string(header[:]), 16, 16)
+	if err != nil || size == 0 {
+		return nil, err
+	}
+	data := make([]byte, size-4)
+	_, err = io.ReadFull(r, data)
+	return data, err
+}
+
+func readList(r io.Reader) ([]string, error) {
+	var lines []string
+	for {
+		data, err := readPacket(r)
+		if err != nil {
+			return nil, err
+		}
+		if data == nil {
+			return lines, nil
+		}
+		lines = append(lines, strings.TrimSuffix(string(data), "\n"))
+	}
+}
@@ -39,8 +39,9 @@ begin_test "batch transfer unsupported on server"
 
   refute_server_object "$reponame" "$contents_oid"
 
-  # Ensure batch transfer is turned on for this repo
+  # Ensure batch transfer is turned on for this repo, and allow the fallback
   git config --add --local lfs.batch true
+  git config --add --local lfs.legacyapi true
 
   # This pushes to the remote repository set up at the top of the test.
   git push origin master 2>&1 | tee push.log
@@ -59,3 +60,36 @@ begin_test "batch transfer unsupported on server"
   set -e
 )
 end_test
+
+begin_test "batch transfer unsupported on server without lfs.legacyapi"
+(
+  set -e
+
+  reponame="batchunsupported-nolegacy"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "a" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  set +e
+  git push origin master 2>&1 | tee push.log
+  res="${PIPESTATUS[0]}"
+  set -e
+
+  if [ "$res" = "0" ]; then
+    echo "push successful?"
+    exit 1
+  fi
+
+  grep "does not support the batch API" push.log
+  grep "git config lfs.legacyapi true" push.log
+  [ "1" = "$(grep -c "does not support the batch API" push.log)" ]
+  refute_server_object "$reponame" "$(calc_oid "a")"
+
+  # the batch setting is left alone
+  [ "" = "$(git config lfs.batch)" ]
+)
+end_test
@@ -0,0 +1,90 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "cache: lfs.cache.maxsize evicts least recently used objects"
+(
+  set -e
+
+  reponame="cache_maxsize"
+  setup_remote_repo "remote_$reponame"
+
+  clone_repo "remote_$reponame" "clone_$reponame"
+
+  git lfs track "*.dat" 2>&1 | tee track.log
+  grep "Tracking \*.dat" track.log
+
+  content_old1="Evicted first: pushed, old, and never used"
+  content_old2="Evicted second: pushed and old, but used"
+  content_head1="Keep: needed for HEAD"
+  content_head2="Keep: also needed for HEAD"
+  content_unpushed="Keep: unpushed"
+  oid_old1=$(calc_oid "$content_old1")
+  oid_old2=$(calc_oid "$content_old2")
+  oid_head1=$(calc_oid "$content_head1")
+  oid_head2=$(calc_oid "$content_head2")
+  oid_unpushed=$(calc_oid "$content_unpushed")
+
+  echo "[
+  {
+    \"CommitDate\":\"$(get_date -10d)\",
+    \"Files\":[
+      {\"Filename\":\"file1.dat\",\"Size\":${#content_old1}, \"Data\":\"$content_old1\"},
+      {\"Filename\":\"file2.dat\",\"Size\":${#content_old2}, \"Data\":\"$content_old2\"}]
+  },
+  {
+    \"Files\":[
+      {\"Filename\":\"file1.dat\",\"Size\":${#content_head1}, \"Data\":\"$content_head1\"},
+      {\"Filename\":\"file2.dat\",\"Size\":${#content_head2}, \"Data\":\"$content_head2\"}]
+  }
+  ]" | lfstest-testutils addcommits
+
+  git push origin master
+
+  printf "$content_unpushed" > unpushed.dat
+  git add unpushed.dat
+  git commit -m "unpushed"
+
+  # make both old objects look stored long ago, then use only the second one
+  touch -t 200001010000 ".git/lfs/objects/${oid_old1:0:2}/${oid_old1:2:2}/$oid_old1"
+  touch -t 200001010000 ".git/lfs/objects/${oid_old2:0:2}/${oid_old2:2:2}/$oid_old2"
+  git show HEAD~2:file2.dat | git lfs smudge file2.dat > smudged.log
+  [ "$content_old2" = "$(cat smudged.log)" ]
+
+  # accesses are only recorded with lfs.cache.maxsize
+  [ ! -e .git/lfs/access ]
+  git config lfs.cache.maxsize 1g
+  git show HEAD~2:file2.dat | git lfs smudge file2.dat > smudged.log
+  [ "$content_old2" = "$(cat smudged.log)" ]
+  grep "$oid_old2" .git/lfs/access
+
+  # nothing is evicted without lfs.cache.maxsize
+  git config --unset lfs.cache.maxsize
+  git lfs fetch 2>&1 | tee fetch.log
+  grep "Evicted" fetch.log && exit 1
+  assert_local_object "$oid_old1" "${#content_old1}"
+
+  # room for everything but the least recently used object
+  maxsize=$((${#content_old2} + ${#content_head1} + ${#content_head2} + ${#content_unpushed}))
+  git config lfs.cache.maxsize "$maxsize"
+  git lfs fetch 2>&1 | tee fetch.log
+  grep "Evicted 1 files from the local cache" fetch.log
+  refute_local_object "$oid_old1"
+  assert_local_object "$oid_old2" "${#content_old2}"
+  grep "$oid_old1" .git/lfs/access && exit 1
+
+  # objects needed for HEAD or unpushed commits are kept over the limit
+  git config lfs.cache.maxsize 1k
+  git lfs pull 2>&1 | tee pull.log
+  grep "Evicted" pull.log && exit 1
+  git config lfs.cache.maxsize 10
+  git lfs pull 2>&1 | tee pull.log
+  grep "Evicted 1 files from the local cache" pull.log
+  grep "over lfs.cache.maxsize" pull.log
+  refute_local_object "$oid_old2"
+  assert_local_object "$oid_head1" "${#content_head1}"
+  assert_local_object "$oid_head2" "${#content_head2}"
+  assert_local_object "$oid_unpushed" "${#content_unpushed}"
+  [ "$content_head1" = "$(cat file1.dat)" ]
+)
+end_test
@@ -0,0 +1,112 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "capabilities probe"
+(
+  set -e
+
+  reponame="$(basename "$0" ".sh")"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" repo
+
+  git lfs track "*.dat"
+  printf "a" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  # probing is off by default
+  GIT_TRACE=1 git push origin master 2>&1 | tee push.log
+  [ "0" = "$(grep -c "capabilities: probing" push.log)" ]
+  [ ! -e .git/lfs/capabilities.json ]
+
+  git config lfs.probecapabilities true
+
+  # env only shows cached capabilities, without probing
+  GIT_TRACE=1 git lfs env 2>&1 | tee env.log
+  grep "  Capabilities=unknown" env.log
+  [ "0" = "$(grep -c "capabilities: probing" env.log)" ]
+
+  printf "b" > b.dat
+  git add b.dat
+  git commit -m "add b.dat"
+
+  GIT_TRACE=1 git push origin master 2>&1 | tee push.log
+  grep "capabilities: probing" push.log
+  grep "tq: running as batched queue, batch size of 50" push.log
+  grep "$GITSERVER/$reponame.git/info/lfs" .git/lfs/capabilities.json
+  assert_server_object "$reponame" "$(calc_oid "b")"
+
+  # the probe is cached
+  printf "c" > c.dat
+  git add c.dat
+  git commit -m "add c.dat"
+
+  GIT_TRACE=1 git push origin master 2>&1 | tee push.log
+  [ "0" = "$(grep -c "capabilities: probing" push.log)" ]
+  grep "tq: running as batched queue, batch size of 50" push.log
+
+  git lfs env 2>&1 | tee env.log
+  grep "  Batch=true" env.log
+  grep "  Operations=upload,download,delete" env.log
+  grep "  Transfers=basic" env.log
+  grep "  HashAlgorithms=sha256" env.log
+  grep "  Locking=false" env.log
+  grep "  MaxBatchSize=50" env.log
+  grep "  ContentEncodings=$" env.log
+)
+end_test
+
+begin_test "capabilities probe without batch support"
+(
+  set -e
+
+  reponame="capabilities-legacy"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" capabilities-legacy
+
+  git config lfs.probecapabilities true
+  git config lfs.legacyapi true
+  git lfs track "*.dat"
+  printf "legacy" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  GIT_TRACE=1 git push origin master 2>&1 | tee push.log
+  grep "tq: server does not support the batch api" push.log
+  grep "tq: running as individual queue" push.log
+  [ "0" = "$(grep -c "api: batch" push.log)" ]
+  assert_server_object "$reponame" "$(calc_oid "legacy")"
+
+  # the batch setting is left alone
+  [ "" = "$(git config lfs.batch)" ]
+)
+end_test
+
+begin_test "capabilities probe with gzipped batch requests"
+(
+  set -e
+
+  reponame="capabilities-gzip"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" capabilities-gzip
+
+  git config lfs.probecapabilities true
+  git lfs track "*.dat"
+  for i in $(seq 1 10); do
+    printf "gzip $i" > "$i.dat"
+  done
+  git add .gitattributes *.dat
+  git commit -m "add 10 files"
+
+  GIT_TRACE=1 git push origin master 2>&1 | tee push.log
+  grep "api: gzipped batch request" push.log
+  # the gzipped response is traced after it's decompressed
+  grep 'HTTP: {"objects":' push.log
+  assert_server_object "$reponame" "$(calc_oid "gzip 1")"
+  assert_server_object "$reponame" "$(calc_oid "gzip 10")"
+
+  git lfs env 2>&1 | tee env.log
+  grep "  ContentEncodings=gzip" env.log
+)
+end_test
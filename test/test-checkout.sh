@@ -119,3 +119,69 @@ begin_test "checkout: outside git repository"
   grep "Not in a git repository" checkout.log
 )
 end_test
+
+begin_test "checkout: lfs.checkout.linkmode"
+(
+  set -e
+
+  reponame="$(basename "$0" ".sh")-linkmode"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  contents="linked"
+  oid="$(calc_oid "$contents")"
+  printf "$contents" > a.dat
+  printf "$contents" > b.dat
+  printf "$contents" > exec.dat
+  chmod +x exec.dat
+  git add .gitattributes a.dat b.dat exec.dat
+  git commit -m "add files"
+
+  object=".git/lfs/objects/${oid:0:2}/${oid:2:2}/$oid"
+  inode() {
+    ls -i "$1" | awk '{print $1}'
+  }
+
+  # hard links share the stored object, which is made read-only
+  git config lfs.checkout.linkmode hardlink
+  rm a.dat b.dat
+  git show HEAD:exec.dat > exec.dat
+  git lfs checkout
+  [ "$contents" = "$(cat a.dat)" ]
+  [ "$contents" = "$(cat b.dat)" ]
+  [ "$(inode "$object")" = "$(inode a.dat)" ]
+  [ "$(inode "$object")" = "$(inode b.dat)" ]
+  [ ! -w "$object" ] || [ "$(id -u)" = "0" ]
+  [ "" = "$(git status --porcelain -- "*.dat")" ]
+
+  # executable files are copied, since the mode would be shared
+  [ "$(inode "$object")" != "$(inode exec.dat)" ]
+  [ -x exec.dat ]
+
+  # reflinks fall back to copies on filesystems without them
+  git config lfs.checkout.linkmode reflink
+  rm b.dat
+  git lfs checkout b.dat
+  [ "$contents" = "$(cat b.dat)" ]
+  [ "$(inode "$object")" != "$(inode b.dat)" ]
+  git lfs fsck
+
+  # editing a hard linked file in place changes the stored object, which is
+  # then refused by push and moved aside by fsck
+  chmod u+w a.dat
+  printf "edited" >> a.dat
+  set +e
+  git lfs push origin master 2>&1 | tee push.log
+  res="${PIPESTATUS[0]}"
+  set -e
+  [ "$res" != "0" ]
+  grep "Error uploading file a.dat ($oid): the object was changed through a hard link" push.log
+  refute_server_object "$reponame" "$oid"
+
+  git lfs fsck 2>&1 | tee fsck.log
+  grep "Object a.dat ($oid) is corrupt" fsck.log
+  grep "it is hard linked" fsck.log
+  [ ! -e "$object" ]
+)
+end_test
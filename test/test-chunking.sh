@@ -0,0 +1,138 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "chunking: similar versions only transfer the chunks that changed"
+(
+  set -e
+
+  reponame="chunked-versions"
+  setup_remote_repo "$reponame"
+
+  clone_repo "$reponame" "$reponame"
+  git config lfs.chunking true
+
+  git lfs track "*.bin"
+  head -c 12582912 /dev/urandom > asset.bin
+  cp asset.bin ../asset-v1.bin
+  oid1=$(shasum -a 256 asset.bin | cut -f 1 -d " ")
+  git add .gitattributes asset.bin
+  git commit -m "add asset"
+
+  objects=".git/lfs/objects"
+  [ -f "$objects/${oid1:0:2}/${oid1:2:2}/$oid1.chunks" ]
+  [ ! -e "$objects/${oid1:0:2}/${oid1:2:2}/$oid1" ]
+  chunks=$(wc -l < "$objects/${oid1:0:2}/${oid1:2:2}/$oid1.chunks" | tr -d ' ')
+  [ "$chunks" -gt 2 ]
+  [ "Git LFS fsck OK" = "$(git lfs fsck)" ]
+
+  GIT_TRACE=1 git push origin master 2>&1 | tee push.log
+  grep "chunking: uploaded $chunks of $chunks chunks of $oid1" push.log
+  assert_server_object "$reponame" "$oid1"
+
+  # change a few bytes in the middle
+  printf "edited" | dd of=asset.bin bs=1 seek=6000000 conv=notrunc
+  cp asset.bin ../asset-v2.bin
+  oid2=$(shasum -a 256 asset.bin | cut -f 1 -d " ")
+  git commit -am "edit asset"
+
+  GIT_TRACE=1 git push origin master 2>&1 | tee push.log
+  grep "chunking: uploaded 1 of [0-9]* chunks of $oid2" push.log
+  assert_server_object "$reponame" "$oid2"
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 clone_repo "$reponame" "$reponame-clone"
+  git config lfs.chunking true
+  git branch v1 HEAD~1
+
+  GIT_TRACE=1 git lfs fetch origin v1 2>&1 | tee fetch.log
+  grep "chunking: downloaded $chunks of $chunks chunks of $oid1" fetch.log
+
+  GIT_TRACE=1 git lfs pull 2>&1 | tee pull.log
+  grep "chunking: downloaded 1 of [0-9]* chunks of $oid2" pull.log
+  cmp asset.bin ../asset-v2.bin
+
+  git checkout HEAD~1 -- asset.bin
+  cmp asset.bin ../asset-v1.bin
+
+  # objects with a corrupt chunk are moved aside by fsck, and the chunk is
+  # downloaded again
+  chunk=$(head -n 1 "$objects/${oid2:0:2}/${oid2:2:2}/$oid2.chunks" | cut -f 1 -d " ")
+  chunkfile=".git/lfs/chunks/${chunk:0:2}/${chunk:2:2}/$chunk"
+  printf "corrupt" | dd of="$chunkfile" bs=1 seek=10 conv=notrunc
+  git lfs fsck 2>&1 | tee fsck.log
+  grep "Object asset.bin ($oid1) is corrupt" fsck.log
+  grep "Object asset.bin ($oid2) is corrupt" fsck.log
+  [ -f ".git/lfs/bad/$oid1.chunks" ]
+  [ -f ".git/lfs/bad/$oid2.chunks" ]
+
+  GIT_TRACE=1 git lfs fetch origin v1 2>&1 | tee fetch.log
+  grep "chunking: downloaded 1 of $chunks chunks of $oid1" fetch.log
+  GIT_TRACE=1 git lfs fetch 2>&1 | tee fetch.log
+  grep "chunking: downloaded 0 of [0-9]* chunks of $oid2" fetch.log
+  [ "Git LFS fsck OK" = "$(git lfs fsck)" ]
+)
+end_test
+
+begin_test "chunking: objects are pruned with their unshared chunks"
+(
+  set -e
+
+  reponame="chunked-prune"
+  setup_remote_repo "$reponame"
+
+  clone_repo "$reponame" "$reponame"
+  git config lfs.chunking true
+  git config lfs.fetchrecentrefsdays 0
+  git config lfs.fetchrecentcommitsdays 0
+  git config lfs.pruneoffsetdays 0
+
+  git lfs track "*.bin"
+  head -c 12582912 /dev/urandom > asset.bin
+  git add .gitattributes asset.bin
+  git commit -m "add asset"
+  printf "edited" | dd of=asset.bin bs=1 seek=6000000 conv=notrunc
+  git commit -am "edit asset"
+  oid=$(shasum -a 256 asset.bin | cut -f 1 -d " ")
+  git push origin master
+
+  before=$(find .git/lfs/chunks -type f | wc -l)
+  git lfs prune 2>&1 | tee prune.log
+  grep "Pruning 1 files" prune.log
+  after=$(find .git/lfs/chunks -type f | wc -l)
+  [ "$after" -eq "$((before - 1))" ]
+
+  rm asset.bin
+  git checkout -- asset.bin
+  [ "$oid" = "$(shasum -a 256 asset.bin | cut -f 1 -d " ")" ]
+)
+end_test
+
+begin_test "chunking: objects are transferred whole with servers without chunking"
+(
+  set -e
+
+  reponame="chunking-unsupported"
+  setup_remote_repo "$reponame"
+
+  clone_repo "$reponame" "$reponame"
+  git config lfs.chunking true
+
+  git lfs track "*.bin"
+  head -c 6291456 /dev/urandom > asset.bin
+  oid=$(shasum -a 256 asset.bin | cut -f 1 -d " ")
+  git add .gitattributes asset.bin
+  git commit -m "add asset"
+
+  GIT_TRACE=1 git push origin master 2>&1 | tee push.log
+  [ "0" -eq "$(grep -c "chunking: uploaded" push.log)" ]
+  assert_server_object "$reponame" "$oid"
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 clone_repo "$reponame" "$reponame-clone"
+  git config lfs.chunking true
+  git lfs pull
+  [ "$oid" = "$(shasum -a 256 asset.bin | cut -f 1 -d " ")" ]
+  [ -f ".git/lfs/objects/${oid:0:2}/${oid:2:2}/$oid.chunks" ]
+)
+end_test
@@ -0,0 +1,71 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "compression: objects are stored compressed with lfs.compression"
+(
+  set -e
+
+  reponame="compression"
+  setup_remote_repo "$reponame"
+
+  clone_repo "$reponame" "$reponame"
+  git config lfs.compression gzip
+
+  git lfs track "*.dat"
+  contents=$(printf 'compress me %.0s' $(seq 1 500))
+  contents_oid=$(calc_oid "$contents")
+  random=$(head -c 300 /dev/urandom | base64 | tr -d '\n')
+  random_oid=$(calc_oid "$random")
+  printf "$contents" > compressed.dat
+  printf "$random" > raw.dat
+  git add .gitattributes *.dat
+  git commit -m "add files"
+
+  objects=".git/lfs/objects"
+  compressed="$objects/${contents_oid:0:2}/${contents_oid:2:2}/$contents_oid"
+  raw="$objects/${random_oid:0:2}/${random_oid:2:2}/$random_oid"
+  [ -f "$compressed.gz" ]
+  [ ! -e "$compressed" ]
+  [ "$contents" = "$(gunzip -c "$compressed.gz")" ]
+
+  # content that doesn't get smaller is stored raw
+  [ -f "$raw" ]
+  [ ! -e "$raw.gz" ]
+
+  rm compressed.dat
+  git checkout -- compressed.dat
+  [ "$contents" = "$(cat compressed.dat)" ]
+
+  git lfs ls-files | grep "compressed.dat"
+  [ "Git LFS fsck OK" = "$(git lfs fsck)" ]
+
+  # the uncompressed content is pushed
+  git push origin master 2>&1 | tee push.log
+  grep "(2 of 2 files)" push.log
+  assert_server_object "$reponame" "$contents_oid"
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 clone_repo "$reponame" "$reponame-pull"
+  git config lfs.compression gzip
+  git lfs pull 2>&1 | tee pull.log
+  [ -f "$compressed.gz" ]
+  [ ! -e "$compressed" ]
+  [ "$contents" = "$(cat compressed.dat)" ]
+  [ "$random" = "$(cat raw.dat)" ]
+
+  # existing objects are still read after compression is turned off
+  git config lfs.compression none
+  rm compressed.dat
+  git lfs checkout compressed.dat
+  [ "$contents" = "$(cat compressed.dat)" ]
+
+  # corrupt compressed objects are moved aside by fsck
+  head -c 120 "$compressed.gz" > truncated.gz
+  mv truncated.gz "$compressed.gz"
+  git lfs fsck 2>&1 | tee fsck.log
+  grep "Object compressed.dat ($contents_oid) is corrupt" fsck.log
+  [ -f ".git/lfs/bad/$contents_oid.gz" ]
+  [ ! -e "$compressed.gz" ]
+)
+end_test
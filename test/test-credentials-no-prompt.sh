@@ -5,6 +5,22 @@
 # these tests rely on GIT_TERMINAL_PROMPT to test properly
 ensure_git_version_isnt $VERSION_LOWER "2.3.0"
 
+# write_askpass writes an askpass program that logs its prompt, and answers
+# with the credentials accepted by lfstest-gitserver.
+write_askpass() {
+  local askpass="$1"
+  local log="$(dirname "$askpass")/askpass.log"
+  cat > "$askpass" <<-EOF
+	#!/bin/sh
+	echo "\$1" >> "$log"
+	case "\$1" in
+	  Username*) echo "user" ;;
+	  Password*) echo "pass" ;;
+	esac
+	EOF
+  chmod +x "$askpass"
+}
+
 begin_test "attempt private access without credential helper"
 (
   set -e
@@ -27,3 +43,72 @@ begin_test "attempt private access without credential helper"
   grep "Git credentials for $GITSERVER/$reponame not found" push.log
 )
 end_test
+
+begin_test "askpass: push with GIT_ASKPASS"
+(
+  set -e
+
+  reponame="askpass-git-askpass"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" askpass-git-askpass
+
+  git lfs track "*.dat"
+  echo "hi" > hi.dat
+  git add hi.dat
+  git add .gitattributes
+  git commit -m "initial commit"
+
+  git config --unset credential.helper
+  git config --global --unset credential.helper
+
+  write_askpass "$(pwd)/askpass.sh"
+
+  GIT_TRACE=1 GIT_TERMINAL_PROMPT=0 GIT_ASKPASS="$(pwd)/askpass.sh" SSH_ASKPASS="" \
+    git push origin master 2>&1 | tee push.log
+
+  grep "(1 of 1 files)" push.log
+  grep "creds: prompting with $(pwd)/askpass.sh" push.log
+  grep "Username for 'http://127.0.0.1" askpass.log
+  grep "Password for 'http://user@127.0.0.1" askpass.log
+)
+end_test
+
+begin_test "askpass: push with core.askpass and SSH_ASKPASS"
+(
+  set -e
+
+  reponame="askpass-core-askpass"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" askpass-core-askpass
+
+  git lfs track "*.dat"
+  echo "hi" > hi.dat
+  git add hi.dat
+  git add .gitattributes
+  git commit -m "initial commit"
+
+  git config --unset credential.helper
+  git config --global --unset credential.helper
+
+  write_askpass "$(pwd)/askpass.sh"
+  git config core.askpass "$(pwd)/askpass.sh"
+
+  GIT_TERMINAL_PROMPT=0 GIT_ASKPASS="" SSH_ASKPASS="/bin/false" \
+    git push origin master 2>&1 | tee push.log
+
+  grep "(1 of 1 files)" push.log
+  grep "Password for" askpass.log
+
+  git config --unset core.askpass
+  echo "more" > more.dat
+  git add more.dat
+  git commit -m "add more.dat"
+  rm askpass.log
+
+  GIT_TERMINAL_PROMPT=0 GIT_ASKPASS="" SSH_ASKPASS="$(pwd)/askpass.sh" \
+    git push origin master 2>&1 | tee push.log
+
+  grep "(1 of 1 files)" push.log
+  grep "Password for" askpass.log
+)
+end_test
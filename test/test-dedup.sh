@@ -0,0 +1,68 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "dedup"
+(
+  set -e
+
+  reponame="dedup"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs track "*.dat"
+  printf "same" > a.dat
+  printf "different" > b.dat
+  printf "deleted" > c.dat
+  git add .gitattributes *.dat
+  git commit -m "add files"
+
+  printf "modified" > b.dat
+  rm c.dat
+
+  if cp --reflink=always a.dat reflink-probe 2> /dev/null; then
+    rm reflink-probe
+    git lfs dedup 2>&1 | tee dedup.log
+    grep "Deduplicated 1 files, reclaimed 4 B" dedup.log
+    grep "Skipped 1 files with local modifications" dedup.log
+    grep "Skipped 1 missing files" dedup.log
+
+    # files that already share their storage aren't counted again
+    git lfs dedup 2>&1 | tee dedup.log
+    grep "Deduplicated 0 files, reclaimed 0 B" dedup.log
+    grep "Skipped 1 files that already share their storage" dedup.log
+  else
+    # without reflinks, nothing is changed
+    set +e
+    git lfs dedup > dedup.log 2>&1
+    res=$?
+    set -e
+    cat dedup.log
+    [ "$res" = "2" ]
+    grep "Could not dedup a.dat" dedup.log
+    grep "copy-on-write" dedup.log
+  fi
+
+  [ "same" = "$(cat a.dat)" ]
+  [ "modified" = "$(cat b.dat)" ]
+  [ " M b.dat
+ D c.dat" = "$(git status --porcelain -- "*.dat")" ]
+  [ -z "$(ls -A | grep lfs-dedup)" ]
+)
+end_test
+
+begin_test "dedup: outside git repository"
+(
+  set +e
+  git lfs dedup 2>&1 > dedup.log
+  res=$?
+
+  set -e
+  if [ "$res" = "0" ]; then
+    echo "Passes because $GIT_LFS_TEST_DIR is unset."
+    exit 0
+  fi
+  [ "$res" = "128" ]
+  grep "Not in a git repository" dedup.log
+)
+end_test
@@ -60,3 +60,120 @@ begin_test "ext"
   [ "$actual" = "$expected" ]
 )
 end_test
+
+begin_test "ext process"
+(
+  set -e
+
+  mkdir ext-process
+  cd ext-process
+  git init
+
+  git config lfs.extension.rot13.clean "lfstest-rot13ext clean %f"
+  git config lfs.extension.rot13.smudge "lfstest-rot13ext smudge %f"
+  git config lfs.extension.rot13.priority 0
+  git config lfs.extension.rot13.process "lfstest-rot13ext --process"
+
+  expected="Extension: rot13
+    clean = lfstest-rot13ext clean %f
+    smudge = lfstest-rot13ext smudge %f
+    priority = 0
+    process = lfstest-rot13ext --process"
+
+  [ "$expected" = "$(git lfs ext list rot13)" ]
+
+  git lfs track "*.dat"
+  printf "abc" > a.dat
+  printf "def" > b.dat
+  printf "snvy" > c.dat
+  git add .gitattributes a.dat b.dat c.dat
+  git commit -m "add files"
+
+  oid=$(printf "nop" | shasum -a 256 | cut -f 1 -d " ")
+  git cat-file -p HEAD:a.dat | grep "ext-0-rot13 sha256:"
+  [ "nop" = "$(cat .git/lfs/objects/${oid:0:2}/${oid:2:2}/$oid)" ]
+
+  # one process smudges every file
+  rm a.dat b.dat c.dat
+  export LFSTEST_EXT_LOG="$(pwd)/../ext-process.log"
+  git lfs checkout
+  [ "3" = "$(grep -c "command=smudge" "$LFSTEST_EXT_LOG")" ]
+  [ "1" = "$(grep "command=smudge" "$LFSTEST_EXT_LOG" | cut -f 1 -d " " | sort -u | wc -l | tr -d " ")" ]
+  grep "command=smudge pathname=a.dat$" "$LFSTEST_EXT_LOG"
+  grep "command=smudge pathname=b.dat$" "$LFSTEST_EXT_LOG"
+  [ "abc" = "$(cat a.dat)" ]
+  [ "def" = "$(cat b.dat)" ]
+
+  # the extension fails to smudge c.dat, and keeps running
+  git lfs logs last | grep "Extension 'rot13' failed to smudge c.dat"
+  [ ! -s c.dat ]
+
+  # without a process, the extension runs for every file
+  git config --unset lfs.extension.rot13.process
+  rm a.dat b.dat c.dat "$LFSTEST_EXT_LOG"
+  git lfs checkout
+  [ "0" = "$(grep -c "command=smudge" "$LFSTEST_EXT_LOG")" ]
+  [ "3" = "$(grep -c "start smudge" "$LFSTEST_EXT_LOG")" ]
+  [ "abc" = "$(cat a.dat)" ]
+  [ "def" = "$(cat b.dat)" ]
+  [ "snvy" = "$(cat c.dat)" ]
+)
+end_test
+
+begin_test "ext lfs-ext attribute"
+(
+  set -e
+
+  mkdir ext-attribute
+  cd ext-attribute
+  git init
+
+  git config lfs.extension.rot13.clean "lfstest-rot13ext clean %f"
+  git config lfs.extension.rot13.smudge "lfstest-rot13ext smudge %f"
+  git config lfs.extension.rot13.priority 0
+  git config lfs.extension.upper.clean "tr a-z A-Z"
+  git config lfs.extension.upper.smudge "tr A-Z a-z"
+  git config lfs.extension.upper.priority 1
+
+  git lfs track "*.dat" "*.txt" "*.zip"
+  echo "*.txt lfs-ext=rot13" >> .gitattributes
+  echo "*.zip -lfs-ext" >> .gitattributes
+
+  printf "abc" > a.dat
+  printf "abc" > a.txt
+  printf "abc" > a.zip
+  git add .gitattributes a.dat a.txt a.zip
+  git commit -m "add files"
+
+  git cat-file -p HEAD:a.dat | grep "ext-0-rot13"
+  git cat-file -p HEAD:a.dat | grep "ext-1-upper"
+  git cat-file -p HEAD:a.txt | grep "ext-0-rot13"
+  [ "0" = "$(git cat-file -p HEAD:a.txt | grep -c "upper")" ]
+  [ "0" = "$(git cat-file -p HEAD:a.zip | grep -c "ext-")" ]
+
+  expected="Extension: rot13
+    clean = lfstest-rot13ext clean %f
+    smudge = lfstest-rot13ext smudge %f
+    priority = 0"
+  [ "$expected" = "$(git lfs ext list a.txt)" ]
+  [ "No extensions apply to a.zip" = "$(git lfs ext list a.zip)" ]
+  [ "$expected" = "$(git lfs ext list rot13)" ]
+
+  rm a.dat a.txt a.zip
+  git lfs checkout
+  [ "abc" = "$(cat a.dat)" ]
+  [ "abc" = "$(cat a.txt)" ]
+  [ "abc" = "$(cat a.zip)" ]
+
+  # extensions in the attribute must be configured
+  echo "*.bin filter=lfs diff=lfs merge=lfs -text lfs-ext=missing" >> .gitattributes
+  printf "abc" > a.bin
+  [ "Extension 'missing' is not configured in the lfs-ext attribute of a.bin" = "$(git lfs ext list a.bin)" ]
+
+  set +e
+  git add a.bin
+  status=$?
+  set -e
+  [ "0" != "$status" ]
+)
+end_test
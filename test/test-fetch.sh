@@ -76,7 +76,9 @@ begin_test "fetch"
   git checkout master
   rm -rf .git/lfs/objects
 
-  git lfs fetch origin master newbranch
+  GIT_TRACE=1 git lfs fetch origin master newbranch 2>&1 | tee fetch.log
+  grep "api: batch 1 files for refs/heads/master" fetch.log
+  grep "api: batch 1 files for refs/heads/newbranch" fetch.log
   assert_local_object "$contents_oid" 1
   assert_local_object "$b_oid" 1
 
@@ -282,8 +284,11 @@ begin_test "fetch-recent"
   refute_local_object "$oid4"
   # pick up just snapshot at remote ref, ie #4
   git config lfs.fetchrecentremoterefs true
-  git lfs fetch --recent origin
+  GIT_TRACE=1 git lfs fetch --recent origin 2>&1 | tee fetch.log
   assert_local_object "$oid4" "${#content4}"
+  # the server is sent the branch's name on the remote
+  grep "api: batch 1 files for refs/heads/other_branch" fetch.log
+  [ "0" = "$(grep -c "refs/remotes/" fetch.log)" ]
   refute_local_object "$oid0"
   refute_local_object "$oid1"
 
@@ -485,6 +490,48 @@ begin_test "fetch with no origin remote"
 )
 end_test
 
+begin_test "fetch with fallback remotes"
+(
+  set -e
+
+  reponame="fetch-fallback-remotes"
+  setup_remote_repo "$reponame"
+  setup_remote_repo "$reponame-mirror"
+
+  clone_repo "$reponame" fallback-repo
+
+  git lfs track "*.dat" 2>&1 | tee track.log
+  grep "Tracking \*.dat" track.log
+
+  contents="fallback"
+  contents_oid=$(calc_oid "$contents")
+
+  printf "$contents" > a.dat
+  git add a.dat .gitattributes
+  git commit -m "add a.dat" 2>&1 | tee commit.log
+  grep "master (root-commit)" commit.log
+
+  git push origin master 2>&1 | tee push.log
+  grep "(1 of 1 files)" push.log
+  assert_server_object "$reponame" "$contents_oid"
+
+  # the mirror has the commits, but none of the objects
+  git remote add mirror "$GITSERVER/$reponame-mirror"
+  refute_server_object "$reponame-mirror" "$contents_oid"
+
+  rm -rf .git/lfs/objects
+  git lfs fetch mirror 2>&1 | tee fetch.log
+  grep "does not exist" fetch.log
+  refute_local_object "$contents_oid"
+
+  git config lfs.fetchremotes origin
+  GIT_TRACE=1 git lfs fetch mirror 2>&1 | tee fetch.log
+  grep "fetch $contents_oid from origin" fetch.log
+  [ "0" = "$(grep -c "errors occurred" fetch.log)" ]
+  assert_local_object "$contents_oid" 8
+)
+end_test
+
 begin_test "fetch --prune"
 (
   set -e
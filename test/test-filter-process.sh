@@ -0,0 +1,80 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "filter process"
+(
+  set -e
+
+  mkdir filter-process
+  cd filter-process
+  git init
+  git config filter.lfs.process "git-lfs filter-process"
+
+  git lfs track "*.dat"
+  for i in $(seq 1 50); do
+    printf "content $i" > "$i.dat"
+  done
+
+  # one process cleans every file
+  GIT_TRACE=1 git add .gitattributes *.dat 2>&1 | tee trace.log
+  [ "1" = "$(grep -c "git-lfs filter-process" trace.log)" ]
+  grep "git-lfs clean" trace.log && exit 1
+  git commit -m "add files"
+
+  for i in 1 25 50; do
+    content="content $i"
+    oid="$(calc_oid "$content")"
+    git cat-file -p "HEAD:$i.dat" | grep "oid sha256:$oid"
+    assert_local_object "$oid" "${#content}"
+  done
+
+  # and smudges them
+  rm *.dat
+  GIT_TRACE=1 git checkout -- . 2>&1 | tee trace.log
+  [ "1" = "$(grep -c "git-lfs filter-process" trace.log)" ]
+  grep "git-lfs smudge" trace.log && exit 1
+  [ "content 1" = "$(cat 1.dat)" ]
+  [ "content 50" = "$(cat 50.dat)" ]
+  [ "" = "$(git status --porcelain -- "*.dat")" ]
+)
+end_test
+
+begin_test "filter process with an extension process"
+(
+  set -e
+
+  mkdir filter-process-ext
+  cd filter-process-ext
+  git init
+  git config filter.lfs.process "git-lfs filter-process"
+  git config lfs.extension.rot13.clean "lfstest-rot13ext clean %f"
+  git config lfs.extension.rot13.smudge "lfstest-rot13ext smudge %f"
+  git config lfs.extension.rot13.priority 0
+  git config lfs.extension.rot13.process "lfstest-rot13ext --process"
+
+  git lfs track "*.dat"
+  for i in $(seq 1 20); do
+    printf "abc $i" > "$i.dat"
+  done
+
+  # the extension is started once for every file git adds
+  export LFSTEST_EXT_LOG="$(pwd)/../filter-process-ext.log"
+  git add .gitattributes *.dat
+  [ "1" = "$(grep -c "start --process" "$LFSTEST_EXT_LOG")" ]
+  [ "20" = "$(grep -c "command=clean" "$LFSTEST_EXT_LOG")" ]
+  git commit -m "add files"
+
+  oid="$(calc_oid "nop 1")"
+  git cat-file -p HEAD:1.dat | grep "ext-0-rot13 sha256:"
+  [ "nop 1" = "$(cat ".git/lfs/objects/${oid:0:2}/${oid:2:2}/$oid")" ]
+
+  # and for every file git checks out
+  rm *.dat "$LFSTEST_EXT_LOG"
+  git checkout -- .
+  [ "1" = "$(grep -c "start --process" "$LFSTEST_EXT_LOG")" ]
+  [ "20" = "$(grep -c "command=smudge" "$LFSTEST_EXT_LOG")" ]
+  [ "abc 1" = "$(cat 1.dat)" ]
+  [ "abc 20" = "$(cat 20.dat)" ]
+)
+end_test
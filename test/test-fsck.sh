@@ -104,6 +104,41 @@ begin_test "fsck dry run"
 )
 end_test
 
+begin_test "fsck --pointers"
+(
+  set -e
+
+  reponame="fsck-pointers"
+  git init $reponame
+  cd $reponame
+
+  git lfs track "*.dat"
+  echo "test data" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "first commit"
+
+  [ "Git LFS fsck OK" = "$(git lfs fsck --pointers)" ]
+
+  # a pointer written by another tool, committed without the clean filter
+  aOid=$(git log --patch a.dat | grep "^+oid" | cut -d ":" -f 2)
+  printf "version https://git-lfs.github.com/spec/v1\r\noid sha256:%s\r\nsize 10\r\n" "$aOid" > b.txt
+  git add b.txt
+  git commit -m "add non-canonical pointer"
+  bSha=$(git rev-parse HEAD:b.txt)
+
+  git rm b.txt
+  git commit -m "remove non-canonical pointer"
+
+  [ "Git LFS fsck OK" = "$(git lfs fsck)" ]
+
+  expected="Pointer b.txt ($bSha) is not canonical:
+  line 1: line ends with a carriage return
+  line 2: line ends with a carriage return
+  line 3: line ends with a carriage return"
+  [ "$expected" = "$(git lfs fsck --pointers)" ]
+)
+end_test
+
 begin_test "fsck: outside git repository"
 (
   set +e
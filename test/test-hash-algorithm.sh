@@ -0,0 +1,77 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "hash algorithm: sha512 objects are stored and transferred"
+(
+  set -e
+
+  reponame="hash-algorithm-sha512"
+  setup_remote_repo "$reponame"
+
+  clone_repo "$reponame" "$reponame"
+  git config lfs.hashalgorithm sha512
+
+  git lfs track "*.dat"
+  printf "sha512 content" > a.dat
+  oid=$(shasum -a 512 a.dat | cut -f 1 -d " ")
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  pointer="$(git cat-file -p :a.dat)"
+  expected="version https://git-lfs.github.com/spec/v2
+oid sha512:$oid
+size 14"
+  [ "$expected" = "$pointer" ]
+
+  [ -f ".git/lfs/objects/sha512/${oid:0:2}/${oid:2:2}/$oid" ]
+  [ ! -e ".git/lfs/objects/${oid:0:2}/${oid:2:2}/$oid" ]
+  [ "sha512:${oid:0:10} * a.dat" = "$(git lfs ls-files)" ]
+  [ "Git LFS fsck OK" = "$(git lfs fsck)" ]
+
+  # sha256 objects are still stored as before
+  git config --unset lfs.hashalgorithm
+  printf "sha256 content" > b.dat
+  oid256=$(shasum -a 256 b.dat | cut -f 1 -d " ")
+  git add b.dat
+  git commit -m "add b.dat"
+  [ -f ".git/lfs/objects/${oid256:0:2}/${oid256:2:2}/$oid256" ]
+  git cat-file -p :b.dat | grep "version https://git-lfs.github.com/spec/v1"
+
+  git push origin master 2>&1 | tee push.log
+  grep "(2 of 2 files)" push.log
+  assert_server_object "$reponame" "$oid"
+  assert_server_object "$reponame" "$oid256"
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 clone_repo "$reponame" "$reponame-clone"
+  git lfs pull
+  [ "sha512 content" = "$(cat a.dat)" ]
+  [ "sha256 content" = "$(cat b.dat)" ]
+  [ -f ".git/lfs/objects/sha512/${oid:0:2}/${oid:2:2}/$oid" ]
+  [ "Git LFS fsck OK" = "$(git lfs fsck)" ]
+
+  rm a.dat
+  git checkout -- a.dat
+  [ "sha512 content" = "$(cat a.dat)" ]
+)
+end_test
+
+begin_test "hash algorithm: v1 pointers only have sha256 oids"
+(
+  set -e
+
+  reponame="hash-algorithm-v1"
+  git init "$reponame"
+  cd "$reponame"
+
+  oid=$(printf "abc" | shasum -a 512 | cut -f 1 -d " ")
+  printf "version https://git-lfs.github.com/spec/v1
+oid sha512:$oid
+size 3
+" > pointer.txt
+
+  git lfs pointer --pointer=pointer.txt 2>&1 | tee pointer.log
+  grep "only sha256 OIDs are allowed in v1 pointers" pointer.log
+)
+end_test
@@ -0,0 +1,37 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "metrics"
+(
+  set -e
+
+  reponame="$(basename "$0" ".sh")"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" repo
+
+  git lfs track "*.dat"
+  printf "metrics" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  # metrics are off by default
+  GIT_TRACE=1 git push origin master 2>&1 | tee push.log
+  grep "(1 of 1 files)" push.log
+  [ "0" = "$(grep -c "metrics:" push.log)" ]
+
+  endpoint="$GITSERVER/$reponame.git/info/lfs"
+  git config "lfs.$endpoint.metrics" true
+  rm -rf .git/lfs/objects
+
+  GIT_TRACE=1 git lfs fetch 2>&1 | tee fetch.log
+  grep "metrics: sending 2 transfer keys" fetch.log
+  grep "HTTP: POST $endpoint/metrics" fetch.log
+  [ "0" = "$(grep -c "error sending metrics" fetch.log)" ]
+
+  # lfs.metrics=false turns them off for every endpoint
+  rm -rf .git/lfs/objects
+  GIT_TRACE=1 git -c lfs.metrics=false lfs fetch 2>&1 | tee fetch.log
+  [ "0" = "$(grep -c "metrics:" fetch.log)" ]
+)
+end_test
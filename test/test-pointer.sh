@@ -268,3 +268,70 @@ begin_test "pointer without args"
   [ "1" = "$status" ]
 )
 end_test
+
+begin_test "pointer --check"
+(
+  set -e
+
+  echo "version https://git-lfs.github.com/spec/v1
+oid sha256:6c17f2007cbe934aee6e309b28b2dba3c119c5dff2ef813ed124699efe319868
+size 7" > valid-pointer
+
+  [ "Pointer from valid-pointer is canonical" = "$(git lfs pointer --check --strict --pointer=valid-pointer 2>&1)" ]
+
+  printf "version https://git-lfs.github.com/spec/v1\r
+oid sha256:6c17f2007cbe934aee6e309b28b2dba3c119c5dff2ef813ed124699efe319868\r
+size 007\r
+" > crlf-pointer
+
+  expected="Pointer from STDIN is not canonical:
+  line 1: line ends with a carriage return
+  line 2: line ends with a carriage return
+  line 3: line ends with a carriage return
+  line 3: size should be \"7\", not \"007\""
+
+  [ "$expected" = "$(git lfs pointer --check --stdin < crlf-pointer 2>&1)" ]
+
+  set +e
+  output=$(git lfs pointer --check --strict --stdin < crlf-pointer 2>&1)
+  status=$?
+  set -e
+
+  [ "2" = "$status" ]
+  [ "$expected" = "$output" ]
+)
+end_test
+
+begin_test "pointer --check with bad pointer"
+(
+  set -e
+
+  echo "version https://git-lfs.github.com/spec/v1
+size 7
+oid sha256:6c17f2007cbe934aee6e309b28b2dba3c119c5dff2ef813ed124699efe319868" > bad-pointer
+
+  set +e
+  output=$(git lfs pointer --check --pointer=bad-pointer 2>&1)
+  status=$?
+  set -e
+
+  [ "1" = "$status" ]
+
+  expected="Pointer from bad-pointer is not a valid Git LFS pointer: Error parsing LFS Pointer. Expected key oid, got size
+  line 3: trailing data after the size line"
+
+  [ "$expected" = "$output" ]
+)
+end_test
+
+begin_test "pointer --check --file"
+(
+  echo "simple" > some-file
+  output=$(git lfs pointer --check --file=some-file 2>&1)
+  status=$?
+  set -e
+
+  [ "1" = "$status" ]
+  [ "--check reads the pointer from --pointer or --stdin, not --file." = "$output" ]
+)
+end_test
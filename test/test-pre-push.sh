@@ -367,3 +367,38 @@ begin_test "pre-push multiple branches"
 
 )
 end_test
+
+begin_test "pre-push sends the remote ref"
+(
+  set -e
+
+  reponame="$(basename "$0" ".sh")-remote-ref"
+  setup_remote_repo "$reponame"
+
+  clone_repo "$reponame" repo-remote-ref
+  git lfs track "*.dat"
+  echo "protected" > protected.dat
+  git add .gitattributes protected.dat
+  git commit -m "add protected.dat"
+
+  oid="$(calc_oid "protected\n")"
+
+  # the test server refuses uploads for refs/heads/protected
+  set +e
+  echo "refs/heads/master master refs/heads/protected 0000000000000000000000000000000000000000" |
+    GIT_TRACE=1 git lfs pre-push origin "$GITSERVER/$reponame" 2>&1 |
+    tee push.log
+  res=${PIPESTATUS[1]}
+  set -e
+  [ "$res" = "2" ]
+  grep "api: batch 1 files for refs/heads/protected" push.log
+  grep "Pushing to refs/heads/protected is not allowed" push.log
+  refute_server_object "$reponame" "$oid"
+
+  echo "refs/heads/master master refs/heads/master 0000000000000000000000000000000000000000" |
+    GIT_TRACE=1 git lfs pre-push origin "$GITSERVER/$reponame" 2>&1 |
+    tee push.log
+  grep "api: batch 1 files for refs/heads/master" push.log
+  assert_server_object "$reponame" "$oid"
+)
+end_test
@@ -47,6 +47,7 @@ begin_test "push (legacy): upload to bad dns"
   git commit -m "welp"
 
   port="$(echo "http://127.0.0.1:63378" | cut -f 3 -d ":")"
+  git config lfs.legacyapi true
   git config lfs.batch false
   git config lfs.url "http://git-lfs-bad-dns:$port"
 
@@ -0,0 +1,65 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "push with checksum header"
+(
+  set -e
+
+  reponame="checksum"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "checksum" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  # the server rejects uploads without a checksum
+  set +e
+  git push origin master 2>&1 | tee push.log
+  res="${PIPESTATUS[0]}"
+  set -e
+  if [ "$res" = "0" ]; then
+    echo "push successful?"
+    exit 1
+  fi
+  refute_server_object "$reponame" "$(calc_oid "checksum")"
+
+  git config "lfs.$GITSERVER/$reponame.git/info/lfs.checksumheader" X-Content-Sha256
+
+  git push origin master 2>&1 | tee push.log
+  grep "(1 of 1 files)" push.log
+  assert_server_object "$reponame" "$(calc_oid "checksum")"
+)
+end_test
+
+begin_test "push with checksum mismatch"
+(
+  set -e
+
+  reponame="checksum-mismatch"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git config "lfs.$GITSERVER/$reponame.git/info/lfs.checksumheader" X-Content-Sha256
+
+  git lfs track "*.dat"
+  printf "mismatch" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  set +e
+  GIT_TRACE=1 git push origin master 2>&1 | tee push.log
+  res="${PIPESTATUS[0]}"
+  set -e
+  if [ "$res" = "0" ]; then
+    echo "push successful?"
+    exit 1
+  fi
+
+  grep "The server rejected the uploaded content as corrupt" push.log
+  [ "0" = "$(grep -c "tq: retrying object" push.log)" ]
+  refute_server_object "$reponame" "$(calc_oid "mismatch")"
+)
+end_test
@@ -10,6 +10,7 @@ push_legacy_fail_test() {
   local reponame="$(basename "$0" ".sh")-$contents"
   setup_remote_repo "$reponame"
   clone_repo "$reponame" "$reponame"
+  git config lfs.legacyapi true
   git config lfs.batch false
 
   git lfs track "*.dat"
@@ -0,0 +1,135 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "rm-remote"
+(
+  set -e
+
+  reponame="$(basename "$0" ".sh")"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" repo
+
+  git lfs track "*.dat"
+  printf "rm a" > a.dat
+  printf "rm b" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "add a.dat and b.dat"
+  git push origin master
+
+  oid_a="$(calc_oid "rm a")"
+  oid_b="$(calc_oid "rm b")"
+  assert_server_object "$reponame" "$oid_a"
+  assert_server_object "$reponame" "$oid_b"
+
+  git lfs rm-remote --dry-run origin "$oid_a" 2>&1 | tee rm.log
+  grep "delete $oid_a" rm.log
+  assert_server_object "$reponame" "$oid_a"
+
+  git lfs rm-remote origin "$oid_a" 2>&1 | tee rm.log
+  grep "deleted $oid_a" rm.log
+  refute_server_object "$reponame" "$oid_a"
+  assert_server_object "$reponame" "$oid_b"
+
+  set +e
+  git lfs rm-remote origin "$oid_a" 2>&1 | tee rm.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" = "2" ]
+  grep "\[$oid_a\] Object $oid_a does not exist" rm.log
+
+  set +e
+  git lfs rm-remote origin not-an-oid 2>&1 | tee rm.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" = "2" ]
+  grep "Invalid Git LFS object ID: \"not-an-oid\"" rm.log
+)
+end_test
+
+begin_test "rm-remote --ref"
+(
+  set -e
+
+  reponame="rm-remote-ref"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" rm-remote-ref
+
+  git lfs track "*.dat"
+  mkdir dir
+  printf "ref a" > a.dat
+  printf "ref b" > dir/b.dat
+  printf "ref c" > dir/c.dat
+  git add .gitattributes a.dat dir
+  git commit -m "add files"
+  git push origin master
+
+  oid_a="$(calc_oid "ref a")"
+  oid_b="$(calc_oid "ref b")"
+  oid_c="$(calc_oid "ref c")"
+
+  # removing the files from the current commit doesn't affect --ref
+  git rm dir/b.dat dir/c.dat
+  git commit -m "remove dir"
+
+  git lfs rm-remote --dry-run --ref HEAD^ origin dir 2>&1 | tee rm.log
+  grep "delete $oid_b" rm.log
+  grep "delete $oid_c" rm.log
+  [ "0" = "$(grep -c "$oid_a" rm.log)" ]
+
+  git lfs rm-remote --ref HEAD^ origin "dir/b.dat" 2>&1 | tee rm.log
+  grep "deleted $oid_b" rm.log
+  [ "1" = "$(grep -c "deleted" rm.log)" ]
+
+  refute_server_object "$reponame" "$oid_b"
+  assert_server_object "$reponame" "$oid_a"
+  assert_server_object "$reponame" "$oid_c"
+)
+end_test
+
+begin_test "rm-remote confirmation"
+(
+  set -e
+
+  reponame="rm-remote-confirm"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" rm-remote-confirm
+
+  git lfs track "*.dat"
+  for i in $(seq 1 11); do
+    printf "confirm $i" > "$i.dat"
+  done
+  git add .gitattributes *.dat
+  git commit -m "add 11 files"
+  git push origin master
+
+  echo "n" | git lfs rm-remote --ref master origin "*.dat" 2>&1 | tee rm.log
+  grep "Delete 11 Git LFS objects from \"origin\"? \[y/N\]" rm.log
+  grep "Not deleting any objects." rm.log
+  assert_server_object "$reponame" "$(calc_oid "confirm 1")"
+
+  echo "y" | git lfs rm-remote --ref master origin "*.dat" 2>&1 | tee rm.log
+  [ "11" = "$(grep -c "deleted" rm.log)" ]
+  refute_server_object "$reponame" "$(calc_oid "confirm 1")"
+)
+end_test
+
+begin_test "rm-remote --yes"
+(
+  set -e
+
+  reponame="rm-remote-confirm"
+  cd "$TRASHDIR/rm-remote-confirm"
+  for i in $(seq 1 11); do
+    oids="$oids $(calc_oid "confirm $i")"
+  done
+
+  git lfs push --object-id origin $oids
+  assert_server_object "$reponame" "$(calc_oid "confirm 1")"
+
+  git lfs rm-remote --yes origin $oids 2>&1 < /dev/null | tee rm.log
+  [ "0" = "$(grep -c "Delete 11" rm.log)" ]
+  [ "11" = "$(grep -c "deleted" rm.log)" ]
+  refute_server_object "$reponame" "$(calc_oid "confirm 1")"
+)
+end_test
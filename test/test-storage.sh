@@ -0,0 +1,91 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "lfs.storage"
+(
+  set -e
+
+  reponame="$(basename "$0" ".sh")"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "storage" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  git push origin master
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 git clone "$GITSERVER/$reponame" storage-clone
+  cd storage-clone
+  git config lfs.storage "$TRASHDIR/shared-storage"
+
+  [ "LocalMediaDir=$TRASHDIR/shared-storage/objects" = "$(git lfs env | grep LocalMediaDir)" ]
+
+  git lfs fetch
+  oid="$(calc_oid "storage")"
+  [ "storage" = "$(cat "$TRASHDIR/shared-storage/objects/${oid:0:2}/${oid:2:2}/$oid")" ]
+  [ ! -e ".git/lfs/objects/${oid:0:2}/${oid:2:2}/$oid" ]
+
+  # another clone's object, which this clone doesn't reference, isn't pruned
+  other_oid="$(calc_oid "other clone")"
+  mkdir -p "$TRASHDIR/shared-storage/objects/${other_oid:0:2}/${other_oid:2:2}"
+  printf "other clone" > "$TRASHDIR/shared-storage/objects/${other_oid:0:2}/${other_oid:2:2}/$other_oid"
+  git lfs prune 2>&1 | tee prune.log
+  grep "Not pruning $TRASHDIR/shared-storage/objects" prune.log
+  [ -e "$TRASHDIR/shared-storage/objects/${other_oid:0:2}/${other_oid:2:2}/$other_oid" ]
+
+  # nor evicted by lfs.cache.maxsize, which doesn't record accesses either
+  git config lfs.cache.maxsize 1
+  git lfs fetch 2>&1 | tee fetch.log
+  grep "Not evicting objects from $TRASHDIR/shared-storage/objects" fetch.log
+  [ -e "$TRASHDIR/shared-storage/objects/${other_oid:0:2}/${other_oid:2:2}/$other_oid" ]
+  git lfs checkout
+  [ ! -e "$TRASHDIR/shared-storage/access" ]
+  git config --unset lfs.cache.maxsize
+
+  # a relative lfs.storage is relative to the .git dir
+  git config lfs.storage lfs-storage
+  [ "LocalMediaDir=$TRASHDIR/storage-clone/.git/lfs-storage/objects" = "$(git lfs env | grep LocalMediaDir)" ]
+)
+end_test
+
+begin_test "lfs alternates"
+(
+  set -e
+
+  reponame="$(basename "$0" ".sh")-alternates"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "shared" > shared.dat
+  git add .gitattributes shared.dat
+  git commit -m "add shared.dat"
+  git push origin master
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 git clone "$GITSERVER/$reponame" alternates-clone
+  cd alternates-clone
+  mkdir -p .git/lfs/objects/info
+  echo "$TRASHDIR/$reponame/.git/lfs/objects" > .git/lfs/objects/info/alternates
+
+  # the object is read from the alternate, without downloading it
+  GIT_TRACE=1 git lfs pull 2>&1 | tee pull.log
+  [ "0" = "$(grep -c "HTTP: GET" pull.log)" ]
+  [ "shared" = "$(cat shared.dat)" ]
+  refute_local_object "$(calc_oid "shared")"
+
+  # new objects go to the local store
+  printf "local" > local.dat
+  git add local.dat
+  git commit -m "add local.dat"
+  assert_local_object "$(calc_oid "local")" 5
+  [ ! -e "$TRASHDIR/$reponame/.git/lfs/objects/$(calc_oid "local" | cut -c 1-2)" ]
+
+  # pushing reads objects from the alternate, too
+  git lfs push --object-id origin "$(calc_oid "shared")" 2>&1 | tee push.log
+  grep "(0 of 1 files, 1 skipped)" push.log
+)
+end_test